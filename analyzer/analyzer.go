@@ -16,7 +16,7 @@ import (
 // Analyzer analyzes an AST.
 type Analyzer interface {
 	ast.Visitor
-	Analyze() []error
+	Analyze() ast.Diagnostics
 }
 
 type analyzer struct {
@@ -25,7 +25,11 @@ type analyzer struct {
 	loopStack   []ast.Loop
 	structStack []*ast.StructExpr
 
-	errors []error
+	// the number of catch or finally clauses that enclose
+	// the current node, within the current function
+	clauseDepth int
+
+	errors ast.Diagnostics
 }
 
 // NewAnalyzer creates a new Analyzer
@@ -36,12 +40,13 @@ func NewAnalyzer(mod *ast.Module) Analyzer {
 		scopeStack:  []ast.Scope{mod.InitFunc.Scope},
 		loopStack:   []ast.Loop{},
 		structStack: []*ast.StructExpr{},
+		clauseDepth: 0,
 		errors:      nil,
 	}
 }
 
 // Analyze analyzes an AST. The names of any imported modules are returned.
-func (a *analyzer) Analyze() []error {
+func (a *analyzer) Analyze() ast.Diagnostics {
 
 	// visit InitFunc
 	a.visitBlock(a.mod.InitFunc.Body)
@@ -50,6 +55,10 @@ func (a *analyzer) Analyze() []error {
 	return a.errors
 }
 
+func (a *analyzer) diagnostic(token *ast.Token, code string, msg string) *ast.Diagnostic {
+	return ast.NewDiagnostic(a.mod.Path, token, code, msg)
+}
+
 func (a *analyzer) Visit(node ast.Node) {
 	switch t := node.(type) {
 
@@ -71,12 +80,29 @@ func (a *analyzer) Visit(node ast.Node) {
 	case *ast.AssignmentExpr:
 		a.visitAssignment(t)
 
+	case *ast.DestructureExpr:
+		a.visitDestructure(t)
+
+	case *ast.BlankExpr:
+		a.errors = append(a.errors,
+			a.diagnostic(t.Token, "blank-outside-pattern", "'_' outside of pattern"))
+
+	case *ast.RestExpr:
+		a.errors = append(a.errors,
+			a.diagnostic(t.TripleDot, "rest-outside-pattern", "'...' outside of pattern"))
+
 	case *ast.TryStmt:
 		a.visitTry(t)
 
 	case *ast.PostfixExpr:
 		a.visitPostfixExpr(t)
 
+	case *ast.SelectCaseNode:
+		a.visitSelectCase(t)
+
+	case *ast.CaseNode:
+		a.visitCase(t)
+
 	case *ast.IdentExpr:
 		a.visitIdentExpr(t)
 
@@ -90,16 +116,19 @@ func (a *analyzer) Visit(node ast.Node) {
 		a.visitFor(t)
 		a.loopStack = a.loopStack[:len(a.loopStack)-1]
 
+	case *ast.YieldStmt:
+		a.visitYield(t)
+
 	case *ast.BreakStmt:
 		if len(a.loopStack) == 0 {
 			a.errors = append(a.errors,
-				fmt.Errorf("'break' outside of loop, at %s:%v", a.mod.Path, t.Token.Position))
+				a.diagnostic(t.Token, "break-outside-loop", "'break' outside of loop"))
 		}
 
 	case *ast.ContinueStmt:
 		if len(a.loopStack) == 0 {
 			a.errors = append(a.errors,
-				fmt.Errorf("'continue' outside of loop, at %s:%v", a.mod.Path, t.Token.Position))
+				a.diagnostic(t.Token, "continue-outside-loop", "'continue' outside of loop"))
 		}
 
 	case *ast.StructExpr:
@@ -120,7 +149,9 @@ func (a *analyzer) visitDecls(decls []*ast.DeclNode, isConst bool) {
 		if d.Val != nil {
 			a.Visit(d.Val)
 		}
-		a.defineIdent(d.Ident, isConst)
+		for _, ident := range d.Idents() {
+			a.defineIdent(ident, isConst)
+		}
 	}
 }
 
@@ -133,23 +164,53 @@ func (a *analyzer) visitImport(imp *ast.ImportStmt) {
 func (a *analyzer) visitTry(t *ast.TryStmt) {
 
 	a.Visit(t.TryBlock)
+
+	a.clauseDepth++
 	if t.CatchToken != nil {
 		a.pushScope(t.CatchScope)
 		t.CatchIdent.Variable = a.putVariable(t.CatchIdent.Symbol.Text, true)
 
+		if t.CatchGuard != nil {
+			a.Visit(t.CatchGuard)
+		}
 		a.Visit(t.CatchBlock)
 		a.popScope()
 	}
 	if t.FinallyToken != nil {
 		a.Visit(t.FinallyBlock)
 	}
+	a.clauseDepth--
+}
+
+func (a *analyzer) visitYield(y *ast.YieldStmt) {
+
+	a.Visit(y.Val)
+
+	// find the nearest parent FuncScope
+	for i := len(a.scopeStack) - 1; i >= 0; i-- {
+		if f, ok := a.scopeStack[i].(ast.FuncScope); ok {
+
+			switch {
+			case i == 0:
+				a.errors = append(a.errors,
+					a.diagnostic(y.Token, "yield-outside-function", "'yield' outside of function"))
+			case a.clauseDepth > 0:
+				a.errors = append(a.errors,
+					a.diagnostic(y.Token, "yield-inside-clause", "'yield' inside of catch or finally clause"))
+			default:
+				f.SetGenerator()
+			}
+			return
+		}
+	}
+	panic("unreachable")
 }
 
 func (a *analyzer) defineIdent(ident *ast.IdentExpr, isConst bool) {
 	sym := ident.Symbol.Text
 	if _, ok := a.getVariable(sym); ok {
 		a.errors = append(a.errors,
-			fmt.Errorf("Symbol '%s' is already defined, at %s:%v", sym, a.mod.Path, ident.Symbol.Position))
+			a.diagnostic(ident.Symbol, "already-defined", fmt.Sprintf("Symbol '%s' is already defined", sym)))
 	} else {
 		ident.Variable = a.putVariable(sym, isConst)
 	}
@@ -199,6 +260,10 @@ func (a *analyzer) visitFor(fr *ast.ForStmt) {
 
 func (a *analyzer) visitFunc(fn *ast.FnExpr) {
 
+	// catch and finally clauses do not extend into nested functions
+	clauseDepth := a.clauseDepth
+	a.clauseDepth = 0
+
 	a.pushScope(fn.Scope)
 
 	for _, f := range fn.Required {
@@ -217,6 +282,8 @@ func (a *analyzer) visitFunc(fn *ast.FnExpr) {
 	a.visitBlock(fn.Body)
 
 	a.popScope()
+
+	a.clauseDepth = clauseDepth
 }
 
 func (a *analyzer) visitAssignment(asn *ast.AssignmentExpr) {
@@ -241,6 +308,14 @@ func (a *analyzer) visitAssignment(asn *ast.AssignmentExpr) {
 	}
 }
 
+func (a *analyzer) visitDestructure(ds *ast.DestructureExpr) {
+
+	a.Visit(ds.Val)
+	for _, ident := range ast.PatternIdents(ds.Pattern) {
+		a.doVisitAssignIdent(ident)
+	}
+}
+
 func (a *analyzer) visitPostfixExpr(ps *ast.PostfixExpr) {
 
 	switch t := ps.Assignee.(type) {
@@ -260,18 +335,85 @@ func (a *analyzer) visitPostfixExpr(ps *ast.PostfixExpr) {
 	}
 }
 
+// visit a select case, assigning the received value if there is one
+func (a *analyzer) visitSelectCase(cs *ast.SelectCaseNode) {
+
+	a.Visit(cs.Comm)
+	if cs.Assignee != nil {
+		a.doVisitAssignIdent(cs.Assignee)
+	}
+
+	for _, n := range cs.Body {
+		a.Visit(n)
+	}
+}
+
+func (a *analyzer) visitCase(cs *ast.CaseNode) {
+
+	a.pushScope(cs.Scope)
+
+	for _, m := range cs.Matches {
+		a.Visit(m)
+	}
+
+	// define the identifiers that are assigned by the patterns
+	for _, pt := range cs.Patterns {
+		a.visitPatternValues(pt)
+		for _, ident := range ast.PatternIdents(pt) {
+			sym := ident.Symbol.Text
+			if _, ok := cs.Scope.GetVariable(sym); ok {
+				a.errors = append(a.errors,
+					a.diagnostic(ident.Symbol, "already-defined", fmt.Sprintf("Symbol '%s' is already defined", sym)))
+			} else {
+				ident.Variable = a.putVariable(sym, false)
+			}
+		}
+	}
+
+	if cs.Guard != nil {
+		a.Visit(cs.Guard)
+	}
+
+	for _, n := range cs.Body {
+		a.Visit(n)
+	}
+
+	a.popScope()
+}
+
+// visit the values that a pattern compares against
+func (a *analyzer) visitPatternValues(pattern ast.Pattern) {
+
+	switch t := pattern.(type) {
+	case *ast.ValuePattern:
+		a.Visit(t.Val)
+	case *ast.TuplePattern:
+		for _, e := range t.Elems {
+			a.visitPatternValues(e)
+		}
+	case *ast.ListPattern:
+		for _, e := range t.Elems {
+			a.visitPatternValues(e)
+		}
+	case *ast.StructPattern:
+		for _, e := range t.Entries {
+			a.visitPatternValues(e.Pattern)
+		}
+	}
+}
+
 // visit an Ident that is part of an assignment
 func (a *analyzer) doVisitAssignIdent(ident *ast.IdentExpr) {
 	sym := ident.Symbol.Text
 	if v, ok := a.getVariable(sym); ok {
 		if v.IsConst() {
 			a.errors = append(a.errors,
-				fmt.Errorf("Symbol '%s' is constant, at %s:%v", sym, a.mod.Path, ident.Symbol.Position))
+				a.diagnostic(ident.Symbol, "assign-to-const", fmt.Sprintf("Symbol '%s' is constant", sym)))
 		}
 		ident.Variable = v
 	} else {
 		a.errors = append(a.errors,
-			fmt.Errorf("Symbol '%s' is not defined, at %s:%v", sym, a.mod.Path, ident.Symbol.Position))
+			a.diagnostic(ident.Symbol, "undefined-symbol", fmt.Sprintf("Symbol '%s' is not defined", sym)))
 	}
 }
 
@@ -283,7 +425,7 @@ func (a *analyzer) visitIdentExpr(ident *ast.IdentExpr) {
 		ident.Variable = v
 	} else {
 		a.errors = append(a.errors,
-			fmt.Errorf("Symbol '%s' is not defined, at %s:%v", sym, a.mod.Path, ident.Symbol.Position))
+			a.diagnostic(ident.Symbol, "undefined-symbol", fmt.Sprintf("Symbol '%s' is not defined", sym)))
 	}
 }
 
@@ -302,7 +444,7 @@ func (a *analyzer) visitThisExpr(this *ast.ThisExpr) {
 	n := len(a.structStack)
 	if n == 0 {
 		a.errors = append(a.errors,
-			fmt.Errorf("'this' outside of struct, at %s:%v", a.mod.Path, this.Token.Position))
+			a.diagnostic(this.Token, "this-outside-struct", "'this' outside of struct"))
 	} else {
 		this.Variable = a.putThis()
 	}
@@ -12,7 +12,7 @@ import (
 	"testing"
 )
 
-func ok(t *testing.T, mod *ast.Module, errors []error, dump string) {
+func ok(t *testing.T, mod *ast.Module, errors ast.Diagnostics, dump string) {
 
 	if len(errors) != 0 {
 		t.Error(errors)
@@ -24,9 +24,9 @@ func ok(t *testing.T, mod *ast.Module, errors []error, dump string) {
 
 }
 
-func fail(t *testing.T, errors []error, expect string) {
+func fail(t *testing.T, errors ast.Diagnostics, expect string) {
 
-	if fmt.Sprintf("%v", errors) != expect {
+	if fmt.Sprintf("%v", []*ast.Diagnostic(errors)) != expect {
 		t.Error(errors, " != ", expect)
 	}
 }
@@ -98,16 +98,16 @@ FnExpr(FuncScope defs:{} captures:{} numLocals:2)
 `)
 
 	errors = NewAnalyzer(newModule("a;")).Analyze()
-	fail(t, errors, "[Symbol 'a' is not defined, at foo.glm:1:1]")
+	fail(t, errors, "[Symbol 'a' is not defined at foo.glm:1:1]")
 
 	errors = NewAnalyzer(newModule("let a = 1;const a = 1;")).Analyze()
-	fail(t, errors, "[Symbol 'a' is already defined, at foo.glm:1:17]")
+	fail(t, errors, "[Symbol 'a' is already defined at foo.glm:1:17]")
 
 	errors = NewAnalyzer(newModule("const a = 1;a = 1;")).Analyze()
-	fail(t, errors, "[Symbol 'a' is constant, at foo.glm:1:13]")
+	fail(t, errors, "[Symbol 'a' is constant at foo.glm:1:13]")
 
 	errors = NewAnalyzer(newModule("a = a;")).Analyze()
-	fail(t, errors, "[Symbol 'a' is not defined, at foo.glm:1:5 Symbol 'a' is not defined, at foo.glm:1:1]")
+	fail(t, errors, "[Symbol 'a' is not defined at foo.glm:1:5 Symbol 'a' is not defined at foo.glm:1:1]")
 }
 
 func TestNested(t *testing.T) {
@@ -184,10 +184,10 @@ FnExpr(FuncScope defs:{} captures:{} numLocals:0)
 `)
 
 	errors = NewAnalyzer(newModule("break;")).Analyze()
-	fail(t, errors, "['break' outside of loop, at foo.glm:1:1]")
+	fail(t, errors, "['break' outside of loop at foo.glm:1:1]")
 
 	errors = NewAnalyzer(newModule("continue;")).Analyze()
-	fail(t, errors, "['continue' outside of loop, at foo.glm:1:1]")
+	fail(t, errors, "['continue' outside of loop at foo.glm:1:1]")
 
 	mod = newModule("let a; for b in [] { break; continue; }")
 	errors = NewAnalyzer(mod).Analyze()
@@ -337,6 +337,107 @@ FnExpr(FuncScope defs:{} captures:{} numLocals:6)
 `)
 }
 
+func TestDestructure(t *testing.T) {
+
+	code := `
+const (a, [b, ...c]) = x
+let { d, e: _ } = y
+(a2, d) = (d, a2)
+`
+	mod := newModule("let x, y, a2\n" + code)
+	errors := NewAnalyzer(mod).Analyze()
+
+	ok(t, mod, errors, `
+FnExpr(FuncScope defs:{} captures:{} numLocals:7)
+.   BlockNode(Scope defs:{a: v(3: a,3,true,false), a2: v(2: a2,2,false,false), b: v(4: b,4,true,false), c: v(5: c,5,true,false), d: v(6: d,6,false,false), x: v(0: x,0,false,false), y: v(1: y,1,false,false)})
+.   .   LetStmt
+.   .   .   IdentExpr(x,v(0: x,0,false,false))
+.   .   .   IdentExpr(y,v(1: y,1,false,false))
+.   .   .   IdentExpr(a2,v(2: a2,2,false,false))
+.   .   ConstStmt
+.   .   .   TuplePattern
+.   .   .   .   IdentExpr(a,v(3: a,3,true,false))
+.   .   .   .   ListPattern
+.   .   .   .   .   IdentExpr(b,v(4: b,4,true,false))
+.   .   .   .   .   IdentExpr(c,v(5: c,5,true,false))
+.   .   .   IdentExpr(x,v(0: x,0,false,false))
+.   .   LetStmt
+.   .   .   StructPattern
+.   .   .   .   IdentExpr(d,v(6: d,6,false,false))
+.   .   .   .   BlankExpr
+.   .   .   IdentExpr(y,v(1: y,1,false,false))
+.   .   ExprStmt
+.   .   .   DestructureExpr
+.   .   .   .   TuplePattern
+.   .   .   .   .   IdentExpr(a2,v(2: a2,2,false,false))
+.   .   .   .   .   IdentExpr(d,v(6: d,6,false,false))
+.   .   .   .   TupleExpr
+.   .   .   .   .   IdentExpr(d,v(6: d,6,false,false))
+.   .   .   .   .   IdentExpr(a2,v(2: a2,2,false,false))
+`)
+
+	errors = NewAnalyzer(newModule("const (a, b) = c; [b, a] = c;")).Analyze()
+	fail(t, errors, "[Symbol 'c' is not defined at foo.glm:1:16 Symbol 'c' is not defined at foo.glm:1:28 Symbol 'b' is constant at foo.glm:1:20 Symbol 'a' is constant at foo.glm:1:23]")
+
+	errors = NewAnalyzer(newModule("let [a, a] = [1, 2];")).Analyze()
+	fail(t, errors, "[Symbol 'a' is already defined at foo.glm:1:9]")
+
+	errors = NewAnalyzer(newModule("let a = _;")).Analyze()
+	fail(t, errors, "['_' outside of pattern at foo.glm:1:9]")
+
+	errors = NewAnalyzer(newModule("let a = [1, ...a];")).Analyze()
+	fail(t, errors, "['...' outside of pattern at foo.glm:1:13]")
+}
+
+func TestSwitchPatterns(t *testing.T) {
+
+	code := `
+let x = 1
+switch x {
+case (a, [_, ...b]) if a > x:
+    let c = a
+case {a: 0}:
+    x
+}
+`
+	mod := newModule(code)
+	errors := NewAnalyzer(mod).Analyze()
+
+	ok(t, mod, errors, `
+FnExpr(FuncScope defs:{} captures:{} numLocals:4)
+.   BlockNode(Scope defs:{x: v(0: x,0,false,false)})
+.   .   LetStmt
+.   .   .   IdentExpr(x,v(0: x,0,false,false))
+.   .   .   BasicExpr(Int,"1")
+.   .   SwitchStmt
+.   .   .   IdentExpr(x,v(0: x,0,false,false))
+.   .   .   CaseNode(Scope defs:{a: v(1: a,1,false,false), b: v(2: b,2,false,false), c: v(3: c,3,false,false)})
+.   .   .   .   TuplePattern
+.   .   .   .   .   IdentExpr(a,v(1: a,1,false,false))
+.   .   .   .   .   ListPattern
+.   .   .   .   .   .   BlankExpr
+.   .   .   .   .   .   IdentExpr(b,v(2: b,2,false,false))
+.   .   .   .   BinaryExpr(">")
+.   .   .   .   .   IdentExpr(a,v(1: a,1,false,false))
+.   .   .   .   .   IdentExpr(x,v(0: x,0,false,false))
+.   .   .   .   LetStmt
+.   .   .   .   .   IdentExpr(c,v(3: c,3,false,false))
+.   .   .   .   .   IdentExpr(a,v(1: a,1,false,false))
+.   .   .   CaseNode(Scope defs:{})
+.   .   .   .   StructPattern
+.   .   .   .   .   ValuePattern
+.   .   .   .   .   .   BasicExpr(Int,"0")
+.   .   .   .   ExprStmt
+.   .   .   .   .   IdentExpr(x,v(0: x,0,false,false))
+`)
+
+	errors = NewAnalyzer(newModule("switch 1 { case (a, a): 2; }")).Analyze()
+	fail(t, errors, "[Symbol 'a' is already defined at foo.glm:1:21]")
+
+	errors = NewAnalyzer(newModule("switch 1 { case {a: b}: 2; case Int: b; }")).Analyze()
+	fail(t, errors, "[Symbol 'b' is not defined at foo.glm:1:38]")
+}
+
 func TestList(t *testing.T) {
 
 	code := `
@@ -415,13 +516,13 @@ FnExpr(FuncScope defs:{} captures:{} numLocals:2)
 func TestFormalParams(t *testing.T) {
 
 	errors := NewAnalyzer(newModule("fn(const a, b) { a = 1; };")).Analyze()
-	fail(t, errors, "[Symbol 'a' is constant, at foo.glm:1:18]")
+	fail(t, errors, "[Symbol 'a' is constant at foo.glm:1:18]")
 
 	errors = NewAnalyzer(newModule("fn(a, const b) { b = 1; };")).Analyze()
-	fail(t, errors, "[Symbol 'b' is constant, at foo.glm:1:18]")
+	fail(t, errors, "[Symbol 'b' is constant at foo.glm:1:18]")
 
 	errors = NewAnalyzer(newModule("fn(a, const b...) { b = 1; };")).Analyze()
-	fail(t, errors, "[Symbol 'b' is constant, at foo.glm:1:21]")
+	fail(t, errors, "[Symbol 'b' is constant at foo.glm:1:21]")
 }
 
 func TestPureFunction(t *testing.T) {
@@ -512,7 +613,7 @@ FnExpr(FuncScope defs:{} captures:{} numLocals:2)
 `)
 
 	errors = NewAnalyzer(newModule("fn a() {}; const a = 1;")).Analyze()
-	fail(t, errors, "[Symbol 'a' is already defined, at foo.glm:1:18]")
+	fail(t, errors, "[Symbol 'a' is already defined at foo.glm:1:18]")
 }
 
 func TestCaptureFunction(t *testing.T) {
@@ -675,13 +776,13 @@ FnExpr(FuncScope defs:{} captures:{} numLocals:2)
 
 func TestImport(t *testing.T) {
 	errors := NewAnalyzer(newModule("import foo; let foo = 2;")).Analyze()
-	fail(t, errors, "[Symbol 'foo' is already defined, at foo.glm:1:17]")
+	fail(t, errors, "[Symbol 'foo' is already defined at foo.glm:1:17]")
 
 	errors = NewAnalyzer(newModule("import foo; import foo;")).Analyze()
-	fail(t, errors, "[Symbol 'foo' is already defined, at foo.glm:1:20]")
+	fail(t, errors, "[Symbol 'foo' is already defined at foo.glm:1:20]")
 
 	errors = NewAnalyzer(newModule("import foo, zork; foo = 2;")).Analyze()
-	fail(t, errors, "[Symbol 'foo' is constant, at foo.glm:1:19]")
+	fail(t, errors, "[Symbol 'foo' is constant at foo.glm:1:19]")
 }
 
 func TestArity(t *testing.T) {
@@ -712,7 +813,7 @@ func TestStruct(t *testing.T) {
 
 	mod := newModule("this")
 	errors := NewAnalyzer(mod).Analyze()
-	fail(t, errors, "['this' outside of struct, at foo.glm:1:1]")
+	fail(t, errors, "['this' outside of struct at foo.glm:1:1]")
 
 	code := `
 struct{ }
@@ -942,3 +1043,34 @@ FnExpr(FuncScope defs:{} captures:{} numLocals:3)
 .   .   .   .   IdentExpr(u,v(4: u,2,false,false))
 `)
 }
+
+func TestYield(t *testing.T) {
+
+	mod := newModule("fn() { yield 1; };")
+	errors := NewAnalyzer(mod).Analyze()
+	fail(t, errors, "[]")
+
+	fn := mod.InitFunc.Body.Statements[0].(*ast.ExprStmt).Expr.(*ast.FnExpr)
+	if !fn.Scope.IsGenerator() || mod.InitFunc.Scope.IsGenerator() {
+		t.Error("generator was not detected")
+	}
+
+	mod = newModule("fn() { fn() { yield 1; }; };")
+	errors = NewAnalyzer(mod).Analyze()
+	fail(t, errors, "[]")
+
+	fn = mod.InitFunc.Body.Statements[0].(*ast.ExprStmt).Expr.(*ast.FnExpr)
+	if fn.Scope.IsGenerator() {
+		t.Error("nested generator was not detected")
+	}
+
+	errors = NewAnalyzer(newModule("yield 1;")).Analyze()
+	fail(t, errors, "['yield' outside of function at foo.glm:1:1]")
+
+	errors = NewAnalyzer(newModule("fn() { try { yield 1; } catch e { yield 2; } finally { yield 3; }; };")).Analyze()
+	fail(t, errors, "['yield' inside of catch or finally clause at foo.glm:1:35 "+
+		"'yield' inside of catch or finally clause at foo.glm:1:56]")
+
+	errors = NewAnalyzer(newModule("fn() { try { } catch e { fn() { yield 1; }; }; };")).Analyze()
+	fail(t, errors, "[]")
+}
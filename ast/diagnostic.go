@@ -0,0 +1,102 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+//-------------------------------------
+// Severity
+
+// Severity is the severity of a Diagnostic.
+type Severity int
+
+// The severities
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		panic("unreachable")
+	}
+}
+
+// MarshalText encodes a Severity as its name.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+//-------------------------------------
+// Diagnostic
+
+// Diagnostic is a problem that was found in a source file while it was being
+// compiled.  The scanner, parser, analyzer and compiler all report problems
+// as Diagnostics.
+type Diagnostic struct {
+	Path string `json:"path"`
+
+	// Start is the position of the first character of the source code that
+	// caused the problem, and End is the position just after the last character.
+	Start Pos `json:"start"`
+	End   Pos `json:"end"`
+
+	Severity Severity `json:"severity"`
+
+	// Code identifies what kind of problem this is, e.g. "undefined-symbol".
+	Code string `json:"code"`
+
+	Msg string `json:"message"`
+}
+
+// NewDiagnostic creates an error Diagnostic that spans a Token.
+func NewDiagnostic(path string, token *Token, code string, msg string) *Diagnostic {
+	return &Diagnostic{
+		Path:     path,
+		Start:    token.Position,
+		End:      tokenEnd(token),
+		Severity: SeverityError,
+		Code:     code,
+		Msg:      msg,
+	}
+}
+
+func (d *Diagnostic) Error() string {
+	return fmt.Sprintf("%s at %s:%v", d.Msg, d.Path, d.Start)
+}
+
+// tokenEnd returns the position just after the last character of a Token.
+func tokenEnd(token *Token) Pos {
+	end := token.Position
+	for _, r := range token.Text {
+		if r == '\n' {
+			end = Pos{end.Line + 1, 1}
+		} else {
+			end.Col += utf8.RuneLen(r)
+		}
+	}
+	return end
+}
+
+// Diagnostics is a list of Diagnostics.  It is returned as an error
+// when a source file fails to compile.
+type Diagnostics []*Diagnostic
+
+func (ds Diagnostics) Error() string {
+	msgs := make([]string, len(ds))
+	for i, d := range ds {
+		msgs[i] = d.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
@@ -111,6 +111,15 @@ type (
 		RBrace      *Token
 	}
 
+	// SelectStmt is a 'select' statement
+	SelectStmt struct {
+		Token       *Token
+		LBrace      *Token
+		Cases       []*SelectCaseNode
+		DefaultNode *DefaultNode
+		RBrace      *Token
+	}
+
 	// BreakStmt is a 'break' statement
 	BreakStmt struct {
 		Token *Token
@@ -127,6 +136,12 @@ type (
 		Val   Expression
 	}
 
+	// YieldStmt is a 'yield' statement
+	YieldStmt struct {
+		Token *Token
+		Val   Expression
+	}
+
 	// ThrowStmt is a 'throw' statement
 	ThrowStmt struct {
 		Token *Token
@@ -139,6 +154,7 @@ type (
 		TryBlock     *BlockNode
 		CatchToken   *Token
 		CatchIdent   *IdentExpr
+		CatchGuard   Expression
 		CatchBlock   *BlockNode
 		FinallyToken *Token
 		FinallyBlock *BlockNode
@@ -147,8 +163,8 @@ type (
 		CatchScope Scope
 	}
 
-	// GoStmt is a 'go' statement
-	GoStmt struct {
+	// DeferStmt is a 'defer' statement
+	DeferStmt struct {
 		Token      *Token
 		Invocation *InvokeExpr
 	}
@@ -167,25 +183,51 @@ type (
 		Scope Scope
 	}
 
-	// DeclNode is a declaration
+	// DeclNode is a declaration.  A declaration either has an Ident, or
+	// it has a Pattern, in which case it always has a Val as well.
 	DeclNode struct {
-		Ident *IdentExpr
-		Val   Expression
+		Ident   *IdentExpr
+		Pattern Pattern
+		Val     Expression
 	}
 
-	// CaseNode is a 'case' clause in a 'switch' statement.
+	// CaseNode is a 'case' clause in a 'switch' statement.  A case either
+	// has Matches, or, in a 'switch' that has an item, it can have Patterns
+	// instead, along with an optional Guard.
 	CaseNode struct {
-		Token   *Token
-		Matches []Expression
-		Body    []Statement
+		Token    *Token
+		Matches  []Expression
+		Patterns []Pattern
+		Guard    Expression
+		Body     []Statement
+
+		// Scope defines the scope for the identifiers in the Patterns,
+		// and for the Body
+		Scope Scope
+	}
+
+	// SelectCaseNode is a 'case' clause in a 'select' statement.  The
+	// Comm is always either a 'recv()' or a 'send()' invocation on a channel.
+	// A 'recv()' can optionally assign its result to an identifier.
+	SelectCaseNode struct {
+		Token    *Token
+		Assignee *IdentExpr
+		Comm     *InvokeExpr
+		Body     []Statement
 	}
 
-	// DefaultNode is a 'default' clause in a 'switch' statement.
+	// DefaultNode is a 'default' clause in a 'switch' or 'select' statement.
 	DefaultNode struct {
 		Token *Token
 		Body  []Statement
 	}
 
+	// GoExpr is a 'go' expression
+	GoExpr struct {
+		Token      *Token
+		Invocation *InvokeExpr
+	}
+
 	// AssignmentExpr is an assigment expressions
 	AssignmentExpr struct {
 		Assignee Assignable
@@ -193,6 +235,14 @@ type (
 		Val      Expression
 	}
 
+	// DestructureExpr is an assignment expression whose assignee is a
+	// tuple or list pattern, e.g. '(a, b) = (b, a)'
+	DestructureExpr struct {
+		Pattern Pattern
+		Eq      *Token
+		Val     Expression
+	}
+
 	// TernaryExpr is a ternary expression
 	TernaryExpr struct {
 		Cond Expression
@@ -224,6 +274,15 @@ type (
 		Token *Token
 	}
 
+	// InterpStrExpr is a raw string that contains interpolated expressions.
+	// The Tokens contain the parts of the string that come before, between,
+	// and after the Exprs, so there is always one more Token than there
+	// are Exprs.
+	InterpStrExpr struct {
+		Tokens []*Token
+		Exprs  []Expression
+	}
+
 	// IdentExpr is an identifier expression
 	IdentExpr struct {
 		Symbol   *Token
@@ -378,6 +437,115 @@ type (
 	}
 )
 
+//--------------------------------------------------------------
+// Pattern
+
+type (
+
+	// Pattern is a Node that destructures a value, by assigning
+	// the parts of the value to identifiers.  An IdentExpr is a Pattern
+	// that matches any value.
+	Pattern interface {
+		Node
+		patternMarker()
+	}
+
+	// BlankExpr is the blank identifier '_'.  It can only be used in a
+	// Pattern, where it matches any value without assigning it.
+	BlankExpr struct {
+		Token *Token
+	}
+
+	// RestExpr is the '...rest' at the end of a list literal.  Like a
+	// BlankExpr, it can only be used in a Pattern.
+	RestExpr struct {
+		TripleDot *Token
+		Rest      Pattern
+	}
+
+	// TuplePattern destructures a tuple
+	TuplePattern struct {
+		LParen *Token
+		Elems  []Pattern
+		RParen *Token
+	}
+
+	// ListPattern destructures a list.  If Rest is not nil, it is
+	// assigned a list of the values that follow the Elems.
+	ListPattern struct {
+		LBracket *Token
+		Elems    []Pattern
+		Rest     Pattern
+		RBracket *Token
+	}
+
+	// StructPattern destructures a struct
+	StructPattern struct {
+		LBrace  *Token
+		Entries []*StructPatternEntry
+		RBrace  *Token
+	}
+
+	// StructPatternEntry destructures one of the fields of a struct
+	StructPatternEntry struct {
+		Key     *Token
+		Pattern Pattern
+	}
+
+	// ValuePattern matches a value that is equal to Val.  It can only be
+	// used in a 'switch' statement.
+	ValuePattern struct {
+		Val Expression
+	}
+
+	// TypePattern matches any value of a given type.  It can only be
+	// used in a 'switch' statement.
+	TypePattern struct {
+		Token *Token
+	}
+)
+
+// Idents returns the identifiers that are defined by a declaration.
+func (n *DeclNode) Idents() []*IdentExpr {
+	if n.Pattern == nil {
+		return []*IdentExpr{n.Ident}
+	}
+	return PatternIdents(n.Pattern)
+}
+
+// PatternIdents returns the identifiers that are assigned by a Pattern,
+// in the order in which they appear.
+func PatternIdents(pattern Pattern) []*IdentExpr {
+
+	idents := []*IdentExpr{}
+
+	var walk func(Pattern)
+	walk = func(p Pattern) {
+		switch t := p.(type) {
+		case *IdentExpr:
+			idents = append(idents, t)
+		case *TuplePattern:
+			for _, e := range t.Elems {
+				walk(e)
+			}
+		case *ListPattern:
+			for _, e := range t.Elems {
+				walk(e)
+			}
+			if t.Rest != nil {
+				walk(t.Rest)
+			}
+		case *StructPattern:
+			for _, e := range t.Entries {
+				walk(e.Pattern)
+			}
+		}
+	}
+	walk(pattern)
+
+	return idents
+}
+
 //--------------------------------------------------------------
 // markers
 
@@ -389,23 +557,27 @@ func (*IfStmt) stmtMarker()       {}
 func (*WhileStmt) stmtMarker()    {}
 func (*ForStmt) stmtMarker()      {}
 func (*SwitchStmt) stmtMarker()   {}
+func (*SelectStmt) stmtMarker()   {}
 func (*BreakStmt) stmtMarker()    {}
 func (*ContinueStmt) stmtMarker() {}
 func (*ReturnStmt) stmtMarker()   {}
+func (*YieldStmt) stmtMarker()    {}
 func (*ThrowStmt) stmtMarker()    {}
 func (*TryStmt) stmtMarker()      {}
-func (*GoStmt) stmtMarker()       {}
+func (*DeferStmt) stmtMarker()    {}
 func (*ExprStmt) stmtMarker()     {}
 
 func (*WhileStmt) loopMarker() {}
 func (*ForStmt) loopMarker()   {}
 
 func (*AssignmentExpr) exprMarker() {}
+func (*GoExpr) exprMarker()         {}
 func (*TernaryExpr) exprMarker()    {}
 func (*BinaryExpr) exprMarker()     {}
 func (*UnaryExpr) exprMarker()      {}
 func (*PostfixExpr) exprMarker()    {}
 func (*BasicExpr) exprMarker()      {}
+func (*InterpStrExpr) exprMarker()  {}
 func (*IdentExpr) exprMarker()      {}
 func (*BuiltinExpr) exprMarker()    {}
 func (*FnExpr) exprMarker()         {}
@@ -422,6 +594,18 @@ func (*SliceExpr) exprMarker()      {}
 func (*SliceFromExpr) exprMarker()  {}
 func (*SliceToExpr) exprMarker()    {}
 
+func (*DestructureExpr) exprMarker() {}
+func (*BlankExpr) exprMarker()       {}
+func (*RestExpr) exprMarker()        {}
+
+func (*IdentExpr) patternMarker()     {}
+func (*BlankExpr) patternMarker()     {}
+func (*TuplePattern) patternMarker()  {}
+func (*ListPattern) patternMarker()   {}
+func (*StructPattern) patternMarker() {}
+func (*ValuePattern) patternMarker()  {}
+func (*TypePattern) patternMarker()   {}
+
 func (*IdentExpr) assignableMarker()   {}
 func (*BuiltinExpr) assignableMarker() {}
 func (*FieldExpr) assignableMarker()   {}
@@ -445,7 +629,12 @@ func (n *ImportStmt) Begin() Pos { return n.Token.Position }
 func (n *ImportStmt) End() Pos { return n.Idents[len(n.Idents)-1].End() }
 
 // Begin DeclNode
-func (n *DeclNode) Begin() Pos { return n.Ident.Begin() }
+func (n *DeclNode) Begin() Pos {
+	if n.Pattern != nil {
+		return n.Pattern.Begin()
+	}
+	return n.Ident.Begin()
+}
 
 // End DeclNode
 func (n *DeclNode) End() Pos {
@@ -508,6 +697,18 @@ func (n *CaseNode) Begin() Pos { return n.Token.Position }
 // End CaseNode
 func (n *CaseNode) End() Pos { return n.Body[len(n.Body)-1].End() }
 
+// Begin SelectStmt
+func (n *SelectStmt) Begin() Pos { return n.Token.Position }
+
+// End SelectStmt
+func (n *SelectStmt) End() Pos { return n.RBrace.Position }
+
+// Begin SelectCaseNode
+func (n *SelectCaseNode) Begin() Pos { return n.Token.Position }
+
+// End SelectCaseNode
+func (n *SelectCaseNode) End() Pos { return n.Body[len(n.Body)-1].End() }
+
 // Begin DefaultNode
 func (n *DefaultNode) Begin() Pos { return n.Token.Position }
 
@@ -537,6 +738,12 @@ func (n *ReturnStmt) End() Pos {
 	return n.Val.End()
 }
 
+// Begin YieldStmt
+func (n *YieldStmt) Begin() Pos { return n.Token.Position }
+
+// End YieldStmt
+func (n *YieldStmt) End() Pos { return n.Val.End() }
+
 // Begin ThrowStmt
 func (n *ThrowStmt) Begin() Pos { return n.Token.Position }
 
@@ -554,11 +761,17 @@ func (n *TryStmt) End() Pos {
 	return n.FinallyBlock.End()
 }
 
-// Begin GoStmt
-func (n *GoStmt) Begin() Pos { return n.Token.Position }
+// Begin GoExpr
+func (n *GoExpr) Begin() Pos { return n.Token.Position }
+
+// End GoExpr
+func (n *GoExpr) End() Pos { return n.Invocation.End() }
+
+// Begin DeferStmt
+func (n *DeferStmt) Begin() Pos { return n.Token.Position }
 
-// End GoStmt
-func (n *GoStmt) End() Pos { return n.Invocation.End() }
+// End DeferStmt
+func (n *DeferStmt) End() Pos { return n.Invocation.End() }
 
 // Begin ExprStmt
 func (n *ExprStmt) Begin() Pos { return n.Expr.Begin() }
@@ -566,6 +779,58 @@ func (n *ExprStmt) Begin() Pos { return n.Expr.Begin() }
 // End ExprStmt
 func (n *ExprStmt) End() Pos { return n.Expr.End() }
 
+// Begin DestructureExpr
+func (n *DestructureExpr) Begin() Pos { return n.Pattern.Begin() }
+
+// End DestructureExpr
+func (n *DestructureExpr) End() Pos { return n.Val.End() }
+
+// Begin BlankExpr
+func (n *BlankExpr) Begin() Pos { return n.Token.Position }
+
+// End BlankExpr
+func (n *BlankExpr) End() Pos { return n.Token.Position }
+
+// Begin RestExpr
+func (n *RestExpr) Begin() Pos { return n.TripleDot.Position }
+
+// End RestExpr
+func (n *RestExpr) End() Pos { return n.Rest.End() }
+
+// Begin TuplePattern
+func (n *TuplePattern) Begin() Pos { return n.LParen.Position }
+
+// End TuplePattern
+func (n *TuplePattern) End() Pos { return n.RParen.Position }
+
+// Begin ListPattern
+func (n *ListPattern) Begin() Pos { return n.LBracket.Position }
+
+// End ListPattern
+func (n *ListPattern) End() Pos { return n.RBracket.Position }
+
+// Begin StructPattern
+func (n *StructPattern) Begin() Pos { return n.LBrace.Position }
+
+// End StructPattern
+func (n *StructPattern) End() Pos { return n.RBrace.Position }
+
+// Begin ValuePattern
+func (n *ValuePattern) Begin() Pos { return n.Val.Begin() }
+
+// End ValuePattern
+func (n *ValuePattern) End() Pos { return n.Val.End() }
+
+// Begin TypePattern
+func (n *TypePattern) Begin() Pos { return n.Token.Position }
+
+// End TypePattern
+func (n *TypePattern) End() Pos {
+	return Pos{
+		n.Token.Position.Line,
+		n.Token.Position.Col + len(n.Token.Text) - 1}
+}
+
 // Begin AssignmentExpr
 func (n *AssignmentExpr) Begin() Pos { return n.Assignee.Begin() }
 
@@ -606,6 +871,17 @@ func (n *BasicExpr) End() Pos {
 		n.Token.Position.Col + len(n.Token.Text) - 1}
 }
 
+// Begin InterpStrExpr
+func (n *InterpStrExpr) Begin() Pos { return n.Tokens[0].Position }
+
+// End InterpStrExpr
+func (n *InterpStrExpr) End() Pos {
+	last := n.Tokens[len(n.Tokens)-1]
+	return Pos{
+		last.Position.Line,
+		last.Position.Col + len(last.Text) + 1}
+}
+
 // Begin IdentExpr
 func (n *IdentExpr) Begin() Pos { return n.Symbol.Position }
 
@@ -787,7 +1063,11 @@ func stringDecls(decls []*DeclNode) string {
 		if i > 0 {
 			buf.WriteString(", ")
 		}
-		buf.WriteString(fmt.Sprintf("%v", d.Ident))
+		if d.Pattern != nil {
+			buf.WriteString(fmt.Sprintf("%v", d.Pattern))
+		} else {
+			buf.WriteString(fmt.Sprintf("%v", d.Ident))
+		}
 		if d.Val != nil {
 			buf.WriteString(fmt.Sprintf(" = %v", d.Val))
 		}
@@ -799,6 +1079,74 @@ func (n *AssignmentExpr) String() string {
 	return fmt.Sprintf("(%v = %v)", n.Assignee, n.Val)
 }
 
+func (n *DestructureExpr) String() string {
+	return fmt.Sprintf("(%v = %v)", n.Pattern, n.Val)
+}
+
+func (n *BlankExpr) String() string {
+	return "_"
+}
+
+func (n *RestExpr) String() string {
+	return "..." + n.Rest.String()
+}
+
+func (n *TuplePattern) String() string {
+	var buf bytes.Buffer
+	buf.WriteString("(")
+	for idx, p := range n.Elems {
+		if idx > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(p.String())
+	}
+	buf.WriteString(")")
+	return buf.String()
+}
+
+func (n *ListPattern) String() string {
+	var buf bytes.Buffer
+	buf.WriteString("[ ")
+	for idx, p := range n.Elems {
+		if idx > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(p.String())
+	}
+	if n.Rest != nil {
+		if len(n.Elems) > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString("...")
+		buf.WriteString(n.Rest.String())
+	}
+	buf.WriteString(" ]")
+	return buf.String()
+}
+
+func (n *StructPattern) String() string {
+	var buf bytes.Buffer
+	buf.WriteString("{ ")
+	for idx, e := range n.Entries {
+		if idx > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(e.Key.Text)
+		buf.WriteString(": ")
+		buf.WriteString(e.Pattern.String())
+	}
+	buf.WriteString(" }")
+	return buf.String()
+}
+
+func (n *ValuePattern) String() string {
+	return n.Val.String()
+}
+
+func (n *TypePattern) String() string {
+	return n.Token.Text
+}
+
 func (n *IfStmt) String() string {
 	if n.Else == nil {
 		return fmt.Sprintf("if %v %v;", n.Cond, n.Then)
@@ -866,6 +1214,15 @@ func (n *CaseNode) String() string {
 		}
 		buf.WriteString(fmt.Sprintf("%v", m))
 	}
+	for i, p := range n.Patterns {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(fmt.Sprintf("%v", p))
+	}
+	if n.Guard != nil {
+		buf.WriteString(fmt.Sprintf(" if %v", n.Guard))
+	}
 
 	buf.WriteString(": ")
 	writeStatements(n.Body, &buf)
@@ -873,6 +1230,49 @@ func (n *CaseNode) String() string {
 	return buf.String()
 }
 
+func (n *SelectStmt) String() string {
+	var buf bytes.Buffer
+
+	buf.WriteString("select { ")
+	for i, c := range n.Cases {
+		if i > 0 {
+			buf.WriteString(" ")
+		}
+		buf.WriteString(fmt.Sprintf("%v", c))
+	}
+	if n.DefaultNode != nil {
+		buf.WriteString(fmt.Sprintf("%v", n.DefaultNode))
+	}
+	buf.WriteString(" };")
+
+	return buf.String()
+}
+
+func (n *SelectCaseNode) String() string {
+	var buf bytes.Buffer
+
+	buf.WriteString("case ")
+	if n.Assignee != nil {
+		buf.WriteString(fmt.Sprintf("%v = ", n.Assignee))
+	}
+	buf.WriteString(fmt.Sprintf("%v", n.Comm))
+
+	buf.WriteString(": ")
+	writeStatements(n.Body, &buf)
+
+	return buf.String()
+}
+
+// IsSend returns whether the case is a 'send()' invocation.
+func (n *SelectCaseNode) IsSend() bool {
+	return n.Comm.Operand.(*FieldExpr).Key.Text == "send"
+}
+
+// Chan returns the channel expression of the case.
+func (n *SelectCaseNode) Chan() Expression {
+	return n.Comm.Operand.(*FieldExpr).Operand
+}
+
 func (n *DefaultNode) String() string {
 	var buf bytes.Buffer
 
@@ -897,6 +1297,10 @@ func (n *ReturnStmt) String() string {
 	return fmt.Sprintf("return %v;", n.Val)
 }
 
+func (n *YieldStmt) String() string {
+	return fmt.Sprintf("yield %v;", n.Val)
+}
+
 func (n *ThrowStmt) String() string {
 	return fmt.Sprintf("throw %v;", n.Val)
 }
@@ -911,6 +1315,10 @@ func (n *TryStmt) String() string {
 	if n.CatchToken != nil {
 		buf.WriteString(" catch ")
 		buf.WriteString(n.CatchIdent.String())
+		if n.CatchGuard != nil {
+			buf.WriteString(" if ")
+			buf.WriteString(n.CatchGuard.String())
+		}
 		buf.WriteString(" ")
 		buf.WriteString(n.CatchBlock.String())
 	}
@@ -924,8 +1332,12 @@ func (n *TryStmt) String() string {
 	return buf.String()
 }
 
-func (n *GoStmt) String() string {
-	return fmt.Sprintf("go %v;", n.Invocation)
+func (n *GoExpr) String() string {
+	return fmt.Sprintf("go %v", n.Invocation)
+}
+
+func (n *DeferStmt) String() string {
+	return fmt.Sprintf("defer %v;", n.Invocation)
 }
 
 func (n *ExprStmt) String() string {
@@ -956,6 +1368,19 @@ func (n *BasicExpr) String() string {
 	return n.Token.Text
 }
 
+func (n *InterpStrExpr) String() string {
+	var buf bytes.Buffer
+	buf.WriteString("`")
+	for i, t := range n.Tokens {
+		buf.WriteString(t.Text)
+		if i < len(n.Exprs) {
+			buf.WriteString(fmt.Sprintf("${%v}", n.Exprs[i]))
+		}
+	}
+	buf.WriteString("`")
+	return buf.String()
+}
+
 func (n *IdentExpr) String() string {
 	return n.Symbol.Text
 }
@@ -37,6 +37,10 @@ type (
 
 		NumCaptures() int
 		GetCaptures() []Capture
+
+		// IsGenerator returns whether the function contains a 'yield'
+		IsGenerator() bool
+		SetGenerator()
 	}
 
 	// Capture defines a parent/child relationship between a 'child' captured Variable,
@@ -134,8 +138,9 @@ func (s *structScope) structMarker() {}
 
 type funcScope struct {
 	scope
-	numLocals int
-	captures  map[string]Capture
+	numLocals   int
+	captures    map[string]Capture
+	isGenerator bool
 }
 
 // NewFuncScope creates a new FuncScope
@@ -144,6 +149,7 @@ func NewFuncScope() FuncScope {
 		scope{make(map[string]Variable)},
 		0,
 		make(map[string]Capture),
+		false,
 	}
 }
 
@@ -169,6 +175,14 @@ func (s *funcScope) IncrementNumLocals() {
 	s.numLocals++
 }
 
+func (s *funcScope) IsGenerator() bool {
+	return s.isGenerator
+}
+
+func (s *funcScope) SetGenerator() {
+	s.isGenerator = true
+}
+
 func (s *funcScope) GetCapture(sym string) (Capture, bool) {
 	cp, ok := s.captures[sym]
 	return cp, ok
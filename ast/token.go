@@ -12,6 +12,7 @@ import (
 // Pos
 
 // Pos represents a Line-and-Column location in Golem source code.
+// Both are 1-based, and the column is a byte offset within the line.
 type Pos struct {
 	Line int
 	Col  int
@@ -101,6 +102,10 @@ const (
 	Float
 	basicEnd
 
+	InterpBegin
+	InterpMiddle
+	InterpEnd
+
 	Ident
 	MagicField
 
@@ -112,6 +117,7 @@ const (
 	Continue
 	Fn
 	Return
+	Yield
 	Const
 	Let
 	For
@@ -132,6 +138,8 @@ const (
 	Throw
 
 	Go
+	Defer
+	Select
 
 	Import
 
@@ -237,6 +245,13 @@ func (t TokenKind) String() string {
 	case Float:
 		return "Float"
 
+	case InterpBegin:
+		return "InterpBegin"
+	case InterpMiddle:
+		return "InterpMiddle"
+	case InterpEnd:
+		return "InterpEnd"
+
 	case Ident:
 		return "Ident"
 	case MagicField:
@@ -256,6 +271,8 @@ func (t TokenKind) String() string {
 		return "Fn"
 	case Return:
 		return "Return"
+	case Yield:
+		return "Yield"
 	case Const:
 		return "Const"
 	case Let:
@@ -293,6 +310,10 @@ func (t TokenKind) String() string {
 
 	case Go:
 		return "Go"
+	case Defer:
+		return "Defer"
+	case Select:
+		return "Select"
 
 	case Import:
 		return "Import"
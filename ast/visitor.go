@@ -25,7 +25,11 @@ func (imp *ImportStmt) Traverse(v Visitor) {
 // Traverse ConstStmt
 func (cns *ConstStmt) Traverse(v Visitor) {
 	for _, d := range cns.Decls {
-		v.Visit(d.Ident)
+		if d.Pattern != nil {
+			v.Visit(d.Pattern)
+		} else {
+			v.Visit(d.Ident)
+		}
 		if d.Val != nil {
 			v.Visit(d.Val)
 		}
@@ -35,7 +39,11 @@ func (cns *ConstStmt) Traverse(v Visitor) {
 // Traverse LetStmt
 func (let *LetStmt) Traverse(v Visitor) {
 	for _, d := range let.Decls {
-		v.Visit(d.Ident)
+		if d.Pattern != nil {
+			v.Visit(d.Pattern)
+		} else {
+			v.Visit(d.Ident)
+		}
 		if d.Val != nil {
 			v.Visit(d.Val)
 		}
@@ -54,6 +62,54 @@ func (asn *AssignmentExpr) Traverse(v Visitor) {
 	v.Visit(asn.Val)
 }
 
+// Traverse DestructureExpr
+func (ds *DestructureExpr) Traverse(v Visitor) {
+	v.Visit(ds.Pattern)
+	v.Visit(ds.Val)
+}
+
+// Traverse BlankExpr
+func (b *BlankExpr) Traverse(v Visitor) {
+}
+
+// Traverse RestExpr
+func (r *RestExpr) Traverse(v Visitor) {
+	v.Visit(r.Rest)
+}
+
+// Traverse TuplePattern
+func (tp *TuplePattern) Traverse(v Visitor) {
+	for _, p := range tp.Elems {
+		v.Visit(p)
+	}
+}
+
+// Traverse ListPattern
+func (ls *ListPattern) Traverse(v Visitor) {
+	for _, p := range ls.Elems {
+		v.Visit(p)
+	}
+	if ls.Rest != nil {
+		v.Visit(ls.Rest)
+	}
+}
+
+// Traverse StructPattern
+func (stc *StructPattern) Traverse(v Visitor) {
+	for _, e := range stc.Entries {
+		v.Visit(e.Pattern)
+	}
+}
+
+// Traverse ValuePattern
+func (vp *ValuePattern) Traverse(v Visitor) {
+	v.Visit(vp.Val)
+}
+
+// Traverse TypePattern
+func (tp *TypePattern) Traverse(v Visitor) {
+}
+
 // Traverse IfStmt
 func (ifn *IfStmt) Traverse(v Visitor) {
 	v.Visit(ifn.Cond)
@@ -100,6 +156,38 @@ func (cs *CaseNode) Traverse(v Visitor) {
 		v.Visit(n)
 	}
 
+	for _, p := range cs.Patterns {
+		v.Visit(p)
+	}
+
+	if cs.Guard != nil {
+		v.Visit(cs.Guard)
+	}
+
+	for _, n := range cs.Body {
+		v.Visit(n)
+	}
+}
+
+// Traverse SelectStmt
+func (sel *SelectStmt) Traverse(v Visitor) {
+	for _, cs := range sel.Cases {
+		v.Visit(cs)
+	}
+
+	if sel.DefaultNode != nil {
+		v.Visit(sel.DefaultNode)
+	}
+}
+
+// Traverse SelectCaseNode
+func (cs *SelectCaseNode) Traverse(v Visitor) {
+	v.Visit(cs.Comm)
+
+	if cs.Assignee != nil {
+		v.Visit(cs.Assignee)
+	}
+
 	for _, n := range cs.Body {
 		v.Visit(n)
 	}
@@ -127,6 +215,11 @@ func (rt *ReturnStmt) Traverse(v Visitor) {
 	}
 }
 
+// Traverse YieldStmt
+func (y *YieldStmt) Traverse(v Visitor) {
+	v.Visit(y.Val)
+}
+
 // Traverse ThrowStmt
 func (t *ThrowStmt) Traverse(v Visitor) {
 	v.Visit(t.Val)
@@ -137,6 +230,9 @@ func (t *TryStmt) Traverse(v Visitor) {
 	v.Visit(t.TryBlock)
 	if t.CatchToken != nil {
 		v.Visit(t.CatchIdent)
+		if t.CatchGuard != nil {
+			v.Visit(t.CatchGuard)
+		}
 		v.Visit(t.CatchBlock)
 	}
 	if t.FinallyToken != nil {
@@ -144,11 +240,16 @@ func (t *TryStmt) Traverse(v Visitor) {
 	}
 }
 
-// Traverse GoStmt
-func (g *GoStmt) Traverse(v Visitor) {
+// Traverse GoExpr
+func (g *GoExpr) Traverse(v Visitor) {
 	v.Visit(g.Invocation)
 }
 
+// Traverse DeferStmt
+func (d *DeferStmt) Traverse(v Visitor) {
+	v.Visit(d.Invocation)
+}
+
 // Traverse ExprStmt
 func (n *ExprStmt) Traverse(v Visitor) {
 	v.Visit(n.Expr)
@@ -188,6 +289,13 @@ func (pf *PostfixExpr) Traverse(v Visitor) {
 func (basic *BasicExpr) Traverse(v Visitor) {
 }
 
+// Traverse InterpStrExpr
+func (is *InterpStrExpr) Traverse(v Visitor) {
+	for _, e := range is.Exprs {
+		v.Visit(e)
+	}
+}
+
 // Traverse IdentExpr
 func (ident *IdentExpr) Traverse(v Visitor) {
 }
@@ -355,18 +463,48 @@ func (p *dump) Visit(node Node) {
 		p.buf.WriteString("ContinueStmt\n")
 	case *ReturnStmt:
 		p.buf.WriteString("ReturnStmt\n")
+	case *YieldStmt:
+		p.buf.WriteString("YieldStmt\n")
 	case *ThrowStmt:
 		p.buf.WriteString("ThrowStmt\n")
 	case *TryStmt:
 		p.buf.WriteString(fmt.Sprintf("TryStmt(%v)\n", t.CatchScope))
-	case *GoStmt:
-		p.buf.WriteString("GoStmt\n")
+	case *SwitchStmt:
+		p.buf.WriteString("SwitchStmt\n")
+	case *CaseNode:
+		p.buf.WriteString(fmt.Sprintf("CaseNode(%v)\n", t.Scope))
+	case *SelectStmt:
+		p.buf.WriteString("SelectStmt\n")
+	case *SelectCaseNode:
+		p.buf.WriteString("SelectCaseNode\n")
+	case *DefaultNode:
+		p.buf.WriteString("DefaultNode\n")
+	case *DeferStmt:
+		p.buf.WriteString("DeferStmt\n")
 
 	case *ExprStmt:
 		p.buf.WriteString("ExprStmt\n")
 
 	case *AssignmentExpr:
 		p.buf.WriteString("AssignmentExpr\n")
+	case *DestructureExpr:
+		p.buf.WriteString("DestructureExpr\n")
+	case *BlankExpr:
+		p.buf.WriteString("BlankExpr\n")
+	case *RestExpr:
+		p.buf.WriteString("RestExpr\n")
+	case *TuplePattern:
+		p.buf.WriteString("TuplePattern\n")
+	case *ListPattern:
+		p.buf.WriteString("ListPattern\n")
+	case *StructPattern:
+		p.buf.WriteString("StructPattern\n")
+	case *ValuePattern:
+		p.buf.WriteString("ValuePattern\n")
+	case *TypePattern:
+		p.buf.WriteString(fmt.Sprintf("TypePattern(%s)\n", t.Token.Text))
+	case *GoExpr:
+		p.buf.WriteString("GoExpr\n")
 	case *BinaryExpr:
 		p.buf.WriteString(fmt.Sprintf("BinaryExpr(%q)\n", t.Op.Text))
 	case *UnaryExpr:
@@ -375,6 +513,8 @@ func (p *dump) Visit(node Node) {
 		p.buf.WriteString(fmt.Sprintf("PostfixExpr(%q)\n", t.Op.Text))
 	case *BasicExpr:
 		p.buf.WriteString(fmt.Sprintf("BasicExpr(%v,%q)\n", t.Token.Kind, t.Token.Text))
+	case *InterpStrExpr:
+		p.buf.WriteString(fmt.Sprintf("InterpStrExpr(%s)\n", tokensString(t.Tokens)))
 	case *IdentExpr:
 		p.buf.WriteString(fmt.Sprintf("IdentExpr(%v,%v)\n", t.Symbol.Text, t.Variable))
 
@@ -0,0 +1,93 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mjarmy/golem-lang/compiler"
+	g "github.com/mjarmy/golem-lang/core"
+	bc "github.com/mjarmy/golem-lang/core/bytecode"
+)
+
+//-------------------------------------------------------------
+// Compiled modules
+//-------------------------------------------------------------
+
+// buildFiles compiles each of the given source files, and writes the
+// resulting module to a '.glmc' file alongside the source file.
+func buildFiles(builtins []*g.Builtin, filenames []string, optimize bool) {
+
+	for _, filename := range filenames {
+
+		src, e := readSourceFromFile(filename)
+		if e != nil {
+			exitError(e)
+		}
+
+		mod, err := compiler.CompileSource(src, builtins, compileOptions(optimize)...)
+		if err != nil {
+			exitCompileError(err, src.Code)
+		}
+
+		if e := writeCompiledModule(compiledPath(src.Path), mod, builtins); e != nil {
+			exitError(e)
+		}
+	}
+}
+
+// compiledPath returns the path of the compiled module for a source file.
+func compiledPath(sourcePath string) string {
+	return strings.TrimSuffix(sourcePath, ".glm") + ".glmc"
+}
+
+func writeCompiledModule(path string, mod *bc.Module, builtins []*g.Builtin) error {
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := bc.WriteModule(f, mod, builtins); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+func readCompiledModule(path string, builtins []*g.Builtin) (*bc.Module, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	mod, err := bc.ReadModule(f, builtins)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	return mod, nil
+}
+
+// isUpToDate returns whether a compiled module exists, and is at least
+// as new as its source file.  A compiled module without a source file is
+// always up to date.
+func isUpToDate(compiled string, source string) bool {
+
+	ci, err := os.Stat(compiled)
+	if err != nil {
+		return false
+	}
+
+	si, err := os.Stat(source)
+	if err != nil {
+		return true
+	}
+	return !ci.ModTime().Before(si.ModTime())
+}
@@ -0,0 +1,27 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.  Use of this
+// source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+
+	g "github.com/mjarmy/golem-lang/core"
+	"github.com/mjarmy/golem-lang/dap"
+	"github.com/mjarmy/golem-lang/interpreter"
+)
+
+// serveDAP runs a Debug Adapter Protocol server over stdin and stdout.
+func serveDAP(builtins []*g.Builtin, importer interpreter.Importer) {
+
+	// The protocol uses stdout, so make sure that nothing
+	// else can write to it.
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+
+	server := dap.NewServer(os.Stdin, stdout, builtins, importer)
+	if err := server.Serve(); err != nil {
+		exitError(err)
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.  Use of this
+// source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	g "github.com/mjarmy/golem-lang/core"
+	"github.com/mjarmy/golem-lang/interpreter"
+)
+
+//-------------------------------------------------------------
+// An interactive command-line debugger
+//-------------------------------------------------------------
+
+const debugHelp = `Commands:
+    c, continue            run until the next breakpoint
+    n, next                run until the next line in the current function
+    s, step                run until the next line, stepping into functions
+    o, out                 run until the current function returns
+    b, break [file:]line   set a breakpoint
+    d, delete [file:]line  delete a breakpoint
+    bt, where              show the call stack
+    f, frame N             select a frame from the call stack
+    l, locals              show the local variables of the selected frame
+    captures               show the captured variables of the selected frame
+    globals                show the module-level variables of the selected frame
+    stack                  show the operand stack of the selected frame
+    p, print expr          evaluate an expression in the selected frame
+    q, quit                exit the debugger
+`
+
+type debugSession struct {
+	in  *bufio.Scanner
+	out io.Writer
+
+	// the source code that has been read so far, by path
+	sources map[string][]string
+
+	// the frame that is being inspected
+	frames []*interpreter.StackFrame
+	frame  int
+}
+
+func newDebugSession(in io.Reader, out io.Writer) *debugSession {
+	return &debugSession{
+		in:      bufio.NewScanner(in),
+		out:     out,
+		sources: map[string][]string{},
+		frames:  nil,
+		frame:   0,
+	}
+}
+
+// debugFile runs a source file under the control of the debugger.
+// The debugger stops at the first line of the file.
+func debugFile(
+	builtins []*g.Builtin,
+	importer interpreter.Importer,
+	filename string,
+	osArgs []string) {
+
+	ds := newDebugSession(os.Stdin, os.Stdout)
+	fmt.Fprintf(ds.out, "Golem %s debugger.  Type 'help' for help.\n", version)
+
+	d := interpreter.NewDebugger(ds.onStop)
+	d.Pause()
+	runFile(builtins, importer, filename, osArgs, false, interpreter.WithDebugger(d))
+}
+
+func (ds *debugSession) onStop(d *interpreter.Debugger, reason interpreter.StopReason) interpreter.Action {
+
+	ds.frames = d.Frames()
+	ds.frame = 0
+
+	top := ds.frames[0]
+	fmt.Fprintf(ds.out, "Stopped at %s:%d (%s)\n", top.Path, top.Line, reason)
+	ds.showLine(top.Path, top.Line)
+
+	for {
+		fmt.Fprintf(ds.out, "(debug) ")
+		if !ds.in.Scan() {
+			// end of input, so just run to completion
+			fmt.Fprintf(ds.out, "\n")
+			return interpreter.Continue
+		}
+
+		fields := strings.Fields(ds.in.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		cmd, args := fields[0], fields[1:]
+
+		switch cmd {
+		case "c", "continue":
+			return interpreter.Continue
+		case "n", "next":
+			return interpreter.StepOver
+		case "s", "step":
+			return interpreter.StepInto
+		case "o", "out":
+			return interpreter.StepOut
+
+		case "b", "break":
+			if path, line, ok := ds.parseLocation(args); ok {
+				d.SetBreakpoint(path, line)
+				fmt.Fprintf(ds.out, "Breakpoint set at %s:%d\n", path, line)
+			}
+		case "d", "delete":
+			if path, line, ok := ds.parseLocation(args); ok {
+				d.ClearBreakpoint(path, line)
+				fmt.Fprintf(ds.out, "Breakpoint deleted at %s:%d\n", path, line)
+			}
+
+		case "bt", "where":
+			for i, f := range ds.frames {
+				marker := " "
+				if i == ds.frame {
+					marker = "*"
+				}
+				if name := f.Func.Template().Name; name != "" {
+					fmt.Fprintf(ds.out, "%s %2d  %s (%s:%d:%d)\n", marker, i, name, f.Path, f.Line, f.Col)
+				} else {
+					fmt.Fprintf(ds.out, "%s %2d  %s:%d:%d\n", marker, i, f.Path, f.Line, f.Col)
+				}
+			}
+		case "f", "frame":
+			if len(args) != 1 {
+				fmt.Fprintf(ds.out, "Usage: frame N\n")
+				continue
+			}
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 0 || n >= len(ds.frames) {
+				fmt.Fprintf(ds.out, "Invalid frame '%s'\n", args[0])
+				continue
+			}
+			ds.frame = n
+			f := ds.frames[n]
+			fmt.Fprintf(ds.out, "%s:%d\n", f.Path, f.Line)
+			ds.showLine(f.Path, f.Line)
+
+		case "l", "locals":
+			ds.showVariables(d, ds.frames[ds.frame].Locals)
+		case "captures":
+			ds.showVariables(d, ds.frames[ds.frame].Captures)
+		case "globals":
+			mod := ds.frames[ds.frame].Func.Template().Module
+			ds.showVariables(d, interpreter.Globals(mod))
+		case "stack":
+			for i, v := range ds.frames[ds.frame].Stack {
+				fmt.Fprintf(ds.out, "    %d: %s\n", i, ds.format(d, v))
+			}
+		case "p", "print":
+			if len(args) == 0 {
+				fmt.Fprintf(ds.out, "Usage: print expr\n")
+				continue
+			}
+			expr := strings.Join(args, " ")
+			if v, err := d.Evaluate(ds.frames[ds.frame], expr); err == nil {
+				fmt.Fprintf(ds.out, "%s\n", ds.format(d, v))
+			} else {
+				fmt.Fprintf(ds.out, "%s\n", err.Error())
+			}
+
+		case "h", "help":
+			fmt.Fprint(ds.out, debugHelp)
+		case "q", "quit":
+			os.Exit(0)
+		default:
+			fmt.Fprintf(ds.out, "Unknown command '%s'.  Type 'help' for help.\n", cmd)
+		}
+	}
+}
+
+// parseLocation parses a breakpoint location of the form '[file:]line'.
+// If there is no file, the file of the selected frame is used.
+func (ds *debugSession) parseLocation(args []string) (string, int, bool) {
+
+	if len(args) != 1 {
+		fmt.Fprintf(ds.out, "Usage: break [file:]line\n")
+		return "", 0, false
+	}
+
+	path := ds.frames[ds.frame].Path
+	loc := args[0]
+	if idx := strings.LastIndex(loc, ":"); idx != -1 {
+		p, err := filepath.Abs(loc[:idx])
+		if err != nil {
+			fmt.Fprintf(ds.out, "%s\n", err.Error())
+			return "", 0, false
+		}
+		path, loc = p, loc[idx+1:]
+	}
+
+	line, err := strconv.Atoi(loc)
+	if err != nil || line < 1 {
+		fmt.Fprintf(ds.out, "Invalid line '%s'\n", loc)
+		return "", 0, false
+	}
+
+	// move the breakpoint to a line that can actually be hit, if we can
+	if path == ds.frames[ds.frame].Path {
+		mod := ds.frames[ds.frame].Func.Template().Module
+		if ln, ok := interpreter.BreakableLine(mod, line); ok {
+			line = ln
+		}
+	}
+
+	return path, line, true
+}
+
+func (ds *debugSession) showVariables(d *interpreter.Debugger, vars []*interpreter.Variable) {
+	for _, v := range vars {
+		fmt.Fprintf(ds.out, "    %s = %s\n", v.Name, ds.format(d, v.Value))
+	}
+}
+
+func (ds *debugSession) format(d *interpreter.Debugger, val g.Value) string {
+	if val == nil {
+		return "<undefined>"
+	}
+	s, err := val.ToStr(d.Interpreter())
+	if err != nil {
+		return fmt.Sprintf("<%s>", err.Error())
+	}
+	return s.String()
+}
+
+// showLine prints a line of source code, if it can be found.
+func (ds *debugSession) showLine(path string, line int) {
+
+	lines, ok := ds.sources[path]
+	if !ok {
+		buf, err := ioutil.ReadFile(path)
+		if err == nil {
+			lines = strings.Split(string(buf), "\n")
+		}
+		ds.sources[path] = lines
+	}
+
+	if line >= 1 && line <= len(lines) {
+		fmt.Fprintf(ds.out, "%5d  %s\n", line, lines[line-1])
+	}
+}
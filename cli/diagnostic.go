@@ -0,0 +1,97 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mjarmy/golem-lang/ast"
+)
+
+// exitCompileError prints the problems that prevented a source file from
+// compiling, and exits.
+func exitCompileError(err error, code string) {
+	printDiagnostics(os.Stdout, err, code)
+	os.Exit(-1)
+}
+
+// printDiagnostics prints the problems that prevented some source code from
+// compiling.  Each problem is followed by the line of code where it was found,
+// with carets underneath the part of the line that caused it.
+func printDiagnostics(w io.Writer, err error, code string) {
+
+	var ds ast.Diagnostics
+	switch t := err.(type) {
+	case ast.Diagnostics:
+		ds = t
+	case *ast.Diagnostic:
+		ds = ast.Diagnostics{t}
+	default:
+		fmt.Fprintf(w, "%s\n", err.Error())
+		return
+	}
+
+	lines := strings.Split(code, "\n")
+	for _, d := range ds {
+		fmt.Fprintf(w, "%s:%v: %s: %s\n", d.Path, d.Start, d.Severity, d.Msg)
+
+		if d.Start.Line < 1 || d.Start.Line > len(lines) {
+			continue
+		}
+		line := strings.TrimRight(lines[d.Start.Line-1], "\r")
+		fmt.Fprintf(w, "    %s\n", line)
+		fmt.Fprintf(w, "    %s\n", carets(line, d))
+	}
+}
+
+// carets underlines the part of a line that a Diagnostic refers to.
+func carets(line string, d *ast.Diagnostic) string {
+
+	var buf strings.Builder
+
+	// The columns are byte offsets, but the carets are lined up
+	// with the characters of the line.  Any tabs are kept, so that the
+	// carets line up with the code.
+	start := d.Start.Col - 1
+	for i, r := range line {
+		if i >= start {
+			break
+		}
+		if r == '\t' {
+			buf.WriteRune('\t')
+		} else {
+			buf.WriteRune(' ')
+		}
+	}
+	for i := len(line); i < start; i++ {
+		buf.WriteRune(' ')
+	}
+
+	n := 1
+	if d.End.Line == d.Start.Line && d.End.Col > d.Start.Col {
+		n = width(line, start, d.End.Col-1)
+	}
+	buf.WriteString(strings.Repeat("^", n))
+
+	return buf.String()
+}
+
+// width returns the number of characters between two byte offsets in a line.
+// Any offsets past the end of the line count as one character each.
+func width(line string, begin int, end int) int {
+	n := 0
+	if begin < len(line) {
+		if end < len(line) {
+			return utf8.RuneCountInString(line[begin:end])
+		}
+		n = utf8.RuneCountInString(line[begin:])
+		begin = len(line)
+	}
+	return n + end - begin
+}
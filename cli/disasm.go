@@ -0,0 +1,49 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/mjarmy/golem-lang/compiler"
+	g "github.com/mjarmy/golem-lang/core"
+	bc "github.com/mjarmy/golem-lang/core/bytecode"
+)
+
+// disasmFile prints a listing of the bytecode for a source file or compiled module.
+func disasmFile(builtins []*g.Builtin, filename string, optimize bool) {
+
+	var mod *bc.Module
+	var code string
+
+	if filepath.Ext(filename) == ".glmc" {
+		m, e := readCompiledModule(filename, builtins)
+		if e != nil {
+			exitError(e)
+		}
+		mod = m
+
+		// show the source code too, if it is available
+		if buf, e := ioutil.ReadFile(mod.Path); e == nil {
+			code = string(buf)
+		}
+	} else {
+		src, e := readSourceFromFile(filename)
+		if e != nil {
+			exitError(e)
+		}
+
+		m, err := compiler.CompileSource(src, builtins, compileOptions(optimize)...)
+		if err != nil {
+			exitCompileError(err, src.Code)
+		}
+		mod = m
+		code = src.Code
+	}
+
+	fmt.Print(bc.Disassemble(mod, builtins, code))
+}
@@ -5,6 +5,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -62,22 +63,34 @@ func readSourceFromFile(filename string) (*scanner.Source, error) {
 	return src, nil
 }
 
+// compileOptions returns the options for compiling source code.
+func compileOptions(optimize bool) []compiler.Option {
+	if optimize {
+		return []compiler.Option{compiler.WithOptimization()}
+	}
+	return nil
+}
+
 type importer struct {
 	builtins  []*g.Builtin
 	moduleMap map[string]g.Module
 	localDir  string
+	optimize  bool
+	debugging bool
 }
 
 func newImporter(
 	builtins []*g.Builtin,
 	modules []g.Module,
-	localDir string) interpreter.Importer {
+	localDir string,
+	optimize bool,
+	debugging bool) interpreter.Importer {
 
 	var moduleMap = map[string]g.Module{}
 	for _, m := range modules {
 		moduleMap[m.Name()] = m
 	}
-	return &importer{builtins, moduleMap, localDir}
+	return &importer{builtins, moduleMap, localDir, optimize, debugging}
 }
 
 func (imp *importer) GetModule(
@@ -88,16 +101,7 @@ func (imp *importer) GetModule(
 		return m, nil
 	}
 
-	path := imp.localDir + "/" + name + ".glm"
-	if _, err := os.Stat(path); err != nil {
-		return nil, fmt.Errorf("Cannot resolve module '%s'", name)
-	}
-	src, err := readSourceFromFile(path)
-	if err != nil {
-		return nil, err
-	}
-
-	m, err := compiler.CompileSource(src, imp.builtins)
+	m, err := imp.loadModule(name)
 	if err != nil {
 		return nil, err
 	}
@@ -111,9 +115,38 @@ func (imp *importer) GetModule(
 	return m, nil
 }
 
-func commandLineArguments() g.List {
+// loadModule loads a module from the local directory.  A compiled module is
+// used instead of the source code if it is up to date.  When debugging, the
+// source code is always used if it exists, since a compiled module may have
+// been optimized.
+func (imp *importer) loadModule(name string) (*bc.Module, error) {
+
+	path := imp.localDir + "/" + name + ".glm"
+	compiled := compiledPath(path)
+
+	_, srcErr := os.Stat(path)
+	if isUpToDate(compiled, path) && (!imp.debugging || srcErr != nil) {
+		m, err := readCompiledModule(compiled, imp.builtins)
+		if err == nil || srcErr != nil {
+			return m, err
+		}
+		// fall back to the source code if the compiled module is unusable,
+		// e.g. because it was built by a different version of golem
+		fmt.Fprintf(os.Stderr, "Ignoring %s, compiling %s instead\n", err.Error(), path)
+	}
+
+	if srcErr != nil {
+		return nil, fmt.Errorf("Cannot resolve module '%s'", name)
+	}
+	src, err := readSourceFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return compiler.CompileSource(src, imp.builtins, compileOptions(imp.optimize)...)
+}
+
+func commandLineArguments(osArgs []string) g.List {
 
-	osArgs := os.Args[2:]
 	args := make([]g.Value, len(osArgs))
 	for i, o := range osArgs {
 		a, e := g.NewStr(o)
@@ -129,10 +162,23 @@ func commandLineArguments() g.List {
 
 func main() {
 
-	if len(os.Args) == 1 {
-		fmt.Printf("Golem %s\n", version)
-		os.Exit(0)
+	//-------------------------------------------------------------
+	// flags
+	//-------------------------------------------------------------
+
+	optFlag := flag.Bool("O", false, "optimize the compiled bytecode")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"Usage: golem [-O] [<file.glm> [args...] | debug | build | disasm | dap | lsp]\n")
+		flag.PrintDefaults()
 	}
+	flag.Parse()
+	args := flag.Args()
+
+	// Debugging sessions never use optimized bytecode, so that breakpoints
+	// and local variables always correspond to the source code.
+	debugging := len(args) > 0 && (args[0] == "debug" || args[0] == "dap")
+	optimize := *optFlag && !debugging
 
 	//-------------------------------------------------------------
 	// builtins and modules
@@ -149,26 +195,90 @@ func main() {
 	if e != nil {
 		exitError(e)
 	}
-	importer := newImporter(builtins, library, localDir)
+	importer := newImporter(builtins, library, localDir, optimize, debugging)
 
 	//-------------------------------------------------------------
-	// parse, compile, interpret
+	// start a REPL if there is no source file
 	//-------------------------------------------------------------
 
-	// read source
-	src, e := readSourceFromFile(os.Args[1])
-	if e != nil {
-		exitError(e)
+	if len(args) == 0 {
+		itp := interpreter.NewInterpreter(builtins, importer)
+		itp.SetGoErrorHandler(func(es interpreter.ErrorStruct) {
+			fmt.Print(es.String())
+		})
+		newRepl(builtins, itp).run(os.Stdin, os.Stdout)
+		return
 	}
 
-	// compile
-	mod, err := compiler.CompileSource(src, builtins)
-	if err != nil {
-		exitError(err)
+	//-------------------------------------------------------------
+	// run a command, or a source file
+	//-------------------------------------------------------------
+
+	switch args[0] {
+	case "debug":
+		if len(args) < 2 {
+			exitError(fmt.Errorf("Usage: golem debug <file.glm> [args...]"))
+		}
+		debugFile(builtins, importer, args[1], args[2:])
+	case "build":
+		if len(args) < 2 {
+			exitError(fmt.Errorf("Usage: golem [-O] build <file.glm>..."))
+		}
+		buildFiles(builtins, args[1:], optimize)
+	case "disasm":
+		if len(args) != 2 {
+			exitError(fmt.Errorf("Usage: golem [-O] disasm <file.glm>"))
+		}
+		disasmFile(builtins, args[1], optimize)
+	case "dap":
+		serveDAP(builtins, importer)
+	case "lsp":
+		serveLSP(builtins, library)
+	default:
+		runFile(builtins, importer, args[0], args[1:], optimize)
+	}
+}
+
+// runFile interprets a source file or compiled module, and then runs its
+// main() function if it has one.
+func runFile(
+	builtins []*g.Builtin,
+	importer interpreter.Importer,
+	filename string,
+	osArgs []string,
+	optimize bool,
+	options ...interpreter.Option) {
+
+	//-------------------------------------------------------------
+	// parse, compile, interpret
+	//-------------------------------------------------------------
+
+	// read source and compile, or read a compiled module
+	var mod *bc.Module
+	if filepath.Ext(filename) == ".glmc" {
+		m, e := readCompiledModule(filename, builtins)
+		if e != nil {
+			exitError(e)
+		}
+		mod = m
+	} else {
+		src, e := readSourceFromFile(filename)
+		if e != nil {
+			exitError(e)
+		}
+
+		m, err := compiler.CompileSource(src, builtins, compileOptions(optimize)...)
+		if err != nil {
+			exitCompileError(err, src.Code)
+		}
+		mod = m
 	}
 
 	// interpret
-	itp := interpreter.NewInterpreter(builtins, importer)
+	itp := interpreter.NewInterpreter(builtins, importer, options...)
+	itp.SetGoErrorHandler(func(es interpreter.ErrorStruct) {
+		fmt.Print(es.String())
+	})
 	_, es := itp.EvalModule(mod)
 	if es != nil {
 		exitInterpreter(es)
@@ -201,7 +311,7 @@ func main() {
 	}
 
 	// turn the command line arguments into a List-of-Str
-	argList := commandLineArguments()
+	argList := commandLineArguments(osArgs)
 
 	// interpret the main function
 	_, es = itp.EvalBytecode(mainFn, []g.Value{argList})
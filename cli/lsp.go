@@ -0,0 +1,21 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.  Use of this
+// source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+
+	g "github.com/mjarmy/golem-lang/core"
+	"github.com/mjarmy/golem-lang/lsp"
+)
+
+// serveLSP runs a Language Server Protocol server over stdin and stdout.
+func serveLSP(builtins []*g.Builtin, library []g.Module) {
+
+	server := lsp.NewServer(os.Stdin, os.Stdout, builtins, library)
+	if err := server.Serve(); err != nil {
+		exitError(err)
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.  Use of this
+// source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mjarmy/golem-lang/ast"
+	"github.com/mjarmy/golem-lang/compiler"
+	g "github.com/mjarmy/golem-lang/core"
+	bc "github.com/mjarmy/golem-lang/core/bytecode"
+	"github.com/mjarmy/golem-lang/interpreter"
+	"github.com/mjarmy/golem-lang/scanner"
+)
+
+//-------------------------------------------------------------
+// An interactive read-eval-print loop
+//-------------------------------------------------------------
+
+const historyFile = ".golem_history"
+
+type repl struct {
+	builtins []*g.Builtin
+	itp      *interpreter.Interpreter
+
+	// the module-level variables that have been defined so far
+	globals []*compiler.Global
+	refs    []*bc.Ref
+
+	history []string
+	histOut io.WriteCloser
+	count   int
+}
+
+func newRepl(builtins []*g.Builtin, itp *interpreter.Interpreter) *repl {
+	return &repl{
+		builtins: builtins,
+		itp:      itp,
+		globals:  []*compiler.Global{},
+		refs:     []*bc.Ref{},
+		history:  []string{},
+		histOut:  nil,
+		count:    0,
+	}
+}
+
+func (r *repl) run(in io.Reader, out io.Writer) {
+
+	fmt.Fprintf(out, "Golem %s\n", version)
+	fmt.Fprintf(out, "Type ':help' for help.\n")
+
+	r.openHistory()
+	defer r.closeHistory()
+
+	lines := bufio.NewScanner(in)
+	for {
+		input, ok := readInput(lines, out)
+		if !ok {
+			fmt.Fprintf(out, "\n")
+			return
+		}
+
+		trimmed := strings.TrimSpace(input)
+		switch trimmed {
+		case "":
+			continue
+		case ":quit", ":exit":
+			return
+		case ":help":
+			fmt.Fprintf(out, "Enter Golem code to evaluate it.  Input continues onto\n")
+			fmt.Fprintf(out, "the next line until all of the braces are balanced.\n")
+			fmt.Fprintf(out, "    :history  show the history of inputs\n")
+			fmt.Fprintf(out, "    :quit     exit the session\n")
+			continue
+		case ":history":
+			for i, h := range r.history {
+				fmt.Fprintf(out, "%4d  %s\n", i+1, h)
+			}
+			continue
+		}
+
+		r.addHistory(trimmed)
+		r.eval(input, out)
+	}
+}
+
+// readInput reads lines until all of the brackets in the input are balanced.
+func readInput(lines *bufio.Scanner, out io.Writer) (string, bool) {
+
+	fmt.Fprintf(out, "> ")
+
+	var buf strings.Builder
+	for lines.Scan() {
+		buf.WriteString(lines.Text())
+		buf.WriteString("\n")
+
+		if isComplete(buf.String()) {
+			return buf.String(), true
+		}
+		fmt.Fprintf(out, "... ")
+	}
+
+	// end of input
+	if buf.Len() > 0 {
+		return buf.String(), true
+	}
+	return "", false
+}
+
+// isComplete returns whether some code has no unclosed
+// brackets, strings or comments.
+func isComplete(code string) bool {
+
+	scn, err := scanner.NewScanner(&scanner.Source{Code: code})
+	if err != nil {
+		return true
+	}
+
+	depth := 0
+	for {
+		tok := scn.Next()
+		switch tok.Kind {
+		case ast.Lbrace, ast.Lparen, ast.Lbracket:
+			depth++
+		case ast.Rbrace, ast.Rparen, ast.Rbracket:
+			depth--
+		case ast.UnexpectedEOF:
+			return false
+		case ast.EOF:
+			return depth <= 0
+		}
+	}
+}
+
+func (r *repl) eval(code string, out io.Writer) {
+
+	r.count++
+	source := &scanner.Source{
+		Name: fmt.Sprintf("repl%d", r.count),
+		Path: "<repl>",
+		Code: code,
+	}
+
+	// compile
+	mod, globals, err := compiler.CompileSourceWithGlobals(source, r.builtins, r.globals)
+	if err != nil {
+		printDiagnostics(out, err, code)
+		return
+	}
+
+	// interpret
+	val, es := r.itp.EvalModuleWithRefs(mod, r.refs)
+	if es != nil {
+		fmt.Fprint(out, es.String())
+		return
+	}
+
+	// keep any new globals
+	for _, gl := range globals[len(r.globals):] {
+		r.globals = append(r.globals, &compiler.Global{
+			Name:    gl.Name,
+			IsConst: gl.IsConst,
+			Index:   len(r.refs),
+		})
+		r.refs = append(r.refs, mod.Refs[gl.Index])
+	}
+
+	// print the result
+	if val != nil && val != g.Null {
+		s, err := val.ToStr(r.itp)
+		if err != nil {
+			fmt.Fprintf(out, "%s\n", err.Error())
+			return
+		}
+		fmt.Fprintf(out, "%s\n", s.String())
+	}
+}
+
+//-------------------------------------------------------------
+// history
+//-------------------------------------------------------------
+
+func historyPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, historyFile)
+}
+
+func (r *repl) openHistory() {
+
+	path := historyPath()
+	if path == "" {
+		return
+	}
+
+	if f, err := os.Open(path); err == nil {
+		lines := bufio.NewScanner(f)
+		for lines.Scan() {
+			r.history = append(r.history, unescapeHistory(lines.Text()))
+		}
+		f.Close()
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err == nil {
+		r.histOut = f
+	}
+}
+
+func (r *repl) closeHistory() {
+	if r.histOut != nil {
+		r.histOut.Close()
+	}
+}
+
+func (r *repl) addHistory(input string) {
+	r.history = append(r.history, input)
+	if r.histOut != nil {
+		fmt.Fprintf(r.histOut, "%s\n", escapeHistory(input))
+	}
+}
+
+// multi-line inputs are stored on a single line in the history file
+func escapeHistory(s string) string {
+	s = strings.Replace(s, "\\", "\\\\", -1)
+	return strings.Replace(s, "\n", "\\n", -1)
+}
+
+func unescapeHistory(s string) string {
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			if s[i] == 'n' {
+				buf.WriteByte('\n')
+			} else {
+				buf.WriteByte(s[i])
+			}
+			continue
+		}
+		buf.WriteByte(s[i])
+	}
+	return buf.String()
+}
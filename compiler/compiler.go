@@ -5,8 +5,6 @@
 package compiler
 
 import (
-	"bytes"
-	"errors"
 	"fmt"
 	"sort"
 	"strconv"
@@ -23,43 +21,122 @@ import (
 // The Golem Compiler
 //---------------------------------------------------------------
 
-// CompileSource is a convenience function that compiles a Module from Source
+// CompileSource is a convenience function that compiles a Module from Source.
+// If the source cannot be compiled, the problems that were found are
+// returned as ast.Diagnostics.
 func CompileSource(
 	source *scanner.Source,
-	builtins []*g.Builtin) (*bc.Module, error) {
+	builtins []*g.Builtin,
+	options ...Option) (*bc.Module, error) {
+
+	mod, _, err := CompileSourceWithGlobals(source, builtins, nil, options...)
+	return mod, err
+}
+
+// Option is an optional setting for the compiler.
+type Option func(*compiler)
+
+// WithOptimization makes the compiler optimize the bytecode of each function,
+// by folding operations on constants, threading jumps, and removing
+// instructions that have no effect or that can never be reached.
+func WithOptimization() Option {
+	return func(c *compiler) {
+		c.optimize = true
+	}
+}
+
+// A Global is a module-level variable.
+type Global struct {
+	Name    string
+	IsConst bool
+
+	// Index is the index of the Global's Ref in the Module's Refs.
+	Index int
+}
+
+// CompileSourceWithGlobals is like CompileSource, except that the module can refer
+// to some module-level variables that have already been defined elsewhere, e.g.
+// by an earlier input to an interactive session.  The given globals are assigned
+// the first indexes in the Module's Refs, in order.
+//
+// All of the module's Globals are returned, including the given globals
+// as well as any that were defined by the module itself.
+func CompileSourceWithGlobals(
+	source *scanner.Source,
+	builtins []*g.Builtin,
+	globals []*Global,
+	options ...Option) (*bc.Module, []*Global, error) {
 
 	builtinMgr := newBuiltinManager(builtins)
 
 	// scan
 	scanner, err := scanner.NewScanner(source)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// parse
 	parser := parser.NewParser(scanner, builtinMgr.contains)
 	astMod, err := parser.ParseModule()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	// define the globals
+	initScope := astMod.InitFunc.Scope
+	for i, gl := range globals {
+		initScope.PutVariable(gl.Name, ast.NewVariable(gl.Name, i, gl.IsConst, false))
+		initScope.IncrementNumLocals()
 	}
 
 	// analyze
 	anl := analyzer.NewAnalyzer(astMod)
 	errs := anl.Analyze()
 	if len(errs) > 0 {
-		var buf bytes.Buffer
-		for i, e := range errs {
-			if i > 0 {
-				buf.WriteString("\n")
+		return nil, nil, errs
+	}
+
+	// compile
+	cmp := newCompiler(astMod, builtinMgr, options)
+	return cmp.Compile(), moduleGlobals(astMod, globals), nil
+}
+
+func moduleGlobals(astMod *ast.Module, globals []*Global) []*Global {
+
+	result := []*Global{}
+	for i, gl := range globals {
+		result = append(result, &Global{gl.Name, gl.IsConst, i})
+	}
+
+	add := func(ident *ast.IdentExpr) {
+		vbl := ident.Variable
+		result = append(result, &Global{vbl.Symbol(), vbl.IsConst(), vbl.Index()})
+	}
+
+	for _, st := range astMod.InitFunc.Body.Statements {
+		switch t := st.(type) {
+		case *ast.ImportStmt:
+			for _, ident := range t.Idents {
+				add(ident)
 			}
-			buf.WriteString(e.Error())
+		case *ast.LetStmt:
+			for _, d := range t.Decls {
+				for _, ident := range d.Idents() {
+					add(ident)
+				}
+			}
+		case *ast.ConstStmt:
+			for _, d := range t.Decls {
+				for _, ident := range d.Idents() {
+					add(ident)
+				}
+			}
+		case *ast.NamedFnStmt:
+			add(t.Ident)
 		}
-		return nil, errors.New(buf.String())
 	}
 
-	// compile
-	cmp := newCompiler(astMod, builtinMgr)
-	return cmp.Compile(), nil
+	return result
 }
 
 // Compiler compiles an AST into bytecode
@@ -74,37 +151,46 @@ type compiler struct {
 	builtinMgr  *builtinManager
 	mod         *bc.Module
 
-	funcs   []*ast.FnExpr
-	funcIdx int
+	funcs     []*ast.FnExpr
+	funcIdx   int
+	funcNames map[*ast.FnExpr]string
 
-	btc      []byte
-	lnum     []bc.LineNumberEntry
-	handlers []bc.ErrorHandler
+	btc       []byte
+	lnum      []bc.LineNumberEntry
+	handlers  []bc.ErrorHandler
+	fieldKeys []int
+
+	optimize bool
 }
 
 // NewCompiler creates a new Compiler
-func NewCompiler(astMod *ast.Module, builtins []*g.Builtin) Compiler {
+func NewCompiler(astMod *ast.Module, builtins []*g.Builtin, options ...Option) Compiler {
 
-	return newCompiler(astMod, newBuiltinManager(builtins))
+	return newCompiler(astMod, newBuiltinManager(builtins), options)
 }
 
-func newCompiler(astMod *ast.Module, builtinMgr *builtinManager) Compiler {
+func newCompiler(astMod *ast.Module, builtinMgr *builtinManager, options []Option) Compiler {
 
 	mod := bc.NewModule(astMod.Name, astMod.Path)
 
 	// the 'init' function is always the first function in the list
 	funcs := []*ast.FnExpr{astMod.InitFunc}
 
-	return &compiler{
+	c := &compiler{
 		builtinMgr:  builtinMgr,
 		poolBuilder: newPoolBuilder(),
 		mod:         mod,
 		funcs:       funcs,
 		funcIdx:     0,
+		funcNames:   funcNames(astMod.InitFunc),
 		btc:         nil,
 		lnum:        nil,
 		handlers:    nil,
 	}
+	for _, opt := range options {
+		opt(c)
+	}
+	return c
 }
 
 // Compile compiles an ast.Module into a bytecode.Module
@@ -115,71 +201,50 @@ func (c *compiler) Compile() *bc.Module {
 		c.poolBuilder.addTemplate(c.compileFunc(c.funcs[c.funcIdx]))
 		c.funcIdx++
 	}
+	if c.optimize {
+		compactConstants(c.poolBuilder)
+	}
 
 	// done
 	c.mod.Pool = c.poolBuilder.build()
-	c.mod.SetContents(c.makeModuleContents())
+	c.mod.Exports = c.makeModuleExports()
+	c.mod.Pool.InitCaches()
+	c.mod.InitContents()
 	return c.mod
 }
 
-func (c *compiler) makeModuleContents() g.Struct {
+func (c *compiler) makeModuleExports() []*bc.Export {
 
-	fields := make(map[string]g.Field)
+	exports := []*bc.Export{}
+	export := func(ident *ast.IdentExpr) {
+		vbl := ident.Variable
+		exports = append(exports, &bc.Export{
+			Name:    ident.Symbol.Text,
+			IsConst: vbl.IsConst(),
+			Index:   vbl.Index(),
+		})
+	}
 
 	stmts := c.funcs[0].Body.Statements
 	for _, st := range stmts {
 		switch t := st.(type) {
 		case *ast.LetStmt:
 			for _, d := range t.Decls {
-				name := d.Ident.Symbol.Text
-				vbl := d.Ident.Variable
-				fields[name] = c.makeModuleProperty(vbl.Index(), vbl.IsConst())
+				for _, ident := range d.Idents() {
+					export(ident)
+				}
 			}
 		case *ast.ConstStmt:
 			for _, d := range t.Decls {
-				name := d.Ident.Symbol.Text
-				vbl := d.Ident.Variable
-				fields[name] = c.makeModuleProperty(vbl.Index(), vbl.IsConst())
+				for _, ident := range d.Idents() {
+					export(ident)
+				}
 			}
 		case *ast.NamedFnStmt:
-			name := t.Ident.Symbol.Text
-			vbl := t.Ident.Variable
-			fields[name] = c.makeModuleProperty(vbl.Index(), vbl.IsConst())
+			export(t.Ident)
 		}
 	}
-
-	stc, err := g.NewStruct(fields)
-	g.Assert(err == nil)
-	return stc
-}
-
-func (c *compiler) makeModuleProperty(index int, isConst bool) g.Field {
-
-	get := g.NewFixedNativeFunc(
-		[]g.Type{}, false,
-		func(ev g.Eval, values []g.Value) (g.Value, g.Error) {
-			return c.mod.Refs[index].Val, nil
-		})
-
-	if isConst {
-		prop, err := g.NewReadonlyProperty(get)
-		if err != nil {
-			panic("unreachable")
-		}
-		return prop
-	}
-
-	set := g.NewFixedNativeFunc(
-		[]g.Type{g.AnyType}, false,
-		func(ev g.Eval, values []g.Value) (g.Value, g.Error) {
-			c.mod.Refs[index].Val = values[0]
-			return g.Null, nil
-		})
-	prop, err := g.NewProperty(get, set)
-	if err != nil {
-		panic("unreachable")
-	}
-	return prop
+	return exports
 }
 
 func makeArity(fe *ast.FnExpr) (g.Arity, []g.Value) {
@@ -219,6 +284,7 @@ func (c *compiler) compileFunc(fe *ast.FnExpr) *bc.FuncTemplate {
 
 	tpl := &bc.FuncTemplate{
 		Module:          c.mod,
+		Name:            c.funcNames[fe],
 		Arity:           arity,
 		OptionalParams:  optional,
 		NumCaptures:     fe.Scope.NumCaptures(),
@@ -226,12 +292,16 @@ func (c *compiler) compileFunc(fe *ast.FnExpr) *bc.FuncTemplate {
 		Bytecodes:       nil,
 		LineNumberTable: nil,
 		ErrorHandlers:   nil,
+		IsGenerator:     fe.Scope.IsGenerator(),
+		LocalNames:      localNames(fe),
+		CaptureNames:    captureNames(fe),
 	}
 
 	// reset template info for current func
 	c.btc = []byte{}
 	c.lnum = []bc.LineNumberEntry{}
 	c.handlers = []bc.ErrorHandler{}
+	c.fieldKeys = []int{}
 
 	// TODO LoadNull and ReturnStmt are workarounds for the fact that
 	// we have not yet written a Control Flow Graph
@@ -243,10 +313,122 @@ func (c *compiler) compileFunc(fe *ast.FnExpr) *bc.FuncTemplate {
 	tpl.Bytecodes = c.btc
 	tpl.LineNumberTable = c.lnum
 	tpl.ErrorHandlers = c.handlers
+	tpl.FieldKeys = c.fieldKeys
+
+	if c.optimize {
+		optimize(tpl, c.poolBuilder)
+	}
 
 	return tpl
 }
 
+// funcNames finds the name of the named function or struct method that
+// contains each function.  A function that is the initial value of a
+// variable is named after the variable.
+func funcNames(initFunc *ast.FnExpr) map[*ast.FnExpr]string {
+	fn := &funcNamer{map[*ast.FnExpr]string{}, ""}
+	fn.Visit(initFunc)
+	return fn.names
+}
+
+type funcNamer struct {
+	names map[*ast.FnExpr]string
+	name  string
+}
+
+func (fn *funcNamer) Visit(node ast.Node) {
+	switch t := node.(type) {
+
+	case *ast.FnExpr:
+		fn.names[t] = fn.name
+		t.Traverse(fn)
+
+	case *ast.NamedFnStmt:
+		fn.visitNamed(t.Ident.Symbol.Text, t.Func)
+
+	case *ast.LetStmt:
+		fn.visitDecls(t.Decls)
+
+	case *ast.ConstStmt:
+		fn.visitDecls(t.Decls)
+
+	case *ast.StructExpr:
+		for _, e := range t.Entries {
+			switch e.Value.(type) {
+			case *ast.FnExpr, *ast.PropNode:
+				fn.visitNamed(e.Key.Text, e.Value)
+			default:
+				fn.Visit(e.Value)
+			}
+		}
+
+	default:
+		node.Traverse(fn)
+	}
+}
+
+func (fn *funcNamer) visitDecls(decls []*ast.DeclNode) {
+	for _, d := range decls {
+		if d.Val != nil {
+			if _, ok := d.Val.(*ast.FnExpr); ok && d.Pattern == nil {
+				fn.visitNamed(d.Ident.Symbol.Text, d.Val)
+			} else {
+				fn.Visit(d.Val)
+			}
+		}
+	}
+}
+
+// visitNamed visits a node whose functions are named after a declaration,
+// or a struct entry.
+func (fn *funcNamer) visitNamed(name string, node ast.Node) {
+	outer := fn.name
+	fn.name = name
+	fn.Visit(node)
+	fn.name = outer
+}
+
+// localNames finds the names of a function's local variables
+func localNames(fe *ast.FnExpr) []string {
+	ln := &localNamer{make([]string, fe.Scope.NumLocals())}
+	fe.Traverse(ln)
+	return ln.names
+}
+
+type localNamer struct {
+	names []string
+}
+
+func (ln *localNamer) Visit(node ast.Node) {
+	switch t := node.(type) {
+
+	case *ast.FnExpr:
+		// nested functions have their own locals
+		return
+
+	case *ast.IdentExpr:
+		if v := t.Variable; v != nil && !v.IsCapture() {
+			ln.names[v.Index()] = v.Symbol()
+		}
+
+	case *ast.ThisExpr:
+		if v := t.Variable; v != nil && !v.IsCapture() {
+			ln.names[v.Index()] = "this"
+		}
+	}
+
+	node.Traverse(ln)
+}
+
+// captureNames finds the names of a function's captured variables
+func captureNames(fe *ast.FnExpr) []string {
+	names := make([]string, fe.Scope.NumCaptures())
+	for _, cp := range fe.Scope.GetCaptures() {
+		names[cp.Child().Index()] = cp.Symbol()
+	}
+	return names
+}
+
 func (c *compiler) Visit(node ast.Node) {
 	switch t := node.(type) {
 
@@ -268,6 +450,9 @@ func (c *compiler) Visit(node ast.Node) {
 	case *ast.AssignmentExpr:
 		c.visitAssignment(t)
 
+	case *ast.DestructureExpr:
+		c.visitDestructure(t)
+
 	case *ast.IfStmt:
 		c.visitIf(t)
 
@@ -280,6 +465,9 @@ func (c *compiler) Visit(node ast.Node) {
 	case *ast.SwitchStmt:
 		c.visitSwitch(t)
 
+	case *ast.SelectStmt:
+		c.visitSelect(t)
+
 	case *ast.BreakStmt:
 		c.visitBreak(t)
 
@@ -292,6 +480,9 @@ func (c *compiler) Visit(node ast.Node) {
 	case *ast.TryStmt:
 		c.visitTry(t)
 
+	case *ast.YieldStmt:
+		c.visitYield(t)
+
 	case *ast.ThrowStmt:
 		c.visitThrow(t)
 
@@ -322,9 +513,12 @@ func (c *compiler) Visit(node ast.Node) {
 	case *ast.InvokeExpr:
 		c.visitInvoke(t)
 
-	case *ast.GoStmt:
+	case *ast.GoExpr:
 		c.visitGo(t)
 
+	case *ast.DeferStmt:
+		c.visitDefer(t)
+
 	case *ast.ExprStmt:
 		c.visitExprStmt(t)
 
@@ -361,6 +555,9 @@ func (c *compiler) Visit(node ast.Node) {
 	case *ast.DictExpr:
 		c.visitDictExpr(t)
 
+	case *ast.InterpStrExpr:
+		c.visitInterpStrExpr(t)
+
 	default:
 		panic(fmt.Sprintf("cannot compile %v\n", node))
 	}
@@ -398,7 +595,11 @@ func (c *compiler) visitDecls(decls []*ast.DeclNode) {
 			c.Visit(d.Val)
 		}
 
-		c.assignIdent(d.Ident)
+		if d.Pattern != nil {
+			c.destructure(d.Pattern)
+		} else {
+			c.assignIdent(d.Ident)
+		}
 	}
 }
 
@@ -469,6 +670,74 @@ func (c *compiler) visitAssignment(asn *ast.AssignmentExpr) {
 	}
 }
 
+func (c *compiler) visitDestructure(ds *ast.DestructureExpr) {
+
+	c.Visit(ds.Val)
+	c.push(ds.Eq.Position, bc.Dup)
+	c.destructure(ds.Pattern)
+}
+
+// destructure assigns the parts of the value on top of the stack
+// to the identifiers in a Pattern, and then pops the value.
+func (c *compiler) destructure(pattern ast.Pattern) {
+
+	pos := pattern.Begin()
+
+	switch t := pattern.(type) {
+
+	case *ast.IdentExpr:
+		c.assignIdent(t)
+
+	case *ast.BlankExpr:
+		c.push(pos, bc.Pop)
+
+	case *ast.TuplePattern:
+		// make sure the value is really a tuple,
+		// and is of the proper length
+		c.pushBytecode(pos, bc.CheckTuple, len(t.Elems))
+		c.destructureElems(pos, t.Elems)
+		c.push(pos, bc.Pop)
+
+	case *ast.ListPattern:
+		rest := 0
+		if t.Rest != nil {
+			rest = 1
+		}
+		c.pushWideBytecode(pos, bc.CheckList, len(t.Elems), rest)
+		c.destructureElems(pos, t.Elems)
+
+		if t.Rest != nil {
+			c.push(pos, bc.Dup)
+			c.pushInt(pos, int64(len(t.Elems)))
+			c.push(pos, bc.SliceFrom)
+			c.destructure(t.Rest)
+		}
+		c.push(pos, bc.Pop)
+
+	case *ast.StructPattern:
+		c.push(pos, bc.CheckStruct)
+		for _, e := range t.Entries {
+			c.push(e.Key.Position, bc.Dup)
+			c.pushBytecode(e.Key.Position, bc.GetField, c.fieldSlot(e.Key.Text))
+			c.destructure(e.Pattern)
+		}
+		c.push(pos, bc.Pop)
+
+	default:
+		panic("invalid pattern type")
+	}
+}
+
+func (c *compiler) destructureElems(pos ast.Pos, elems []ast.Pattern) {
+
+	for i, e := range elems {
+		c.push(pos, bc.Dup)
+		c.pushInt(pos, int64(i))
+		c.push(pos, bc.GetIndex)
+		c.destructure(e)
+	}
+}
+
 func (c *compiler) visitPostfixExpr(pe *ast.PostfixExpr) {
 
 	switch t := pe.Assignee.(type) {
@@ -654,6 +923,80 @@ func (c *compiler) fixBreakContinue(begin instPtr, body instPtr, end instPtr) {
 	}
 }
 
+func (c *compiler) visitSelect(sel *ast.SelectStmt) {
+
+	// Visit the channel of each case, followed by either the value
+	// that is to be sent, or Null.  Then push a Bool that specifies
+	// whether the case is a send.
+	for _, cs := range sel.Cases {
+		c.Visit(cs.Chan())
+		if cs.IsSend() {
+			c.Visit(cs.Comm.Params[0])
+			c.push(cs.Comm.Begin(), bc.LoadTrue)
+		} else {
+			c.push(cs.Comm.Begin(), bc.LoadNull)
+			c.push(cs.Comm.Begin(), bc.LoadFalse)
+		}
+	}
+
+	// The Select will leave the received value (or Null) on the stack,
+	// followed by the index of the chosen case (or -1 for the default).
+	hasDefault := 0
+	if sel.DefaultNode != nil {
+		hasDefault = 1
+	}
+	c.pushWideBytecode(sel.Begin(), bc.Select, len(sel.Cases), hasDefault)
+
+	// visit each case
+	endJumps := []int{}
+	for i, cs := range sel.Cases {
+		endJumps = append(endJumps, c.visitSelectCase(cs, i))
+	}
+
+	// visit default
+	if sel.DefaultNode != nil {
+		c.push(sel.DefaultNode.Begin(), bc.Pop)
+		c.push(sel.DefaultNode.Begin(), bc.Pop)
+		for _, n := range sel.DefaultNode.Body {
+			c.Visit(n)
+		}
+	}
+
+	// set all the end jumps
+	for _, j := range endJumps {
+		c.setJump(j, c.btcLen())
+	}
+}
+
+func (c *compiler) visitSelectCase(cs *ast.SelectCaseNode, index int) int {
+
+	// compare the index of the chosen case against this one
+	c.push(cs.Begin(), bc.Dup)
+	c.pushInt(cs.Begin(), int64(index))
+	c.push(cs.Begin(), bc.Eq)
+	caseEndJump := c.push(cs.Begin(), bc.JumpFalse, 0xFF, 0xFF)
+
+	// pop the index, and then either assign or pop the value
+	c.push(cs.Begin(), bc.Pop)
+	if cs.Assignee != nil {
+		c.assignIdent(cs.Assignee)
+	} else {
+		c.push(cs.Begin(), bc.Pop)
+	}
+
+	// visit body, and then push a jump to the very end of the select
+	for _, n := range cs.Body {
+		c.Visit(n)
+	}
+	endJump := c.push(cs.End(), bc.Jump, 0xFF, 0xFF)
+
+	// set the jump to the end of the case
+	c.setJump(caseEndJump, c.btcLen())
+
+	// return the jump to end of the select
+	return endJump
+}
+
 func (c *compiler) visitBreak(br *ast.BreakStmt) {
 	c.push(br.Begin(), bc.Break, 0xFF, 0xFF)
 }
@@ -697,6 +1040,10 @@ func (c *compiler) visitSwitch(sw *ast.SwitchStmt) {
 
 func (c *compiler) visitCase(cs *ast.CaseNode, hasItem bool) int {
 
+	if len(cs.Patterns) > 0 {
+		return c.visitPatternCase(cs)
+	}
+
 	bodyJumps := []int{}
 
 	// visit each match, and jump to body if true
@@ -736,8 +1083,174 @@ func (c *compiler) visitCase(cs *ast.CaseNode, hasItem bool) int {
 	return endJump
 }
 
+// visitPatternCase compiles a case that matches the item of a switch
+// against some patterns.
+func (c *compiler) visitPatternCase(cs *ast.CaseNode) int {
+
+	bodyJumps := []int{}
+
+	// try each of the patterns in turn
+	for _, pt := range cs.Patterns {
+
+		// match a copy of the item, and jump to the body if it matches
+		c.push(pt.Begin(), bc.Dup)
+		failJumps := [][]int{}
+		c.match(pt, 1, &failJumps)
+		bodyJumps = append(bodyJumps, c.push(pt.End(), bc.Jump, 0xFF, 0xFF))
+
+		// Otherwise, pop whatever the match left on the stack, and then
+		// go on to the next pattern.  A match that failed while leaving
+		// n values on the stack jumps to the n'th Pop from the end.
+		for depth := len(failJumps) - 1; depth > 0; depth-- {
+			for _, j := range failJumps[depth] {
+				c.setJump(j, c.btcLen())
+			}
+			c.push(pt.End(), bc.Pop)
+		}
+	}
+
+	// none of the patterns matched -- jump to the end of the case
+	caseEndJump := c.push(cs.End(), bc.Jump, 0xFF, 0xFF)
+
+	// set all the body jumps
+	for _, j := range bodyJumps {
+		c.setJump(j, c.btcLen())
+	}
+
+	// check the guard
+	guardJump := -1
+	if cs.Guard != nil {
+		c.Visit(cs.Guard)
+		guardJump = c.push(cs.Guard.End(), bc.JumpFalse, 0xFF, 0xFF)
+	}
+
+	// visit body, and then push a jump to the very end of the switch
+	for _, n := range cs.Body {
+		c.Visit(n)
+	}
+	endJump := c.push(cs.End(), bc.Jump, 0xFF, 0xFF)
+
+	// set the jump to the end of the case
+	c.setJump(caseEndJump, c.btcLen())
+	if guardJump != -1 {
+		c.setJump(guardJump, c.btcLen())
+	}
+
+	// return the jump to end of the switch
+	return endJump
+}
+
+// match compiles a pattern that is matched against the value on top of
+// the stack.  If the value matches, it is popped, and its parts are assigned
+// to the identifiers in the pattern.  If it does not match, the code jumps
+// away, leaving 'depth' values on the stack.  The jumps are added to
+// failJumps[depth], so that the stack can be cleaned up afterwards.
+func (c *compiler) match(pattern ast.Pattern, depth int, failJumps *[][]int) {
+
+	pos := pattern.Begin()
+
+	fail := func(p ast.Pos) {
+		for len(*failJumps) <= depth {
+			*failJumps = append(*failJumps, []int{})
+		}
+		(*failJumps)[depth] = append((*failJumps)[depth],
+			c.push(p, bc.JumpFalse, 0xFF, 0xFF))
+	}
+
+	switch t := pattern.(type) {
+
+	case *ast.IdentExpr:
+		c.assignIdent(t)
+
+	case *ast.BlankExpr:
+		c.push(pos, bc.Pop)
+
+	case *ast.ValuePattern:
+		c.push(pos, bc.Dup)
+		c.Visit(t.Val)
+		c.push(pos, bc.Eq)
+		fail(pos)
+		c.push(pos, bc.Pop)
+
+	case *ast.TypePattern:
+		c.pushBytecode(pos, bc.MatchType, int(typeOf(t.Token.Text)))
+		fail(pos)
+		c.push(pos, bc.Pop)
+
+	case *ast.TuplePattern:
+		c.pushBytecode(pos, bc.MatchType, int(g.TupleType))
+		fail(pos)
+		c.pushWideBytecode(pos, bc.MatchLen, len(t.Elems), 0)
+		fail(pos)
+		c.matchElems(pos, t.Elems, depth, failJumps)
+		c.push(pos, bc.Pop)
+
+	case *ast.ListPattern:
+		rest := 0
+		if t.Rest != nil {
+			rest = 1
+		}
+		c.pushBytecode(pos, bc.MatchType, int(g.ListType))
+		fail(pos)
+		c.pushWideBytecode(pos, bc.MatchLen, len(t.Elems), rest)
+		fail(pos)
+		c.matchElems(pos, t.Elems, depth, failJumps)
+
+		if t.Rest != nil {
+			c.push(pos, bc.Dup)
+			c.pushInt(pos, int64(len(t.Elems)))
+			c.push(pos, bc.SliceFrom)
+			c.match(t.Rest, depth+1, failJumps)
+		}
+		c.push(pos, bc.Pop)
+
+	case *ast.StructPattern:
+		c.pushBytecode(pos, bc.MatchType, int(g.StructType))
+		fail(pos)
+		for _, e := range t.Entries {
+			key := c.poolBuilder.constIndex(g.MustStr(e.Key.Text))
+			c.pushBytecode(e.Key.Position, bc.MatchField, key)
+			fail(e.Key.Position)
+			c.push(e.Key.Position, bc.Dup)
+			c.pushBytecode(e.Key.Position, bc.GetField, c.fieldSlot(e.Key.Text))
+			c.match(e.Pattern, depth+1, failJumps)
+		}
+		c.push(pos, bc.Pop)
+
+	default:
+		panic("invalid pattern type")
+	}
+}
+
+func (c *compiler) matchElems(pos ast.Pos, elems []ast.Pattern, depth int, failJumps *[][]int) {
+
+	for i, e := range elems {
+		c.push(pos, bc.Dup)
+		c.pushInt(pos, int64(i))
+		c.push(pos, bc.GetIndex)
+		c.match(e, depth+1, failJumps)
+	}
+}
+
+// typeOf returns the Type that has the given name
+func typeOf(name string) g.Type {
+	for t := g.NullType; t <= g.ChanType; t++ {
+		if t.String() == name {
+			return t
+		}
+	}
+	panic("invalid type name")
+}
+
 func (c *compiler) visitReturn(rt *ast.ReturnStmt) {
-	c.Visit(rt.Val)
+
+	// An invocation that is returned directly is in tail position, so
+	// the interpreter may be able to reuse the current frame for it.
+	if inv, ok := rt.Val.(*ast.InvokeExpr); ok {
+		c.compileInvoke(inv, bc.TailInvoke, bc.TailInvokeField)
+	} else {
+		c.Visit(rt.Val)
+	}
 	c.push(rt.Begin(), bc.Return)
 }
 
@@ -756,6 +1269,7 @@ func (c *compiler) compileTryBlock(block *ast.BlockNode) int {
 func (c *compiler) compileCatchBlock(
 	tryEnd ast.Pos,
 	ident *ast.IdentExpr,
+	guard ast.Expression,
 	block *ast.BlockNode) bc.TryClause {
 
 	// push a jump, so that we'll skip the catch block during normal execution
@@ -769,6 +1283,15 @@ func (c *compiler) compileCatchBlock(
 	g.Assert(!v.IsCapture())
 	c.pushBytecode(ident.Begin(), bc.StoreLocal, v.Index())
 
+	// if the guard fails, re-throw the error
+	if guard != nil {
+		c.Visit(guard)
+		j0 := c.push(guard.End(), bc.JumpTrue, 0xFF, 0xFF)
+		c.pushBytecode(ident.Begin(), bc.LoadLocal, v.Index())
+		c.push(guard.End(), bc.Throw)
+		c.setJump(j0, c.btcLen())
+	}
+
 	// compile the catch
 	c.Visit(block)
 
@@ -799,7 +1322,7 @@ func (c *compiler) visitTry(t *ast.TryStmt) {
 	// catch
 	var catch = bc.TryClause{Begin: -1, End: -1}
 	if t.CatchBlock != nil {
-		catch = c.compileCatchBlock(t.TryBlock.End(), t.CatchIdent, t.CatchBlock)
+		catch = c.compileCatchBlock(t.TryBlock.End(), t.CatchIdent, t.CatchGuard, t.CatchBlock)
 	}
 
 	// finally
@@ -815,6 +1338,11 @@ func (c *compiler) visitTry(t *ast.TryStmt) {
 	}
 }
 
+func (c *compiler) visitYield(y *ast.YieldStmt) {
+	c.Visit(y.Val)
+	c.push(y.Begin(), bc.Yield)
+}
+
 func (c *compiler) visitThrow(t *ast.ThrowStmt) {
 	c.Visit(t.Val)
 	c.push(t.End(), bc.Throw)
@@ -1065,6 +1593,10 @@ func (c *compiler) visitFunc(fe *ast.FnExpr) {
 }
 
 func (c *compiler) visitInvoke(inv *ast.InvokeExpr) {
+	c.compileInvoke(inv, bc.Invoke, bc.InvokeField)
+}
+
+func (c *compiler) compileInvoke(inv *ast.InvokeExpr, invoke byte, invokeField byte) {
 
 	// InvokeField
 	if fe, ok := inv.Operand.(*ast.FieldExpr); ok {
@@ -1074,11 +1606,11 @@ func (c *compiler) visitInvoke(inv *ast.InvokeExpr) {
 			c.Visit(n)
 		}
 
-		// push the field index, and number of params
+		// push the field's cache slot, and number of params
 		c.pushWideBytecode(
 			fe.Key.Position,
-			bc.InvokeField,
-			c.poolBuilder.constIndex(g.MustStr(fe.Key.Text)),
+			invokeField,
+			c.fieldSlot(fe.Key.Text),
 			len(inv.Params))
 		return
 	}
@@ -1089,21 +1621,47 @@ func (c *compiler) visitInvoke(inv *ast.InvokeExpr) {
 		c.Visit(n)
 	}
 	// push the number of params
-	c.pushBytecode(inv.Begin(), bc.Invoke, len(inv.Params))
+	c.pushBytecode(inv.Begin(), invoke, len(inv.Params))
+
+}
 
+func (c *compiler) visitGo(gw *ast.GoExpr) {
+	c.goInvoke(gw, false)
 }
 
-func (c *compiler) visitGo(gw *ast.GoStmt) {
+// goInvoke starts a goroutine.  A goroutine is 'detached' if it was started by
+// a 'go' statement, in which case its handle is discarded, and nothing can
+// ever join it.
+func (c *compiler) goInvoke(gw *ast.GoExpr, detached bool) {
 
 	inv := gw.Invocation
 	c.Visit(inv.Operand)
 	for _, n := range inv.Params {
 		c.Visit(n)
 	}
-	c.pushBytecode(inv.Begin(), bc.Go, len(inv.Params))
+
+	flag := 0
+	if detached {
+		flag = 1
+	}
+	c.pushWideBytecode(inv.Begin(), bc.Go, len(inv.Params), flag)
+}
+
+func (c *compiler) visitDefer(df *ast.DeferStmt) {
+
+	inv := df.Invocation
+	c.Visit(inv.Operand)
+	for _, n := range inv.Params {
+		c.Visit(n)
+	}
+	c.pushBytecode(inv.Begin(), bc.Defer, len(inv.Params))
 }
 
 func (c *compiler) visitExprStmt(es *ast.ExprStmt) {
+	if gw, ok := es.Expr.(*ast.GoExpr); ok {
+		c.goInvoke(gw, true)
+		return
+	}
 	c.Visit(es.Expr)
 }
 
@@ -1177,11 +1735,15 @@ func (c *compiler) visitFieldExpr(fe *ast.FieldExpr) {
 
 	c.Visit(fe.Operand)
 
-	// push the field index
-	c.pushBytecode(
-		fe.Key.Position,
-		bc.GetField,
-		c.poolBuilder.constIndex(g.MustStr(fe.Key.Text)))
+	// push the field's cache slot
+	c.pushBytecode(fe.Key.Position, bc.GetField, c.fieldSlot(fe.Key.Text))
+}
+
+// fieldSlot creates an inline cache slot for an instruction that looks up
+// the field with the given name.
+func (c *compiler) fieldSlot(name string) int {
+	c.fieldKeys = append(c.fieldKeys, c.poolBuilder.constIndex(g.MustStr(name)))
+	return len(c.fieldKeys) - 1
 }
 
 func (c *compiler) visitIndexExpr(ie *ast.IndexExpr) {
@@ -1243,6 +1805,27 @@ func (c *compiler) visitDictExpr(d *ast.DictExpr) {
 	c.pushBytecode(d.Begin(), bc.NewDict, len(d.Entries))
 }
 
+// visitInterpStrExpr pushes each of the non-empty chunks of text, and each of
+// the interpolated values, onto the stack, and then concatenates all of them.
+func (c *compiler) visitInterpStrExpr(is *ast.InterpStrExpr) {
+
+	n := 0
+	for i, tok := range is.Tokens {
+		if tok.Text != "" {
+			c.pushBytecode(
+				tok.Position,
+				bc.LoadConst,
+				c.poolBuilder.constIndex(g.MustStr(tok.Text)))
+			n++
+		}
+		if i < len(is.Exprs) {
+			c.Visit(is.Exprs[i])
+			n++
+		}
+	}
+	c.pushBytecode(is.Begin(), bc.Concat, n)
+}
+
 func (c *compiler) pushInt(pos ast.Pos, i int64) {
 	switch i {
 	case 0:
@@ -1264,10 +1847,11 @@ func (c *compiler) push(pos ast.Pos, bytes ...byte) int {
 	c.btc = append(c.btc, bytes...)
 
 	ln := len(c.lnum)
-	if (ln == 0) || (pos.Line != c.lnum[ln-1].LineNum) {
+	if (ln == 0) || (pos.Line != c.lnum[ln-1].LineNum) || (pos.Col != c.lnum[ln-1].Col) {
 		c.lnum = append(c.lnum, bc.LineNumberEntry{
 			Index:   n,
 			LineNum: pos.Line,
+			Col:     pos.Col,
 		})
 	}
 
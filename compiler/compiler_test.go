@@ -53,6 +53,13 @@ func ok(t *testing.T, pool *bc.Pool, expect *bc.Pool) {
 				t.Error("LineNumberTable: ", pool, " != ", expect)
 			}
 		}
+
+		// checking FieldKeys is optional
+		if et.FieldKeys != nil {
+			if !reflect.DeepEqual(mt.FieldKeys, et.FieldKeys) {
+				t.Error("FieldKeys: ", mt.FieldKeys, " != ", et.FieldKeys)
+			}
+		}
 	}
 }
 
@@ -104,9 +111,19 @@ func TestExpression(t *testing.T) {
 					bc.Plus,
 					bc.Return},
 				LineNumberTable: []bc.LineNumberEntry{
-					{Index: 0, LineNum: 0},
-					{Index: 1, LineNum: 1},
-					{Index: 16, LineNum: 0}},
+					{Index: 0, LineNum: 0, Col: 0},
+					{Index: 1, LineNum: 1, Col: 1},
+					{Index: 4, LineNum: 1, Col: 6},
+					{Index: 5, LineNum: 1, Col: 4},
+					{Index: 6, LineNum: 1, Col: 11},
+					{Index: 7, LineNum: 1, Col: 9},
+					{Index: 8, LineNum: 1, Col: 16},
+					{Index: 9, LineNum: 1, Col: 14},
+					{Index: 10, LineNum: 1, Col: 20},
+					{Index: 11, LineNum: 1, Col: 18},
+					{Index: 12, LineNum: 1, Col: 24},
+					{Index: 15, LineNum: 1, Col: 22},
+					{Index: 16, LineNum: 0, Col: 0}},
 				ErrorHandlers: nil,
 			}},
 	})
@@ -131,9 +148,15 @@ func TestExpression(t *testing.T) {
 					bc.Div,
 					bc.Return},
 				LineNumberTable: []bc.LineNumberEntry{
-					{Index: 0, LineNum: 0},
-					{Index: 1, LineNum: 1},
-					{Index: 16, LineNum: 0}},
+					{Index: 0, LineNum: 0, Col: 0},
+					{Index: 1, LineNum: 1, Col: 2},
+					{Index: 4, LineNum: 1, Col: 6},
+					{Index: 7, LineNum: 1, Col: 4},
+					{Index: 8, LineNum: 1, Col: 11},
+					{Index: 11, LineNum: 1, Col: 9},
+					{Index: 12, LineNum: 1, Col: 16},
+					{Index: 15, LineNum: 1, Col: 14},
+					{Index: 16, LineNum: 0, Col: 0}},
 				ErrorHandlers: nil,
 			}},
 	})
@@ -156,11 +179,13 @@ func TestExpression(t *testing.T) {
 					bc.Plus,
 					bc.Return},
 				LineNumberTable: []bc.LineNumberEntry{
-					{Index: 0, LineNum: 0},
-					{Index: 1, LineNum: 1},
-					{Index: 4, LineNum: 2},
-					{Index: 5, LineNum: 1},
-					{Index: 6, LineNum: 0}},
+					{Index: 0, LineNum: 0, Col: 0},
+					{Index: 1, LineNum: 1, Col: 1},
+					{Index: 2, LineNum: 1, Col: 8},
+					{Index: 3, LineNum: 1, Col: 6},
+					{Index: 4, LineNum: 2, Col: 1},
+					{Index: 5, LineNum: 1, Col: 13},
+					{Index: 6, LineNum: 0, Col: 0}},
 				ErrorHandlers: nil,
 			}},
 	})
@@ -181,9 +206,11 @@ func TestExpression(t *testing.T) {
 					bc.Mul,
 					bc.Return},
 				LineNumberTable: []bc.LineNumberEntry{
-					{Index: 0, LineNum: 0},
-					{Index: 1, LineNum: 1},
-					{Index: 8, LineNum: 0}},
+					{Index: 0, LineNum: 0, Col: 0},
+					{Index: 1, LineNum: 1, Col: 1},
+					{Index: 4, LineNum: 1, Col: 7},
+					{Index: 7, LineNum: 1, Col: 5},
+					{Index: 8, LineNum: 0, Col: 0}},
 				ErrorHandlers: nil,
 			}},
 	})
@@ -204,9 +231,11 @@ func TestExpression(t *testing.T) {
 					bc.Eq,
 					bc.Return},
 				LineNumberTable: []bc.LineNumberEntry{
-					{Index: 0, LineNum: 0},
-					{Index: 1, LineNum: 1},
-					{Index: 6, LineNum: 0}},
+					{Index: 0, LineNum: 0, Col: 0},
+					{Index: 1, LineNum: 1, Col: 1},
+					{Index: 4, LineNum: 1, Col: 8},
+					{Index: 5, LineNum: 1, Col: 5},
+					{Index: 6, LineNum: 0, Col: 0}},
 				ErrorHandlers: nil,
 			}},
 	})
@@ -227,9 +256,11 @@ func TestExpression(t *testing.T) {
 					bc.Ne,
 					bc.Return},
 				LineNumberTable: []bc.LineNumberEntry{
-					{Index: 0, LineNum: 0},
-					{Index: 1, LineNum: 1},
-					{Index: 4, LineNum: 0}},
+					{Index: 0, LineNum: 0, Col: 0},
+					{Index: 1, LineNum: 1, Col: 1},
+					{Index: 2, LineNum: 1, Col: 9},
+					{Index: 3, LineNum: 1, Col: 6},
+					{Index: 4, LineNum: 0, Col: 0}},
 				ErrorHandlers: nil,
 			}},
 	})
@@ -253,9 +284,14 @@ func TestExpression(t *testing.T) {
 					bc.Gte,
 					bc.Return},
 				LineNumberTable: []bc.LineNumberEntry{
-					{Index: 0, LineNum: 0},
-					{Index: 1, LineNum: 1},
-					{Index: 7, LineNum: 0}},
+					{Index: 0, LineNum: 0, Col: 0},
+					{Index: 1, LineNum: 1, Col: 1},
+					{Index: 2, LineNum: 1, Col: 8},
+					{Index: 3, LineNum: 1, Col: 6},
+					{Index: 4, LineNum: 1, Col: 15},
+					{Index: 5, LineNum: 1, Col: 23},
+					{Index: 6, LineNum: 1, Col: 20},
+					{Index: 7, LineNum: 0, Col: 0}},
 				ErrorHandlers: nil,
 			}},
 	})
@@ -282,9 +318,17 @@ func TestExpression(t *testing.T) {
 					bc.Cmp,
 					bc.Return},
 				LineNumberTable: []bc.LineNumberEntry{
-					{Index: 0, LineNum: 0},
-					{Index: 1, LineNum: 1},
-					{Index: 10, LineNum: 0}},
+					{Index: 0, LineNum: 0, Col: 0},
+					{Index: 1, LineNum: 1, Col: 1},
+					{Index: 2, LineNum: 1, Col: 8},
+					{Index: 3, LineNum: 1, Col: 6},
+					{Index: 4, LineNum: 1, Col: 15},
+					{Index: 5, LineNum: 1, Col: 23},
+					{Index: 6, LineNum: 1, Col: 20},
+					{Index: 7, LineNum: 1, Col: 30},
+					{Index: 8, LineNum: 1, Col: 39},
+					{Index: 9, LineNum: 1, Col: 35},
+					{Index: 10, LineNum: 0, Col: 0}},
 				ErrorHandlers: nil,
 			}},
 	})
@@ -310,9 +354,11 @@ func TestExpression(t *testing.T) {
 					bc.StoreLocal, 0, 0,
 					bc.Return},
 				LineNumberTable: []bc.LineNumberEntry{
-					{Index: 0, LineNum: 0},
-					{Index: 1, LineNum: 1},
-					{Index: 21, LineNum: 0}},
+					{Index: 0, LineNum: 0, Col: 0},
+					{Index: 1, LineNum: 1, Col: 9},
+					{Index: 7, LineNum: 1, Col: 14},
+					{Index: 18, LineNum: 1, Col: 5},
+					{Index: 21, LineNum: 0, Col: 0}},
 				ErrorHandlers: nil,
 			}},
 	})
@@ -338,9 +384,11 @@ func TestExpression(t *testing.T) {
 					bc.StoreLocal, 0, 0,
 					bc.Return},
 				LineNumberTable: []bc.LineNumberEntry{
-					{Index: 0, LineNum: 0},
-					{Index: 1, LineNum: 1},
-					{Index: 21, LineNum: 0}},
+					{Index: 0, LineNum: 0, Col: 0},
+					{Index: 1, LineNum: 1, Col: 9},
+					{Index: 7, LineNum: 1, Col: 14},
+					{Index: 18, LineNum: 1, Col: 5},
+					{Index: 21, LineNum: 0, Col: 0}},
 				ErrorHandlers: nil,
 			}},
 	})
@@ -368,17 +416,153 @@ func TestAssignment(t *testing.T) {
 					bc.StoreLocal, 0, 0,
 					bc.Return},
 				LineNumberTable: []bc.LineNumberEntry{
-					{Index: 0, LineNum: 0},
-					{Index: 1, LineNum: 1},
-					{Index: 5, LineNum: 3},
-					{Index: 8, LineNum: 2},
-					{Index: 11, LineNum: 3},
-					{Index: 18, LineNum: 0}},
+					{Index: 0, LineNum: 0, Col: 0},
+					{Index: 1, LineNum: 1, Col: 9},
+					{Index: 2, LineNum: 1, Col: 5},
+					{Index: 5, LineNum: 3, Col: 1},
+					{Index: 8, LineNum: 2, Col: 7},
+					{Index: 11, LineNum: 3, Col: 7},
+					{Index: 14, LineNum: 3, Col: 5},
+					{Index: 15, LineNum: 3, Col: 3},
+					{Index: 18, LineNum: 0, Col: 0}},
 				ErrorHandlers: nil,
 			}},
 	})
 }
 
+func TestDestructure(t *testing.T) {
+
+	mod := testCompile(t, "let x = 1\nlet (a, [_, ...b]) = x\nlet {c} = x")
+	tassert(t, reflect.DeepEqual(mod.Pool.Constants, []g.Basic{g.MustStr("c")}))
+
+	tpl := mod.Pool.Templates[0]
+	tassert(t, reflect.DeepEqual(tpl.Bytecodes, []byte{
+		bc.LoadNull,
+		bc.LoadOne,
+		bc.StoreLocal, 0, 0,
+
+		bc.LoadLocal, 0, 0,
+		bc.CheckTuple, 0, 2,
+		bc.Dup,
+		bc.LoadZero,
+		bc.GetIndex,
+		bc.StoreLocal, 0, 1,
+		bc.Dup,
+		bc.LoadOne,
+		bc.GetIndex,
+		bc.CheckList, 0, 1, 0, 1,
+		bc.Dup,
+		bc.LoadZero,
+		bc.GetIndex,
+		bc.Pop,
+		bc.Dup,
+		bc.LoadOne,
+		bc.SliceFrom,
+		bc.StoreLocal, 0, 2,
+		bc.Pop,
+		bc.Pop,
+
+		bc.LoadLocal, 0, 0,
+		bc.CheckStruct,
+		bc.Dup,
+		bc.GetField, 0, 0,
+		bc.StoreLocal, 0, 3,
+		bc.Pop,
+		bc.Return}))
+}
+
+func TestSwitchPatterns(t *testing.T) {
+
+	mod := testCompile(t, "let x = 1\nswitch x { case (Int, s): s; }")
+
+	tpl := mod.Pool.Templates[0]
+	tassert(t, reflect.DeepEqual(tpl.Bytecodes, []byte{
+		bc.LoadNull,
+		bc.LoadOne,
+		bc.StoreLocal, 0, 0,
+		bc.LoadLocal, 0, 0,
+
+		// match
+		bc.Dup,
+		bc.MatchType, 0, byte(g.TupleType),
+		bc.JumpFalse, 0, 44,
+		bc.MatchLen, 0, 2, 0, 0,
+		bc.JumpFalse, 0, 44,
+		bc.Dup,
+		bc.LoadZero,
+		bc.GetIndex,
+		bc.MatchType, 0, byte(g.IntType),
+		bc.JumpFalse, 0, 43,
+		bc.Pop,
+		bc.Dup,
+		bc.LoadOne,
+		bc.GetIndex,
+		bc.StoreLocal, 0, 1,
+		bc.Pop,
+		bc.Jump, 0, 48,
+
+		// failed
+		bc.Pop,
+		bc.Pop,
+		bc.Jump, 0, 54,
+
+		// body
+		bc.LoadLocal, 0, 1,
+		bc.Jump, 0, 55,
+
+		bc.Pop,
+		bc.Return}))
+}
+
+func TestInterpStr(t *testing.T) {
+
+	mod := testCompile(t, "let a = 1\nlet b = `x${a}${a + 1}y`")
+	tassert(t, reflect.DeepEqual(mod.Pool.Constants, []g.Basic{g.MustStr("x"), g.MustStr("y")}))
+
+	tpl := mod.Pool.Templates[0]
+	tassert(t, reflect.DeepEqual(tpl.Bytecodes, []byte{
+		bc.LoadNull,
+		bc.LoadOne,
+		bc.StoreLocal, 0, 0,
+
+		bc.LoadConst, 0, 0,
+		bc.LoadLocal, 0, 0,
+		bc.LoadLocal, 0, 0,
+		bc.LoadOne,
+		bc.Plus,
+		bc.LoadConst, 0, 1,
+		bc.Concat, 0, 4,
+		bc.StoreLocal, 0, 1,
+		bc.Return}))
+
+	// the interpolated expressions have their own positions
+	tassert(t, reflect.DeepEqual(tpl.LineNumberTable, []bc.LineNumberEntry{
+		{Index: 0, LineNum: 0, Col: 0},
+		{Index: 1, LineNum: 1, Col: 9},
+		{Index: 2, LineNum: 1, Col: 5},
+		{Index: 5, LineNum: 2, Col: 9},
+		{Index: 8, LineNum: 2, Col: 13},
+		{Index: 11, LineNum: 2, Col: 17},
+		{Index: 14, LineNum: 2, Col: 21},
+		{Index: 15, LineNum: 2, Col: 19},
+		{Index: 16, LineNum: 2, Col: 22},
+		{Index: 19, LineNum: 2, Col: 9},
+		{Index: 22, LineNum: 2, Col: 5},
+		{Index: 25, LineNum: 0, Col: 0}}))
+
+	// empty chunks of text are skipped
+	mod = testCompile(t, "let a = 1\nlet b = `${a}`")
+	tassert(t, len(mod.Pool.Constants) == 0)
+	tassert(t, reflect.DeepEqual(mod.Pool.Templates[0].Bytecodes, []byte{
+		bc.LoadNull,
+		bc.LoadOne,
+		bc.StoreLocal, 0, 0,
+		bc.LoadLocal, 0, 0,
+		bc.Concat, 0, 1,
+		bc.StoreLocal, 0, 1,
+		bc.Return}))
+}
+
 func TestShift(t *testing.T) {
 
 	a := 0x1234
@@ -416,9 +600,14 @@ func TestIf(t *testing.T) {
 					bc.StoreLocal, 0, 0,
 					bc.Return},
 				LineNumberTable: []bc.LineNumberEntry{
-					{Index: 0, LineNum: 0},
-					{Index: 1, LineNum: 1},
-					{Index: 17, LineNum: 0}},
+					{Index: 0, LineNum: 0, Col: 0},
+					{Index: 1, LineNum: 1, Col: 5},
+					{Index: 4, LineNum: 1, Col: 10},
+					{Index: 7, LineNum: 1, Col: 7},
+					{Index: 8, LineNum: 1, Col: 10},
+					{Index: 11, LineNum: 1, Col: 23},
+					{Index: 14, LineNum: 1, Col: 19},
+					{Index: 17, LineNum: 0, Col: 0}},
 				ErrorHandlers: nil,
 			}},
 	})
@@ -455,14 +644,19 @@ func TestIf(t *testing.T) {
 					bc.StoreLocal, 0, 3,
 					bc.Return},
 				LineNumberTable: []bc.LineNumberEntry{
-					{Index: 0, LineNum: 0},
-					{Index: 1, LineNum: 1},
-					{Index: 5, LineNum: 2},
-					{Index: 9, LineNum: 3},
-					{Index: 15, LineNum: 4},
-					{Index: 18, LineNum: 5},
-					{Index: 24, LineNum: 7},
-					{Index: 30, LineNum: 0}},
+					{Index: 0, LineNum: 0, Col: 0},
+					{Index: 1, LineNum: 1, Col: 9},
+					{Index: 2, LineNum: 1, Col: 5},
+					{Index: 5, LineNum: 2, Col: 7},
+					{Index: 6, LineNum: 2, Col: 11},
+					{Index: 9, LineNum: 3, Col: 15},
+					{Index: 12, LineNum: 3, Col: 11},
+					{Index: 15, LineNum: 4, Col: 10},
+					{Index: 18, LineNum: 5, Col: 15},
+					{Index: 21, LineNum: 5, Col: 11},
+					{Index: 24, LineNum: 7, Col: 11},
+					{Index: 27, LineNum: 7, Col: 7},
+					{Index: 30, LineNum: 0, Col: 0}},
 				ErrorHandlers: nil,
 			}},
 	})
@@ -493,9 +687,17 @@ func TestWhile(t *testing.T) {
 					bc.Jump, 0, 5,
 					bc.Return},
 				LineNumberTable: []bc.LineNumberEntry{
-					{Index: 0, LineNum: 0},
-					{Index: 1, LineNum: 1},
-					{Index: 20, LineNum: 0}},
+					{Index: 0, LineNum: 0, Col: 0},
+					{Index: 1, LineNum: 1, Col: 9},
+					{Index: 2, LineNum: 1, Col: 5},
+					{Index: 5, LineNum: 1, Col: 19},
+					{Index: 6, LineNum: 1, Col: 23},
+					{Index: 7, LineNum: 1, Col: 21},
+					{Index: 8, LineNum: 1, Col: 23},
+					{Index: 11, LineNum: 1, Col: 36},
+					{Index: 14, LineNum: 1, Col: 32},
+					{Index: 17, LineNum: 1, Col: 39},
+					{Index: 20, LineNum: 0, Col: 0}},
 				ErrorHandlers: nil,
 			}},
 	})
@@ -527,10 +729,21 @@ func TestWhile(t *testing.T) {
 					bc.StoreLocal, 0, 2,
 					bc.Return},
 				LineNumberTable: []bc.LineNumberEntry{
-					{Index: 0, LineNum: 0},
-					{Index: 1, LineNum: 1},
-					{Index: 13, LineNum: 2},
-					{Index: 34, LineNum: 0}},
+					{Index: 0, LineNum: 0, Col: 0},
+					{Index: 1, LineNum: 1, Col: 9},
+					{Index: 4, LineNum: 1, Col: 5},
+					{Index: 7, LineNum: 1, Col: 21},
+					{Index: 8, LineNum: 1, Col: 25},
+					{Index: 9, LineNum: 1, Col: 23},
+					{Index: 10, LineNum: 1, Col: 25},
+					{Index: 13, LineNum: 2, Col: 3},
+					{Index: 16, LineNum: 2, Col: 10},
+					{Index: 19, LineNum: 2, Col: 28},
+					{Index: 22, LineNum: 2, Col: 24},
+					{Index: 25, LineNum: 2, Col: 31},
+					{Index: 28, LineNum: 2, Col: 42},
+					{Index: 31, LineNum: 2, Col: 38},
+					{Index: 34, LineNum: 0, Col: 0}},
 				ErrorHandlers: nil,
 			}},
 	})
@@ -562,12 +775,17 @@ func TestReturn(t *testing.T) {
 					bc.StoreLocal, 0, 0,
 					bc.Return},
 				LineNumberTable: []bc.LineNumberEntry{
-					{Index: 0, LineNum: 0},
-					{Index: 1, LineNum: 1},
-					{Index: 8, LineNum: 2},
-					{Index: 12, LineNum: 1},
-					{Index: 13, LineNum: 2},
-					{Index: 20, LineNum: 0}},
+					{Index: 0, LineNum: 0, Col: 0},
+					{Index: 1, LineNum: 1, Col: 9},
+					{Index: 2, LineNum: 1, Col: 5},
+					{Index: 5, LineNum: 1, Col: 19},
+					{Index: 8, LineNum: 2, Col: 3},
+					{Index: 11, LineNum: 2, Col: 1},
+					{Index: 12, LineNum: 1, Col: 12},
+					{Index: 13, LineNum: 2, Col: 10},
+					{Index: 16, LineNum: 2, Col: 8},
+					{Index: 17, LineNum: 2, Col: 6},
+					{Index: 20, LineNum: 0, Col: 0}},
 				ErrorHandlers: nil,
 			}},
 	})
@@ -610,10 +828,12 @@ let b = fn(x) {
 					bc.StoreLocal, 0, 1,
 					bc.Return},
 				LineNumberTable: []bc.LineNumberEntry{
-					{Index: 0, LineNum: 0},
-					{Index: 1, LineNum: 2},
-					{Index: 7, LineNum: 3},
-					{Index: 13, LineNum: 0}},
+					{Index: 0, LineNum: 0, Col: 0},
+					{Index: 1, LineNum: 2, Col: 9},
+					{Index: 4, LineNum: 2, Col: 5},
+					{Index: 7, LineNum: 3, Col: 9},
+					{Index: 10, LineNum: 3, Col: 5},
+					{Index: 13, LineNum: 0, Col: 0}},
 				ErrorHandlers: nil,
 			},
 			&bc.FuncTemplate{
@@ -625,9 +845,9 @@ let b = fn(x) {
 					bc.LoadConst, 0, 0,
 					bc.Return},
 				LineNumberTable: []bc.LineNumberEntry{
-					{Index: 0, LineNum: 0},
-					{Index: 1, LineNum: 2},
-					{Index: 4, LineNum: 0}},
+					{Index: 0, LineNum: 0, Col: 0},
+					{Index: 1, LineNum: 2, Col: 16},
+					{Index: 4, LineNum: 0, Col: 0}},
 				ErrorHandlers: nil,
 			},
 			&bc.FuncTemplate{
@@ -647,10 +867,17 @@ let b = fn(x) {
 					bc.Plus,
 					bc.Return},
 				LineNumberTable: []bc.LineNumberEntry{
-					{Index: 0, LineNum: 0},
-					{Index: 1, LineNum: 4},
-					{Index: 7, LineNum: 7},
-					{Index: 24, LineNum: 0}},
+					{Index: 0, LineNum: 0, Col: 0},
+					{Index: 1, LineNum: 4, Col: 13},
+					{Index: 4, LineNum: 4, Col: 9},
+					{Index: 7, LineNum: 7, Col: 5},
+					{Index: 10, LineNum: 7, Col: 9},
+					{Index: 13, LineNum: 7, Col: 7},
+					{Index: 14, LineNum: 7, Col: 13},
+					{Index: 17, LineNum: 7, Col: 15},
+					{Index: 20, LineNum: 7, Col: 13},
+					{Index: 23, LineNum: 7, Col: 11},
+					{Index: 24, LineNum: 0, Col: 0}},
 				ErrorHandlers: nil,
 			},
 			&bc.FuncTemplate{
@@ -664,9 +891,11 @@ let b = fn(x) {
 					bc.Mul,
 					bc.Return},
 				LineNumberTable: []bc.LineNumberEntry{
-					{Index: 0, LineNum: 0},
-					{Index: 1, LineNum: 5},
-					{Index: 8, LineNum: 0}},
+					{Index: 0, LineNum: 0, Col: 0},
+					{Index: 1, LineNum: 5, Col: 9},
+					{Index: 4, LineNum: 5, Col: 13},
+					{Index: 7, LineNum: 5, Col: 11},
+					{Index: 8, LineNum: 0, Col: 0}},
 				ErrorHandlers: nil,
 			}},
 	})
@@ -714,14 +943,22 @@ c(2, 3)
 					bc.Invoke, 0, 2,
 					bc.Return},
 				LineNumberTable: []bc.LineNumberEntry{
-					{Index: 0, LineNum: 0},
-					{Index: 1, LineNum: 2},
-					{Index: 7, LineNum: 3},
-					{Index: 13, LineNum: 4},
-					{Index: 19, LineNum: 5},
-					{Index: 25, LineNum: 6},
-					{Index: 32, LineNum: 7},
-					{Index: 44, LineNum: 0}},
+					{Index: 0, LineNum: 0, Col: 0},
+					{Index: 1, LineNum: 2, Col: 9},
+					{Index: 4, LineNum: 2, Col: 5},
+					{Index: 7, LineNum: 3, Col: 9},
+					{Index: 10, LineNum: 3, Col: 5},
+					{Index: 13, LineNum: 4, Col: 9},
+					{Index: 16, LineNum: 4, Col: 5},
+					{Index: 19, LineNum: 5, Col: 1},
+					{Index: 25, LineNum: 6, Col: 1},
+					{Index: 28, LineNum: 6, Col: 3},
+					{Index: 29, LineNum: 6, Col: 1},
+					{Index: 32, LineNum: 7, Col: 1},
+					{Index: 35, LineNum: 7, Col: 3},
+					{Index: 38, LineNum: 7, Col: 6},
+					{Index: 41, LineNum: 7, Col: 1},
+					{Index: 44, LineNum: 0, Col: 0}},
 				ErrorHandlers: nil,
 			},
 			&bc.FuncTemplate{
@@ -732,7 +969,7 @@ c(2, 3)
 					bc.LoadNull,
 					bc.Return},
 				LineNumberTable: []bc.LineNumberEntry{
-					{Index: 0, LineNum: 0}},
+					{Index: 0, LineNum: 0, Col: 0}},
 				ErrorHandlers: nil,
 			},
 			&bc.FuncTemplate{
@@ -744,9 +981,9 @@ c(2, 3)
 					bc.LoadLocal, 0, 0,
 					bc.Return},
 				LineNumberTable: []bc.LineNumberEntry{
-					{Index: 0, LineNum: 0},
-					{Index: 1, LineNum: 3},
-					{Index: 4, LineNum: 0}},
+					{Index: 0, LineNum: 0, Col: 0},
+					{Index: 1, LineNum: 3, Col: 17},
+					{Index: 4, LineNum: 0, Col: 0}},
 				ErrorHandlers: nil,
 			},
 			&bc.FuncTemplate{
@@ -764,9 +1001,15 @@ c(2, 3)
 					bc.Mul,
 					bc.Return},
 				LineNumberTable: []bc.LineNumberEntry{
-					{Index: 0, LineNum: 0},
-					{Index: 1, LineNum: 4},
-					{Index: 18, LineNum: 0}},
+					{Index: 0, LineNum: 0, Col: 0},
+					{Index: 1, LineNum: 4, Col: 28},
+					{Index: 4, LineNum: 4, Col: 24},
+					{Index: 7, LineNum: 4, Col: 31},
+					{Index: 10, LineNum: 4, Col: 35},
+					{Index: 13, LineNum: 4, Col: 33},
+					{Index: 14, LineNum: 4, Col: 39},
+					{Index: 17, LineNum: 4, Col: 37},
+					{Index: 18, LineNum: 0, Col: 0}},
 				ErrorHandlers: nil,
 			}},
 	})
@@ -797,9 +1040,10 @@ const accumGen = fn(n) {
 				bc.StoreLocal, 0, 0,
 				bc.Return},
 			LineNumberTable: []bc.LineNumberEntry{
-				{Index: 0, LineNum: 0},
-				{Index: 1, LineNum: 2},
-				{Index: 7, LineNum: 0}},
+				{Index: 0, LineNum: 0, Col: 0},
+				{Index: 1, LineNum: 2, Col: 18},
+				{Index: 4, LineNum: 2, Col: 7},
+				{Index: 7, LineNum: 0, Col: 0}},
 			ErrorHandlers: nil,
 		}, &bc.FuncTemplate{
 			Arity:       fixedArity(1),
@@ -812,9 +1056,10 @@ const accumGen = fn(n) {
 				bc.Return,
 				bc.Return},
 			LineNumberTable: []bc.LineNumberEntry{
-				{Index: 0, LineNum: 0},
-				{Index: 1, LineNum: 3},
-				{Index: 8, LineNum: 0}},
+				{Index: 0, LineNum: 0, Col: 0},
+				{Index: 1, LineNum: 3, Col: 12},
+				{Index: 7, LineNum: 3, Col: 5},
+				{Index: 8, LineNum: 0, Col: 0}},
 			ErrorHandlers: nil,
 		}, &bc.FuncTemplate{
 			Arity:       fixedArity(1),
@@ -831,10 +1076,15 @@ const accumGen = fn(n) {
 				bc.Return,
 				bc.Return},
 			LineNumberTable: []bc.LineNumberEntry{
-				{Index: 0, LineNum: 0},
-				{Index: 1, LineNum: 4},
-				{Index: 12, LineNum: 5},
-				{Index: 16, LineNum: 0}},
+				{Index: 0, LineNum: 0, Col: 0},
+				{Index: 1, LineNum: 4, Col: 13},
+				{Index: 4, LineNum: 4, Col: 17},
+				{Index: 7, LineNum: 4, Col: 15},
+				{Index: 8, LineNum: 4, Col: 11},
+				{Index: 9, LineNum: 4, Col: 9},
+				{Index: 12, LineNum: 5, Col: 16},
+				{Index: 15, LineNum: 5, Col: 9},
+				{Index: 16, LineNum: 0, Col: 0}},
 			ErrorHandlers: nil,
 		}},
 	})
@@ -871,10 +1121,12 @@ const accumGen = fn(n) {
 				bc.StoreLocal, 0, 1,
 				bc.Return},
 			LineNumberTable: []bc.LineNumberEntry{
-				{Index: 0, LineNum: 0},
-				{Index: 1, LineNum: 2},
-				{Index: 7, LineNum: 3},
-				{Index: 16, LineNum: 0}},
+				{Index: 0, LineNum: 0, Col: 0},
+				{Index: 1, LineNum: 2, Col: 9},
+				{Index: 4, LineNum: 2, Col: 5},
+				{Index: 7, LineNum: 3, Col: 18},
+				{Index: 13, LineNum: 3, Col: 7},
+				{Index: 16, LineNum: 0, Col: 0}},
 			ErrorHandlers: nil,
 		}, &bc.FuncTemplate{
 			Arity:       fixedArity(1),
@@ -888,9 +1140,10 @@ const accumGen = fn(n) {
 				bc.Return,
 				bc.Return},
 			LineNumberTable: []bc.LineNumberEntry{
-				{Index: 0, LineNum: 0},
-				{Index: 1, LineNum: 4},
-				{Index: 11, LineNum: 0}},
+				{Index: 0, LineNum: 0, Col: 0},
+				{Index: 1, LineNum: 4, Col: 12},
+				{Index: 10, LineNum: 4, Col: 5},
+				{Index: 11, LineNum: 0, Col: 0}},
 			ErrorHandlers: nil,
 		}, &bc.FuncTemplate{
 			Arity:       fixedArity(1),
@@ -909,10 +1162,17 @@ const accumGen = fn(n) {
 				bc.Return,
 				bc.Return},
 			LineNumberTable: []bc.LineNumberEntry{
-				{Index: 0, LineNum: 0},
-				{Index: 1, LineNum: 5},
-				{Index: 16, LineNum: 6},
-				{Index: 20, LineNum: 0}},
+				{Index: 0, LineNum: 0, Col: 0},
+				{Index: 1, LineNum: 5, Col: 13},
+				{Index: 4, LineNum: 5, Col: 17},
+				{Index: 7, LineNum: 5, Col: 15},
+				{Index: 8, LineNum: 5, Col: 21},
+				{Index: 11, LineNum: 5, Col: 19},
+				{Index: 12, LineNum: 5, Col: 11},
+				{Index: 13, LineNum: 5, Col: 9},
+				{Index: 16, LineNum: 6, Col: 16},
+				{Index: 19, LineNum: 6, Col: 9},
+				{Index: 20, LineNum: 0, Col: 0}},
 			ErrorHandlers: nil,
 		}},
 	})
@@ -959,12 +1219,20 @@ let d = b--
 				bc.StoreLocal, 0, 3,
 				bc.Return},
 			LineNumberTable: []bc.LineNumberEntry{
-				{Index: 0, LineNum: 0},
-				{Index: 1, LineNum: 2},
-				{Index: 7, LineNum: 3},
-				{Index: 13, LineNum: 4},
-				{Index: 25, LineNum: 5},
-				{Index: 37, LineNum: 0}},
+				{Index: 0, LineNum: 0, Col: 0},
+				{Index: 1, LineNum: 2, Col: 9},
+				{Index: 4, LineNum: 2, Col: 5},
+				{Index: 7, LineNum: 3, Col: 9},
+				{Index: 10, LineNum: 3, Col: 5},
+				{Index: 13, LineNum: 4, Col: 9},
+				{Index: 17, LineNum: 4, Col: 10},
+				{Index: 19, LineNum: 4, Col: 9},
+				{Index: 22, LineNum: 4, Col: 5},
+				{Index: 25, LineNum: 5, Col: 9},
+				{Index: 29, LineNum: 5, Col: 10},
+				{Index: 31, LineNum: 5, Col: 9},
+				{Index: 34, LineNum: 5, Col: 5},
+				{Index: 37, LineNum: 0, Col: 0}},
 			ErrorHandlers: nil,
 		}},
 	})
@@ -1008,7 +1276,7 @@ let p = ls.iter().next()
 				bc.LoadConst, 0, 0,
 				bc.StoreLocal, 0, 0,
 				bc.LoadLocal, 0, 0,
-				bc.GetField, 0, 1,
+				bc.GetField, 0, 0,
 				bc.StoreLocal, 0, 1,
 				bc.LoadLocal, 0, 1,
 				bc.LoadConst, 0, 2,
@@ -1022,26 +1290,101 @@ let p = ls.iter().next()
 				bc.NewList, 0, 1,
 				bc.StoreLocal, 0, 4,
 				bc.LoadLocal, 0, 4,
-				bc.InvokeField, 0, 4, 0, 0,
-				bc.InvokeField, 0, 5, 0, 0,
+				bc.InvokeField, 0, 2, 0, 0,
+				bc.InvokeField, 0, 3, 0, 0,
 				bc.StoreLocal, 0, 5,
 				bc.Return,
 			},
 			LineNumberTable: []bc.LineNumberEntry{
-				{Index: 0, LineNum: 0},
-				{Index: 1, LineNum: 2},
-				{Index: 7, LineNum: 3},
-				{Index: 16, LineNum: 4},
-				{Index: 28, LineNum: 5},
-				{Index: 42, LineNum: 7},
-				{Index: 49, LineNum: 8},
-				{Index: 65, LineNum: 0},
+				{Index: 0, LineNum: 0, Col: 0},
+				{Index: 1, LineNum: 2, Col: 9},
+				{Index: 4, LineNum: 2, Col: 5},
+				{Index: 7, LineNum: 3, Col: 9},
+				{Index: 10, LineNum: 3, Col: 11},
+				{Index: 13, LineNum: 3, Col: 5},
+				{Index: 16, LineNum: 4, Col: 9},
+				{Index: 19, LineNum: 4, Col: 11},
+				{Index: 22, LineNum: 4, Col: 9},
+				{Index: 25, LineNum: 4, Col: 5},
+				{Index: 28, LineNum: 5, Col: 9},
+				{Index: 31, LineNum: 5, Col: 20},
+				{Index: 34, LineNum: 5, Col: 11},
+				{Index: 39, LineNum: 5, Col: 5},
+				{Index: 42, LineNum: 7, Col: 11},
+				{Index: 43, LineNum: 7, Col: 10},
+				{Index: 46, LineNum: 7, Col: 5},
+				{Index: 49, LineNum: 8, Col: 9},
+				{Index: 52, LineNum: 8, Col: 12},
+				{Index: 57, LineNum: 8, Col: 19},
+				{Index: 62, LineNum: 8, Col: 5},
+				{Index: 65, LineNum: 0, Col: 0},
 			},
 			ErrorHandlers: nil,
+			// each field lookup has its own cache slot
+			FieldKeys: []int{1, 1, 4, 5},
 		}},
 	})
 }
 
+func TestFuncNames(t *testing.T) {
+
+	mod := testCompile(t, `
+fn a() {
+    return || => 1
+}
+let b = fn() {}
+let s = struct {
+    c: fn() {},
+    d: prop { || => 2 }
+}
+(|| => 3)()
+`)
+
+	names := []string{}
+	for _, tpl := range mod.Pool.Templates {
+		names = append(names, tpl.Name)
+	}
+	tassert(t, reflect.DeepEqual(names, []string{"", "a", "b", "c", "d", "", "a"}))
+}
+
+func TestTailCall(t *testing.T) {
+
+	mod := testCompile(t, `
+fn a(n) {
+    return a(n)
+}
+let s = struct { b: a }
+return s.b(1 + a(2))
+`)
+
+	tassert(t, reflect.DeepEqual(mod.Pool.Templates[0].Bytecodes, []byte{
+		bc.LoadNull,
+		bc.NewFunc, 0, 1,
+		bc.FuncLocal, 0, 0,
+		bc.StoreLocal, 0, 0,
+		bc.NewStruct, 0, 0,
+		bc.LoadLocal, 0, 0,
+		bc.InitField, 0, 0,
+		bc.StoreLocal, 0, 1,
+		bc.LoadLocal, 0, 1,
+		bc.LoadOne,
+		bc.LoadLocal, 0, 0,
+		bc.LoadConst, 0, 1,
+		bc.Invoke, 0, 1,
+		bc.Plus,
+		bc.TailInvokeField, 0, 0, 0, 1,
+		bc.Return,
+		bc.Return}))
+
+	tassert(t, reflect.DeepEqual(mod.Pool.Templates[1].Bytecodes, []byte{
+		bc.LoadNull,
+		bc.LoadCapture, 0, 0,
+		bc.LoadLocal, 0, 0,
+		bc.TailInvoke, 0, 1,
+		bc.Return,
+		bc.Return}))
+}
+
 //func TestDebug(t *testing.T) {
 //
 //	code := `
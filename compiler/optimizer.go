@@ -0,0 +1,648 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package compiler
+
+import (
+	g "github.com/mjarmy/golem-lang/core"
+	bc "github.com/mjarmy/golem-lang/core/bytecode"
+)
+
+//--------------------------------------------------------------
+// Optimization
+//
+// The optimizer rewrites the bytecode of a function after it has been
+// compiled.  It folds operations on constants, removes instructions that
+// have no effect, threads jumps-to-jumps, and removes unreachable code.
+// Once every function has been optimized, the constants that are no longer
+// used are removed from the pool.
+//
+// An instruction is an 'entry' if control can arrive at it other than by
+// falling through from the previous instruction, i.e. it is the target of a
+// jump, or the boundary of a catch or finally clause.  Rewrites that combine
+// several instructions into one never span an entry.
+//--------------------------------------------------------------
+
+type instruction struct {
+	op   byte
+	p, q int
+
+	// the index of the instruction that a jump goes to
+	target int
+
+	line, col int
+}
+
+type optimizer struct {
+	poolBuilder *poolBuilder
+
+	code []*instruction
+
+	// the indexes of the instructions at the beginning
+	// and end of each catch and finally clause
+	handlers []handlerBounds
+
+	entries map[int]bool
+	changed bool
+}
+
+type handlerBounds struct {
+	catchBegin, catchEnd     int
+	finallyBegin, finallyEnd int
+}
+
+// maxThreading limits how far a jump is threaded, in case of a loop of jumps
+const maxThreading = 16
+
+// optimize rewrites the bytecode, line number table and error handlers
+// of a template.
+func optimize(tpl *bc.FuncTemplate, pb *poolBuilder) {
+
+	opt := &optimizer{poolBuilder: pb}
+	opt.decode(tpl)
+
+	for i := 0; i < 10; i++ {
+		opt.changed = false
+
+		opt.findEntries()
+		opt.foldConstants()
+
+		opt.findEntries()
+		opt.removeNoOps()
+		opt.compact()
+
+		opt.findEntries()
+		opt.threadJumps()
+		opt.compact()
+
+		opt.findEntries()
+		opt.removeDeadCode()
+		opt.compact()
+
+		if !opt.changed {
+			break
+		}
+	}
+
+	opt.encode(tpl)
+}
+
+//--------------------------------------------------------------
+// decoding and encoding
+
+func (opt *optimizer) decode(tpl *bc.FuncTemplate) {
+
+	btc := tpl.Bytecodes
+
+	// map instruction pointers to indexes
+	indexes := map[int]int{}
+	for ip := 0; ip < len(btc); ip += bc.Size(btc[ip]) {
+		indexes[ip] = len(opt.code)
+
+		in := &instruction{op: btc[ip], target: -1}
+		switch bc.Size(btc[ip]) {
+		case 3:
+			in.p = bc.DecodeParam(btc, ip)
+		case 5:
+			in.p, in.q = bc.DecodeWideParams(btc, ip)
+		}
+		in.line, in.col = tpl.Position(ip)
+
+		opt.code = append(opt.code, in)
+	}
+	indexes[len(btc)] = len(opt.code)
+
+	for _, in := range opt.code {
+		if isJump(in.op) {
+			in.target = indexes[in.p]
+		}
+	}
+
+	bound := func(ip int) int {
+		if ip == -1 {
+			return -1
+		}
+		return indexes[ip]
+	}
+	for _, eh := range tpl.ErrorHandlers {
+		opt.handlers = append(opt.handlers, handlerBounds{
+			bound(eh.Catch.Begin), bound(eh.Catch.End),
+			bound(eh.Finally.Begin), bound(eh.Finally.End),
+		})
+	}
+}
+
+func (opt *optimizer) encode(tpl *bc.FuncTemplate) {
+
+	// find the instruction pointer of each instruction
+	ips := make([]int, len(opt.code)+1)
+	ip := 0
+	for i, in := range opt.code {
+		ips[i] = ip
+		ip += bc.Size(in.op)
+	}
+	ips[len(opt.code)] = ip
+
+	btc := make([]byte, 0, ip)
+	lnum := []bc.LineNumberEntry{}
+
+	for i, in := range opt.code {
+
+		n := len(lnum)
+		if n == 0 || in.line != lnum[n-1].LineNum || in.col != lnum[n-1].Col {
+			lnum = append(lnum, bc.LineNumberEntry{Index: ips[i], LineNum: in.line, Col: in.col})
+		}
+
+		if isJump(in.op) {
+			in.p = ips[in.target]
+		}
+
+		switch bc.Size(in.op) {
+		case 1:
+			btc = append(btc, in.op)
+		case 3:
+			high, low := bc.EncodeParam(in.p)
+			btc = append(btc, in.op, high, low)
+		case 5:
+			a, b, c, d := bc.EncodeWideParams(in.p, in.q)
+			btc = append(btc, in.op, a, b, c, d)
+		}
+	}
+
+	bound := func(idx int) int {
+		if idx == -1 {
+			return -1
+		}
+		return ips[idx]
+	}
+	handlers := make([]bc.ErrorHandler, len(opt.handlers))
+	for i, h := range opt.handlers {
+		handlers[i] = bc.ErrorHandler{
+			Catch:   bc.TryClause{Begin: bound(h.catchBegin), End: bound(h.catchEnd)},
+			Finally: bc.TryClause{Begin: bound(h.finallyBegin), End: bound(h.finallyEnd)},
+		}
+	}
+
+	tpl.Bytecodes = btc
+	tpl.LineNumberTable = lnum
+	tpl.ErrorHandlers = handlers
+}
+
+//--------------------------------------------------------------
+// bookkeeping
+
+func isJump(op byte) bool {
+	return op == bc.Jump || op == bc.JumpTrue || op == bc.JumpFalse
+}
+
+func (opt *optimizer) findEntries() {
+
+	opt.entries = map[int]bool{}
+	for _, in := range opt.code {
+		if in != nil && isJump(in.op) {
+			opt.entries[in.target] = true
+		}
+	}
+	for _, h := range opt.handlers {
+		for _, idx := range []int{h.catchBegin, h.catchEnd, h.finallyBegin, h.finallyEnd} {
+			if idx != -1 {
+				opt.entries[idx] = true
+			}
+		}
+	}
+}
+
+func (opt *optimizer) isBoundary(idx int) bool {
+	for _, h := range opt.handlers {
+		if idx == h.catchBegin || idx == h.catchEnd ||
+			idx == h.finallyBegin || idx == h.finallyEnd {
+			return true
+		}
+	}
+	return false
+}
+
+// remove marks an instruction for removal by the next call to compact().
+func (opt *optimizer) remove(idx int) {
+	opt.code[idx] = nil
+	opt.changed = true
+}
+
+// compact gets rid of the removed instructions.  Jumps and clause boundaries
+// that referred to a removed instruction are moved to the next instruction.
+func (opt *optimizer) compact() {
+
+	indexes := make([]int, len(opt.code)+1)
+	code := []*instruction{}
+	for i, in := range opt.code {
+		indexes[i] = len(code)
+		if in != nil {
+			code = append(code, in)
+		}
+	}
+	indexes[len(opt.code)] = len(code)
+
+	for _, in := range code {
+		if isJump(in.op) {
+			in.target = indexes[in.target]
+		}
+	}
+
+	bound := func(idx int) int {
+		if idx == -1 {
+			return -1
+		}
+		return indexes[idx]
+	}
+	for i, h := range opt.handlers {
+		opt.handlers[i] = handlerBounds{
+			bound(h.catchBegin), bound(h.catchEnd),
+			bound(h.finallyBegin), bound(h.finallyEnd),
+		}
+	}
+
+	opt.code = code
+}
+
+//--------------------------------------------------------------
+// constants
+
+// constant returns the value that an instruction loads, if it loads a constant.
+func (opt *optimizer) constant(in *instruction) (g.Basic, bool) {
+	switch in.op {
+	case bc.LoadNull:
+		return g.Null, true
+	case bc.LoadTrue:
+		return g.True, true
+	case bc.LoadFalse:
+		return g.False, true
+	case bc.LoadZero:
+		return g.Zero, true
+	case bc.LoadOne:
+		return g.One, true
+	case bc.LoadNegOne:
+		return g.NegOne, true
+	case bc.LoadConst:
+		return opt.poolBuilder.constAt(in.p), true
+	default:
+		return nil, false
+	}
+}
+
+// loadConstant turns an instruction into one that loads the given constant.
+func (opt *optimizer) loadConstant(in *instruction, val g.Basic) {
+
+	in.p = 0
+	switch val {
+	case g.Null:
+		in.op = bc.LoadNull
+	case g.True:
+		in.op = bc.LoadTrue
+	case g.False:
+		in.op = bc.LoadFalse
+	default:
+		in.op = bc.LoadConst
+		in.p = opt.poolBuilder.constIndex(val)
+
+		if i, ok := val.(g.Int); ok {
+			switch i.ToInt() {
+			case 0:
+				in.op = bc.LoadZero
+			case 1:
+				in.op = bc.LoadOne
+			case -1:
+				in.op = bc.LoadNegOne
+			}
+		}
+	}
+}
+
+func (opt *optimizer) foldConstants() {
+
+	for i := 0; i < len(opt.code); i++ {
+		if opt.foldAt(i) {
+			opt.compact()
+			opt.findEntries()
+
+			// the result might be foldable along with the previous instruction
+			i -= 2
+			if i < -1 {
+				i = -1
+			}
+		}
+	}
+}
+
+// foldAt folds the operation that follows a constant, if possible.
+func (opt *optimizer) foldAt(i int) bool {
+
+	a, ok := opt.constant(opt.code[i])
+	if !ok {
+		return false
+	}
+
+	// unary operations
+	if i+1 < len(opt.code) && !opt.entries[i+1] {
+		next := opt.code[i+1]
+
+		if val, ok := foldUnary(next.op, a); ok {
+			opt.loadConstant(opt.code[i], val)
+			opt.remove(i + 1)
+			return true
+		}
+
+		// a conditional jump on a constant either always jumps, or never does
+		if b, ok := a.(g.Bool); ok && (next.op == bc.JumpTrue || next.op == bc.JumpFalse) {
+			if b.BoolVal() == (next.op == bc.JumpTrue) {
+				next.op = bc.Jump
+			} else {
+				opt.remove(i + 1)
+			}
+			opt.remove(i)
+			return true
+		}
+	}
+
+	// binary operations
+	if i+2 < len(opt.code) && !opt.entries[i+1] && !opt.entries[i+2] {
+		b, ok := opt.constant(opt.code[i+1])
+		if !ok {
+			return false
+		}
+		if val, ok := foldBinary(opt.code[i+2].op, a, b); ok {
+			opt.loadConstant(opt.code[i], val)
+			opt.remove(i + 1)
+			opt.remove(i + 2)
+			return true
+		}
+	}
+
+	return false
+}
+
+// foldUnary performs a unary operation on a constant, if the operation
+// would succeed at run time.
+func foldUnary(op byte, a g.Basic) (g.Basic, bool) {
+
+	switch op {
+	case bc.Negate:
+		if n, ok := a.(g.Number); ok {
+			return n.Negate(), true
+		}
+	case bc.Not:
+		if b, ok := a.(g.Bool); ok {
+			return b.Not(), true
+		}
+	case bc.Complement:
+		if n, ok := a.(g.Int); ok {
+			return n.Complement(), true
+		}
+	}
+	return nil, false
+}
+
+// foldBinary performs a binary operation on two constants, if the operation
+// would succeed at run time.
+func foldBinary(op byte, a g.Basic, b g.Basic) (g.Basic, bool) {
+
+	switch op {
+
+	case bc.Plus:
+		_, sa := a.(g.Str)
+		_, sb := b.(g.Str)
+		if sa || sb {
+			as, err := a.ToStr(nil)
+			if err != nil {
+				return nil, false
+			}
+			bs, err := b.ToStr(nil)
+			if err != nil {
+				return nil, false
+			}
+			s, err := as.Concat(nil, bs)
+			return s, err == nil
+		}
+		if x, y, ok := numbers(a, b); ok {
+			return x.Add(y), true
+		}
+
+	case bc.Sub:
+		if x, y, ok := numbers(a, b); ok {
+			return x.Sub(y), true
+		}
+
+	case bc.Mul:
+		if x, y, ok := numbers(a, b); ok {
+			return x.Mul(y), true
+		}
+
+	case bc.Div:
+		if x, y, ok := numbers(a, b); ok {
+			n, err := x.Div(y)
+			return n, err == nil
+		}
+
+	case bc.Rem, bc.BitAnd, bc.BitOr, bc.BitXor, bc.LeftShift, bc.RightShift:
+		x, xOk := a.(g.Int)
+		y, yOk := b.(g.Int)
+		if xOk && yOk {
+			return foldInts(op, x, y)
+		}
+
+	case bc.Eq, bc.Ne:
+		eq, err := a.Eq(nil, b)
+		if err != nil {
+			return nil, false
+		}
+		if op == bc.Ne {
+			return eq.Not(), true
+		}
+		return eq, true
+
+	case bc.Lt, bc.Lte, bc.Gt, bc.Gte, bc.Cmp:
+		x, xOk := a.(g.Comparable)
+		y, yOk := b.(g.Comparable)
+		if !xOk || !yOk {
+			return nil, false
+		}
+		cmp, err := x.Cmp(nil, y)
+		if err != nil {
+			return nil, false
+		}
+		c := cmp.ToInt()
+		switch op {
+		case bc.Lt:
+			return g.NewBool(c < 0), true
+		case bc.Lte:
+			return g.NewBool(c <= 0), true
+		case bc.Gt:
+			return g.NewBool(c > 0), true
+		case bc.Gte:
+			return g.NewBool(c >= 0), true
+		default:
+			return cmp, true
+		}
+	}
+
+	return nil, false
+}
+
+func numbers(a g.Basic, b g.Basic) (g.Number, g.Number, bool) {
+	x, xOk := a.(g.Number)
+	y, yOk := b.(g.Number)
+	return x, y, xOk && yOk
+}
+
+func foldInts(op byte, x g.Int, y g.Int) (g.Basic, bool) {
+
+	switch op {
+	case bc.Rem:
+		// leave division by zero for the interpreter to deal with
+		if y.ToInt() == 0 {
+			return nil, false
+		}
+		return x.Rem(y), true
+	case bc.BitAnd:
+		return x.BitAnd(y), true
+	case bc.BitOr:
+		return x.BitOr(y), true
+	case bc.BitXor:
+		return x.BitXOr(y), true
+	case bc.LeftShift:
+		n, err := x.LeftShift(y)
+		return n, err == nil
+	case bc.RightShift:
+		n, err := x.RightShift(y)
+		return n, err == nil
+	}
+	return nil, false
+}
+
+//--------------------------------------------------------------
+// the constant pool
+
+// usesConstant returns whether the parameter of an instruction is
+// the index of a constant in the pool.
+func usesConstant(op byte) bool {
+	switch op {
+	case bc.ImportModule, bc.LoadConst,
+		bc.InitField, bc.InitProperty, bc.InitReadonlyProperty, bc.SetField, bc.IncField,
+		bc.MatchField:
+		return true
+	default:
+		return false
+	}
+}
+
+// compactConstants removes the constants that none of the templates use
+// any more, e.g. the operands of an operation that was folded, and then
+// renumbers the constants that remain.
+func compactConstants(pb *poolBuilder) {
+
+	used := make([]bool, len(pb.values))
+	eachConstant(pb.templates, func(idx int) int {
+		used[idx] = true
+		return idx
+	})
+
+	indexes := pb.retainConstants(used)
+	eachConstant(pb.templates, func(idx int) int {
+		return indexes[idx]
+	})
+}
+
+// eachConstant replaces each of the constant indexes in the templates,
+// including the keys of their field caches, with the result of a function.
+func eachConstant(templates []*bc.FuncTemplate, fn func(int) int) {
+
+	for _, tpl := range templates {
+		btc := tpl.Bytecodes
+		for ip := 0; ip < len(btc); ip += bc.Size(btc[ip]) {
+			if usesConstant(btc[ip]) {
+				btc[ip+1], btc[ip+2] = bc.EncodeParam(fn(bc.DecodeParam(btc, ip)))
+			}
+		}
+		for i, key := range tpl.FieldKeys {
+			tpl.FieldKeys[i] = fn(key)
+		}
+	}
+}
+
+//--------------------------------------------------------------
+// peephole rewrites
+
+// removeNoOps removes values that are pushed onto the stack and then
+// immediately popped, and jumps to the next instruction.
+func (opt *optimizer) removeNoOps() {
+
+	for i := 0; i < len(opt.code); i++ {
+		in := opt.code[i]
+
+		if in.op == bc.Jump && in.target == i+1 {
+			opt.remove(i)
+			continue
+		}
+
+		if i+1 < len(opt.code) && !opt.entries[i+1] && opt.code[i+1].op == bc.Pop {
+			switch in.op {
+			case bc.LoadNull, bc.LoadTrue, bc.LoadFalse,
+				bc.LoadZero, bc.LoadOne, bc.LoadNegOne,
+				bc.LoadConst, bc.LoadLocal, bc.LoadCapture, bc.LoadBuiltin,
+				bc.Dup:
+
+				opt.remove(i)
+				opt.remove(i + 1)
+				i++
+			}
+		}
+	}
+}
+
+// threadJumps makes jumps that go to an unconditional jump go
+// straight to the final destination instead.
+func (opt *optimizer) threadJumps() {
+
+	for _, in := range opt.code {
+		if !isJump(in.op) {
+			continue
+		}
+
+		for n := 0; n < maxThreading; n++ {
+
+			// The interpreter relies on reaching the end of a catch or
+			// finally clause exactly, so jumps to a clause boundary stay put.
+			t := in.target
+			if t >= len(opt.code) || opt.isBoundary(t) {
+				break
+			}
+
+			next := opt.code[t]
+			if next.op != bc.Jump || next.target == t {
+				break
+			}
+			in.target = next.target
+			opt.changed = true
+		}
+	}
+}
+
+// removeDeadCode removes the instructions that can never be reached, because
+// they follow an unconditional jump, return or throw, and are not an entry.
+func (opt *optimizer) removeDeadCode() {
+
+	reachable := true
+	for i, in := range opt.code {
+		if opt.entries[i] {
+			reachable = true
+		}
+		if !reachable {
+			opt.remove(i)
+			continue
+		}
+		switch in.op {
+		case bc.Jump, bc.Return, bc.Throw:
+			reachable = false
+		}
+	}
+}
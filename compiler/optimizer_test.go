@@ -0,0 +1,157 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package compiler
+
+import (
+	"reflect"
+	"testing"
+
+	g "github.com/mjarmy/golem-lang/core"
+	bc "github.com/mjarmy/golem-lang/core/bytecode"
+	"github.com/mjarmy/golem-lang/scanner"
+)
+
+func testOptimize(t *testing.T, code string) *bc.Module {
+
+	source := &scanner.Source{Name: "foo", Path: "foo.glm", Code: code}
+	mod, err := CompileSource(source, builtins, WithOptimization())
+	tassert(t, err == nil)
+
+	return mod
+}
+
+func TestOptimizePeephole(t *testing.T) {
+
+	pb := newPoolBuilder()
+	pb.constIndex(g.NewInt(3))
+	pb.constIndex(g.NewInt(4))
+
+	tpl := &bc.FuncTemplate{
+		Bytecodes: []byte{
+			bc.LoadNull,
+			bc.LoadLocal, 0, 0,
+			bc.Pop,
+			bc.Jump, 0, 8,
+			bc.Jump, 0, 13,
+			bc.LoadOne,
+			bc.Return,
+			bc.LoadConst, 0, 0,
+			bc.LoadConst, 0, 1,
+			bc.Mul,
+			bc.Return},
+		LineNumberTable: []bc.LineNumberEntry{
+			{Index: 0, LineNum: 0, Col: 0},
+			{Index: 1, LineNum: 1, Col: 1},
+			{Index: 5, LineNum: 2, Col: 1},
+			{Index: 8, LineNum: 3, Col: 1},
+			{Index: 13, LineNum: 4, Col: 1},
+			{Index: 16, LineNum: 4, Col: 5},
+			{Index: 19, LineNum: 4, Col: 3},
+			{Index: 20, LineNum: 0, Col: 0}},
+		ErrorHandlers: []bc.ErrorHandler{},
+	}
+
+	optimize(tpl, pb)
+
+	tassert(t, reflect.DeepEqual(tpl.Bytecodes, []byte{
+		bc.LoadNull,
+		bc.LoadConst, 0, 2,
+		bc.Return}))
+
+	tassert(t, reflect.DeepEqual(tpl.LineNumberTable, []bc.LineNumberEntry{
+		{Index: 0, LineNum: 0, Col: 0},
+		{Index: 1, LineNum: 4, Col: 1},
+		{Index: 4, LineNum: 0, Col: 0}}))
+
+	tassert(t, reflect.DeepEqual(pb.makeConstants(), []g.Basic{
+		g.NewInt(3), g.NewInt(4), g.NewInt(12)}))
+}
+
+func TestOptimizeConstants(t *testing.T) {
+
+	mod := testOptimize(t, `
+let a = 2 * 3 + 'x'
+let b = -(4 - 10) / 2 < 5
+let c = 1 / 0
+while true {
+    if b { break; }
+}`)
+
+	tpl := mod.Pool.Templates[0]
+	tassert(t, reflect.DeepEqual(tpl.Bytecodes, []byte{
+		bc.LoadNull,
+		bc.LoadConst, 0, 0,
+		bc.StoreLocal, 0, 0,
+		bc.LoadTrue,
+		bc.StoreLocal, 0, 1,
+		bc.LoadOne,
+		bc.LoadZero,
+		bc.Div,
+		bc.StoreLocal, 0, 2,
+		bc.LoadLocal, 0, 1,
+		bc.JumpFalse, 0, 17,
+		bc.Return}))
+
+	// the constants that were folded away are no longer in the pool
+	tassert(t, reflect.DeepEqual(mod.Pool.Constants, []g.Basic{g.MustStr("6x")}))
+}
+
+func TestOptimizeTry(t *testing.T) {
+
+	mod := testOptimize(t, `
+let a = 0
+try {
+    a = 1 + 2
+    return a
+    a = 5
+} finally {
+    a = 4
+}`)
+
+	tpl := mod.Pool.Templates[0]
+	tassert(t, reflect.DeepEqual(tpl.Bytecodes, []byte{
+		bc.LoadNull,
+		bc.LoadZero,
+		bc.StoreLocal, 0, 0,
+		bc.PushTry, 0, 0,
+		bc.LoadConst, 0, 1,
+		bc.Dup,
+		bc.StoreLocal, 0, 0,
+		bc.LoadLocal, 0, 0,
+		bc.Return,
+		bc.LoadConst, 0, 0,
+		bc.Dup,
+		bc.StoreLocal, 0, 0,
+		bc.Return}))
+
+	tassert(t, reflect.DeepEqual(mod.Pool.Constants, []g.Basic{g.NewInt(4), g.NewInt(3)}))
+
+	tassert(t, reflect.DeepEqual(tpl.ErrorHandlers, []bc.ErrorHandler{
+		{
+			Catch:   bc.TryClause{Begin: -1, End: -1},
+			Finally: bc.TryClause{Begin: 19, End: 26},
+		}}))
+}
+
+func TestOptimizeConstantPool(t *testing.T) {
+
+	mod := testOptimize(t, `
+let s = struct { a: 2 * 3 }
+s.a = s.a + 10 * 10
+`)
+
+	// every reference to a constant is renumbered
+	tassert(t, reflect.DeepEqual(mod.Pool.Constants, []g.Basic{
+		g.MustStr("a"), g.NewInt(6), g.NewInt(100)}))
+
+	tpl := mod.Pool.Templates[0]
+	tassert(t, reflect.DeepEqual(tpl.FieldKeys, []int{0}))
+	for ip := 0; ip < len(tpl.Bytecodes); ip += bc.Size(tpl.Bytecodes[ip]) {
+		switch tpl.Bytecodes[ip] {
+		case bc.InitField, bc.SetField:
+			tassert(t, bc.DecodeParam(tpl.Bytecodes, ip) == 0)
+		}
+	}
+}
@@ -5,8 +5,6 @@
 package compiler
 
 import (
-	"sort"
-
 	g "github.com/mjarmy/golem-lang/core"
 	bc "github.com/mjarmy/golem-lang/core/bytecode"
 )
@@ -14,6 +12,7 @@ import (
 // poolBuilder builds a Pool
 type poolBuilder struct {
 	constants  *g.HashMap
+	values     []g.Basic
 	templates  []*bc.FuncTemplate
 	structDefs [][]string
 }
@@ -21,6 +20,7 @@ type poolBuilder struct {
 func newPoolBuilder() *poolBuilder {
 	return &poolBuilder{
 		constants:  g.EmptyHashMap(),
+		values:     []g.Basic{},
 		templates:  []*bc.FuncTemplate{},
 		structDefs: [][]string{},
 	}
@@ -47,9 +47,32 @@ func (p *poolBuilder) constIndex(key g.Basic) int {
 	i := p.constants.Len()
 	err = p.constants.Put(ev, key, i)
 	g.Assert(err == nil)
+	p.values = append(p.values, key)
 	return int(i.ToInt())
 }
 
+// constAt returns the constant at the given index
+func (p *poolBuilder) constAt(idx int) g.Basic {
+	return p.values[idx]
+}
+
+// retainConstants removes the constants that are not used, and returns
+// the new index of each of the constants that remain.
+func (p *poolBuilder) retainConstants(used []bool) []int {
+
+	values := p.values
+	p.constants = g.EmptyHashMap()
+	p.values = []g.Basic{}
+
+	indexes := make([]int, len(values))
+	for i, val := range values {
+		if used[i] {
+			indexes[i] = p.constIndex(val)
+		}
+	}
+	return indexes
+}
+
 func (p *poolBuilder) addTemplate(tpl *bc.FuncTemplate) {
 	p.templates = append(p.templates, tpl)
 }
@@ -73,26 +96,7 @@ func (p *poolBuilder) build() *bc.Pool {
 
 func (p *poolBuilder) makeConstants() []g.Basic {
 
-	n := int(p.constants.Len().ToInt())
-
-	entries := make([]*g.HEntry, 0, n)
-	itr := p.constants.Iterator()
-	for itr.Next() {
-		entries = append(entries, itr.Get())
-	}
-
-	sort.Slice(entries, func(i, j int) bool {
-		x := entries[i].Value.(g.Int)
-		y := entries[j].Value.(g.Int)
-		return x.ToInt() < y.ToInt()
-	})
-
-	constants := make([]g.Basic, n)
-	for i, e := range entries {
-		b, ok := e.Key.(g.Basic)
-		g.Assert(ok)
-		constants[i] = b
-	}
-
+	constants := make([]g.Basic, len(p.values))
+	copy(constants, p.values)
 	return constants
 }
@@ -327,7 +327,7 @@ var BuiltinIter = NewFixedNativeFunc(
 			return nil, NullValueError()
 		}
 
-		if ibl, ok := params[0].(Iterable); ok {
+		if ibl, ok := AsIterable(params[0]); ok {
 			return ibl.NewIterator(ev)
 		}
 		return nil, IterableMismatch(params[0].Type())
@@ -502,9 +502,9 @@ var BuiltinStream = NewFixedNativeFunc(
 	false,
 	func(ev Eval, params []Value) (Value, Error) {
 
-		ibl, ok := params[0].(Iterable)
+		ibl, ok := AsIterable(params[0])
 		if !ok {
-			return nil, fmt.Errorf("TypeMismatch: stream() expected iterable value, got %s", params[0].Type())
+			return nil, NewError("TypeMismatch", fmt.Sprintf("stream() expected iterable value, got %s", params[0].Type()))
 		}
 		itr, err := ibl.NewIterator(ev)
 		if err != nil {
@@ -547,8 +547,8 @@ var streamMethods = map[string]Method{
 			fn := params[0].(Func)
 			expected := Arity{FixedArity, 1, 0}
 			if fn.Arity() != expected {
-				return nil, fmt.Errorf(
-					"ArityMismatch: filter function must have 1 parameter")
+				return nil, NewError(
+					"ArityMismatch", "filter function must have 1 parameter")
 			}
 
 			// transform
@@ -560,8 +560,8 @@ var streamMethods = map[string]Method{
 
 				result, ok := val.(Bool)
 				if !ok {
-					return nil, fmt.Errorf(
-						"TypeMismatch: filter function must return Bool, not %s", val.Type())
+					return nil, NewError(
+						"TypeMismatch", fmt.Sprintf("filter function must return Bool, not %s", val.Type()))
 				}
 				return result, nil
 			})
@@ -591,8 +591,8 @@ var streamMethods = map[string]Method{
 			fn := params[0].(Func)
 			expected := Arity{FixedArity, 1, 0}
 			if fn.Arity() != expected {
-				return nil, fmt.Errorf(
-					"ArityMismatch: map function must have 1 parameter")
+				return nil, NewError(
+					"ArityMismatch", "map function must have 1 parameter")
 			}
 
 			// transform
@@ -636,8 +636,8 @@ var streamMethods = map[string]Method{
 			fn := params[1].(Func)
 			expected := Arity{FixedArity, 2, 0}
 			if fn.Arity() != expected {
-				return nil, fmt.Errorf(
-					"ArityMismatch: reduce function must have 2 parameters")
+				return nil, NewError(
+					"ArityMismatch", "reduce function must have 2 parameters")
 			}
 
 			// invoke
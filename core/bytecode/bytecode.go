@@ -62,8 +62,12 @@ const (
 	FuncLocal
 
 	Invoke
+	TailInvoke
 	Go
+	Defer
+	Select
 	Return
+	Yield
 
 	PushTry
 	PopTry
@@ -74,10 +78,17 @@ const (
 	NewList
 	NewSet
 	NewTuple
+	Concat
 	CheckTuple
+	CheckList
+	CheckStruct
+	MatchType
+	MatchLen
+	MatchField
 
 	GetField
 	InvokeField
+	TailInvokeField
 	InitField
 	InitProperty
 	InitReadonlyProperty
@@ -197,10 +208,18 @@ func String(bc byte) string {
 
 	case Invoke:
 		return "Invoke"
+	case TailInvoke:
+		return "TailInvoke"
 	case Go:
 		return "Go"
+	case Defer:
+		return "Defer"
+	case Select:
+		return "Select"
 	case Return:
 		return "Return"
+	case Yield:
+		return "Yield"
 
 	case PushTry:
 		return "PushTry"
@@ -226,11 +245,15 @@ func String(bc byte) string {
 		return "NewSet"
 	case NewTuple:
 		return "NewTuple"
+	case Concat:
+		return "Concat"
 
 	case GetField:
 		return "GetField"
 	case InvokeField:
 		return "InvokeField"
+	case TailInvokeField:
+		return "TailInvokeField"
 	case SetField:
 		return "SetField"
 	case IncField:
@@ -259,6 +282,16 @@ func String(bc byte) string {
 
 	case CheckTuple:
 		return "CheckTuple"
+	case CheckList:
+		return "CheckList"
+	case CheckStruct:
+		return "CheckStruct"
+	case MatchType:
+		return "MatchType"
+	case MatchLen:
+		return "MatchLen"
+	case MatchField:
+		return "MatchField"
 
 	case Pop:
 		return "Pop"
@@ -286,9 +319,9 @@ func Size(bc byte) int {
 		Plus, Inc, Sub, Mul, Div,
 		Rem, BitAnd, BitOr, BitXor, LeftShift, RightShift,
 		Negate, Not, Complement,
-		Return, PopTry, Throw,
+		Return, Yield, PopTry, Throw,
 		GetIndex, SetIndex, IncIndex, Slice, SliceFrom, SliceTo,
-		NewIter, IterNext, IterGet, Pop, Dup:
+		NewIter, IterNext, IterGet, Pop, Dup, CheckStruct:
 
 		return 1
 
@@ -296,15 +329,16 @@ func Size(bc byte) int {
 		ImportModule, LoadBuiltin, LoadConst,
 		LoadLocal, LoadCapture, StoreLocal, StoreCapture,
 		Jump, JumpTrue, JumpFalse, Break, Continue,
-		NewFunc, FuncCapture, FuncLocal, Invoke, Go, PushTry,
+		NewFunc, FuncCapture, FuncLocal, Invoke, TailInvoke, Defer, PushTry,
 		NewStruct, GetField,
 		InitField, InitProperty, InitReadonlyProperty,
 		SetField, IncField,
-		NewDict, NewList, NewSet, NewTuple, CheckTuple:
+		NewDict, NewList, NewSet, NewTuple, Concat, CheckTuple,
+		MatchType, MatchField:
 
 		return 3
 
-	case InvokeField:
+	case Go, InvokeField, TailInvokeField, Select, CheckList, MatchLen:
 
 		return 5
 
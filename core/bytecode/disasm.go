@@ -0,0 +1,316 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package bytecode
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	g "github.com/mjarmy/golem-lang/core"
+)
+
+// Disassemble returns a readable listing of a Module's constants, struct
+// definitions and function templates.  Jump targets are shown as labels,
+// and the operands of each instruction are described alongside it.
+//
+// The Module must have been compiled with the given builtins.  If the source
+// code of the Module is provided, the lines of source code are interleaved with
+// the instructions that were compiled from them.
+func Disassemble(mod *Module, builtins []*g.Builtin, source string) string {
+
+	d := &disassembler{mod: mod, builtins: builtins}
+	if source != "" {
+		d.lines = strings.Split(source, "\n")
+	}
+
+	d.printf("module %s (%s)\n", mod.name, mod.Path)
+
+	pool := mod.Pool
+
+	d.printf("\nconstants:\n")
+	for i, c := range pool.Constants {
+		d.printf("    %-4d %-6s %s\n", i, c.Type(), basicString(c))
+	}
+
+	d.printf("\nstruct defs:\n")
+	for i, def := range pool.StructDefs {
+		d.printf("    %-4d %s\n", i, structDefString(def))
+	}
+
+	for i, t := range pool.Templates {
+		d.template(i, t)
+	}
+
+	return d.buf.String()
+}
+
+type disassembler struct {
+	buf      bytes.Buffer
+	mod      *Module
+	builtins []*g.Builtin
+	lines    []string
+}
+
+func (d *disassembler) printf(format string, args ...interface{}) {
+	d.buf.WriteString(fmt.Sprintf(format, args...))
+}
+
+func (d *disassembler) template(idx int, t *FuncTemplate) {
+
+	d.printf("\nfn %d: %s\n", idx, templateName(idx, t))
+
+	a := t.Arity
+	switch a.Kind {
+	case g.FixedArity:
+		d.printf("    arity:      %d\n", a.Required)
+	case g.VariadicArity:
+		d.printf("    arity:      %d or more\n", a.Required)
+	case g.MultipleArity:
+		d.printf("    arity:      %d to %d, defaults %s\n",
+			a.Required, a.Required+a.Optional, valuesString(t.OptionalParams))
+	}
+	if t.IsGenerator {
+		d.printf("    generator:  true\n")
+	}
+	d.printf("    locals:     %d %s\n", t.NumLocals, namesString(t.LocalNames))
+	d.printf("    captures:   %d %s\n", t.NumCaptures, namesString(t.CaptureNames))
+
+	labels := jumpLabels(t)
+
+	if len(t.ErrorHandlers) > 0 {
+		d.printf("    handlers:\n")
+		for i, eh := range t.ErrorHandlers {
+			d.printf("        %d: catch %s, finally %s\n", i,
+				clauseString(eh.Catch, labels), clauseString(eh.Finally, labels))
+		}
+	}
+
+	d.printf("    code:\n")
+
+	btc := t.Bytecodes
+	curLine := -1
+	for ip := 0; ip < len(btc); ip += Size(btc[ip]) {
+
+		if lbl, ok := labels[ip]; ok {
+			d.printf("    %s:\n", lbl)
+		}
+
+		if line := t.LineNumber(ip); line != curLine {
+			curLine = line
+			if line > 0 && line <= len(d.lines) {
+				d.printf("        // %d: %s\n", line, strings.TrimSpace(d.lines[line-1]))
+			}
+		}
+
+		operands, comment := d.operands(t, btc, ip, labels)
+		line := fmt.Sprintf("        %-5d %-20s %s", ip, String(btc[ip]), operands)
+		if comment != "" {
+			line = fmt.Sprintf("%-44s ; %s", line, comment)
+		}
+		d.printf("%s\n", strings.TrimRight(line, " "))
+	}
+}
+
+// operands describes the operands of an instruction.
+func (d *disassembler) operands(
+	t *FuncTemplate,
+	btc []byte,
+	ip int,
+	labels map[int]string) (string, string) {
+
+	op := btc[ip]
+
+	switch Size(op) {
+	case 1:
+		return "", ""
+	case 5:
+		p, q := DecodeWideParams(btc, ip)
+		switch op {
+		case InvokeField, TailInvokeField:
+			return fmt.Sprintf("%d %d", p, q),
+				d.fieldKey(t, p) + ", " + numArgs(q)
+		case Go:
+			return fmt.Sprintf("%d %d", p, q),
+				fmt.Sprintf("%s, detached: %t", numArgs(p), q != 0)
+		case Select:
+			return fmt.Sprintf("%d %d", p, q),
+				fmt.Sprintf("%d cases, default: %t", p, q != 0)
+		case CheckList, MatchLen:
+			return fmt.Sprintf("%d %d", p, q),
+				fmt.Sprintf("%d elements, rest: %t", p, q != 0)
+		}
+		return fmt.Sprintf("%d %d", p, q), ""
+	}
+
+	p := DecodeParam(btc, ip)
+	operand := fmt.Sprintf("%d", p)
+
+	switch op {
+
+	case Jump, JumpTrue, JumpFalse:
+		return labels[p], ""
+
+	case GetField:
+		return operand, d.fieldKey(t, p)
+
+	case ImportModule, LoadConst,
+		InitField, InitProperty, InitReadonlyProperty, SetField, IncField,
+		MatchField:
+		return operand, d.constant(p)
+
+	case MatchType:
+		return operand, g.Type(p).String()
+
+	case LoadBuiltin:
+		if p < len(d.builtins) {
+			return operand, d.builtins[p].Name
+		}
+
+	case LoadLocal, StoreLocal:
+		return operand, nameAt(t.LocalNames, p)
+
+	case LoadCapture, StoreCapture:
+		return operand, nameAt(t.CaptureNames, p)
+
+	case NewFunc:
+		if p < len(d.mod.Pool.Templates) {
+			return operand, "fn " + templateName(p, d.mod.Pool.Templates[p])
+		}
+
+	case FuncLocal:
+		return operand, "local " + nameAt(t.LocalNames, p)
+
+	case FuncCapture:
+		return operand, "capture " + nameAt(t.CaptureNames, p)
+
+	case NewStruct:
+		if p < len(d.mod.Pool.StructDefs) {
+			return operand, structDefString(d.mod.Pool.StructDefs[p])
+		}
+
+	case PushTry:
+		return operand, "handler"
+
+	case Invoke, TailInvoke, Defer:
+		return operand, numArgs(p)
+
+	case Concat:
+		return operand, fmt.Sprintf("%d values", p)
+	}
+
+	return operand, ""
+}
+
+func (d *disassembler) constant(idx int) string {
+	pool := d.mod.Pool
+	if idx >= len(pool.Constants) {
+		return ""
+	}
+	return basicString(pool.Constants[idx])
+}
+
+// fieldKey describes the constant that names the field looked up via
+// the given inline cache slot.
+func (d *disassembler) fieldKey(t *FuncTemplate, slot int) string {
+	if slot >= len(t.FieldKeys) {
+		return ""
+	}
+	return d.constant(t.FieldKeys[slot])
+}
+
+// jumpLabels assigns a label to each of the instructions that are
+// the target of a jump, or the boundary of a catch or finally clause.
+func jumpLabels(t *FuncTemplate) map[int]string {
+
+	targets := []int{}
+
+	btc := t.Bytecodes
+	for ip := 0; ip < len(btc); ip += Size(btc[ip]) {
+		switch btc[ip] {
+		case Jump, JumpTrue, JumpFalse:
+			targets = append(targets, DecodeParam(btc, ip))
+		}
+	}
+	for _, eh := range t.ErrorHandlers {
+		for _, tc := range []TryClause{eh.Catch, eh.Finally} {
+			if !tc.IsEmpty() {
+				targets = append(targets, tc.Begin, tc.End)
+			}
+		}
+	}
+	sort.Ints(targets)
+
+	labels := map[int]string{}
+	for _, ip := range targets {
+		if _, ok := labels[ip]; !ok {
+			labels[ip] = fmt.Sprintf("L%d", len(labels)+1)
+		}
+	}
+	return labels
+}
+
+func numArgs(n int) string {
+	if n == 1 {
+		return "1 arg"
+	}
+	return fmt.Sprintf("%d args", n)
+}
+
+func templateName(idx int, t *FuncTemplate) string {
+	switch {
+	case t.Name != "":
+		return t.Name
+	case idx == 0:
+		return "<module>"
+	default:
+		return "<anonymous>"
+	}
+}
+
+func clauseString(tc TryClause, labels map[int]string) string {
+	if tc.IsEmpty() {
+		return "none"
+	}
+	return labels[tc.Begin] + ".." + labels[tc.End]
+}
+
+func nameAt(names []string, idx int) string {
+	if idx < len(names) && names[idx] != "" {
+		return names[idx]
+	}
+	return fmt.Sprintf("#%d", idx)
+}
+
+func namesString(names []string) string {
+	list := make([]string, len(names))
+	for i := range names {
+		list[i] = nameAt(names, i)
+	}
+	return "[" + strings.Join(list, ", ") + "]"
+}
+
+func structDefString(def []string) string {
+	return "{" + strings.Join(def, ", ") + "}"
+}
+
+func valuesString(values []g.Value) string {
+	list := make([]string, len(values))
+	for i, v := range values {
+		list[i] = basicString(v.(g.Basic))
+	}
+	return "[" + strings.Join(list, ", ") + "]"
+}
+
+// basicString formats a constant the way that it would be written in source code.
+func basicString(b g.Basic) string {
+	if s, ok := b.(g.Str); ok {
+		return fmt.Sprintf("%q", s.String())
+	}
+	s, err := b.ToStr(nil)
+	g.Assert(err == nil)
+	return s.String()
+}
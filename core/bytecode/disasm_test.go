@@ -0,0 +1,111 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package bytecode
+
+import (
+	"testing"
+
+	g "github.com/mjarmy/golem-lang/core"
+)
+
+func TestDisassemble(t *testing.T) {
+
+	source := `let a = 1
+fn f(x, y = 7) {
+    try { return x; } finally { y = 0; }
+}
+`
+	mod := NewModule("foo", "/tmp/foo.glm")
+	mod.Pool = &Pool{
+		Constants:  []g.Basic{g.MustStr("abc"), g.NewFloat(1.5)},
+		StructDefs: [][]string{{"x", "y"}},
+		Templates: []*FuncTemplate{
+			{
+				Module:    mod,
+				Arity:     g.Arity{Kind: g.FixedArity, Required: 0, Optional: 0},
+				NumLocals: 2,
+				Bytecodes: []byte{
+					LoadNull,
+					LoadOne, StoreLocal, 0, 0,
+					NewFunc, 0, 1, StoreLocal, 0, 1,
+					Return},
+				LineNumberTable: []LineNumberEntry{{0, 0, 0}, {1, 1, 9}, {5, 2, 1}, {11, 0, 0}},
+				ErrorHandlers:   []ErrorHandler{},
+				LocalNames:      []string{"a", "f"},
+				CaptureNames:    []string{},
+			},
+			{
+				Module:         mod,
+				Name:           "f",
+				Arity:          g.Arity{Kind: g.MultipleArity, Required: 1, Optional: 1},
+				OptionalParams: []g.Value{g.NewInt(7)},
+				NumLocals:      2,
+				Bytecodes: []byte{
+					LoadNull,
+					PushTry, 0, 0,
+					LoadLocal, 0, 0,
+					Jump, 0, 12,
+					PopTry,
+					Return,
+					LoadZero, StoreLocal, 0, 1,
+					Return},
+				LineNumberTable: []LineNumberEntry{{0, 0, 0}, {1, 3, 5}},
+				ErrorHandlers: []ErrorHandler{
+					{TryClause{-1, -1}, TryClause{12, 16}},
+				},
+				LocalNames:   []string{"x", "y"},
+				CaptureNames: []string{},
+			},
+		},
+	}
+
+	expect := `module foo (/tmp/foo.glm)
+
+constants:
+    0    Str    "abc"
+    1    Float  1.5
+
+struct defs:
+    0    {x, y}
+
+fn 0: <module>
+    arity:      0
+    locals:     2 [a, f]
+    captures:   0 []
+    code:
+        0     LoadNull
+        // 1: let a = 1
+        1     LoadOne
+        2     StoreLocal           0         ; a
+        // 2: fn f(x, y = 7) {
+        5     NewFunc              1         ; fn f
+        8     StoreLocal           1         ; f
+        11    Return
+
+fn 1: f
+    arity:      1 to 2, defaults [7]
+    locals:     2 [x, y]
+    captures:   0 []
+    handlers:
+        0: catch none, finally L1..L2
+    code:
+        0     LoadNull
+        // 3: try { return x; } finally { y = 0; }
+        1     PushTry              0         ; handler
+        4     LoadLocal            0         ; x
+        7     Jump                 L1
+        10    PopTry
+        11    Return
+    L1:
+        12    LoadZero
+        13    StoreLocal           1         ; y
+    L2:
+        16    Return
+`
+	result := Disassemble(mod, nil, source)
+	if result != expect {
+		t.Error("expected\n" + expect + "\nreceived\n" + result)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package bytecode
+
+import (
+	"sync/atomic"
+
+	g "github.com/mjarmy/golem-lang/core"
+)
+
+// FieldCache is an inline cache entry for a GetField, InvokeField or
+// TailInvokeField instruction.  It records the Shape of a Struct whose field
+// was looked up by the instruction, and the index of the field within
+// that Shape.
+type FieldCache struct {
+	Shape *g.Shape
+	Index int
+}
+
+// InitCaches creates a Shape for each of the Pool's struct definitions,
+// and an empty inline cache for each of the field lookups in the
+// Pool's templates.  It must be called after the Pool is complete,
+// and before any of the Pool's code is run.
+func (p *Pool) InitCaches() {
+
+	p.shapes = make([]*g.Shape, len(p.StructDefs))
+	for i, def := range p.StructDefs {
+		// A definition that is not valid will be reported
+		// when a struct is created from it.
+		if shape, err := g.NewShape(def); err == nil {
+			p.shapes[i] = shape
+		}
+	}
+
+	for _, t := range p.Templates {
+		t.fieldCaches = make([]atomic.Value, len(t.FieldKeys))
+	}
+}
+
+// Shape returns the Shape of the struct definition at the given index,
+// or nil if there is no such Shape.
+func (p *Pool) Shape(idx int) *g.Shape {
+	if idx < len(p.shapes) {
+		return p.shapes[idx]
+	}
+	return nil
+}
+
+// MaxFieldCaches is the maximum number of entries in a single inline cache.
+// Once the cache is full, the instruction that uses it is megamorphic, and
+// the cache is no longer updated.
+const MaxFieldCaches = 4
+
+// FieldCaches returns the entries of the inline cache in the given slot.
+func (t *FuncTemplate) FieldCaches(slot int) []FieldCache {
+	if slot < len(t.fieldCaches) {
+		return loadFieldCaches(t.fieldCaches[slot].Load())
+	}
+	return nil
+}
+
+// loadFieldCaches returns the entries of an inline cache, given the
+// cache's current value.
+func loadFieldCaches(val interface{}) []FieldCache {
+	if caches, ok := val.(*[]FieldCache); ok {
+		return *caches
+	}
+	return nil
+}
+
+// AddFieldCache adds an entry to the inline cache in the given slot, unless
+// the cache is already full, or already has an entry for the Shape.
+//
+// The cache may be shared by several goroutines, so the entries are never
+// modified in place.  Instead, the cache is replaced via compare-and-swap,
+// which is retried if another goroutine has replaced the cache in the
+// meantime.  That way no entry is ever lost, and the cache never has more
+// than MaxFieldCaches entries.
+func (t *FuncTemplate) AddFieldCache(slot int, fc FieldCache) {
+	if slot >= len(t.fieldCaches) {
+		return
+	}
+	cache := &t.fieldCaches[slot]
+
+	for {
+		val := cache.Load()
+		caches := loadFieldCaches(val)
+
+		n := len(caches)
+		if n == MaxFieldCaches {
+			return
+		}
+		for _, c := range caches {
+			if c.Shape == fc.Shape {
+				return
+			}
+		}
+
+		entries := make([]FieldCache, n+1)
+		copy(entries, caches)
+		entries[n] = fc
+
+		if cache.CompareAndSwap(val, &entries) {
+			return
+		}
+	}
+}
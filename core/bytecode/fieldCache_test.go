@@ -0,0 +1,65 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package bytecode
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	g "github.com/mjarmy/golem-lang/core"
+)
+
+func TestAddFieldCache(t *testing.T) {
+
+	pool := &Pool{
+		StructDefs: [][]string{},
+		Templates:  []*FuncTemplate{{FieldKeys: []int{0}}},
+	}
+	for i := 0; i < 2*MaxFieldCaches; i++ {
+		pool.StructDefs = append(pool.StructDefs, []string{"x", fmt.Sprintf("f%d", i)})
+	}
+	pool.InitCaches()
+	tpl := pool.Templates[0]
+
+	// goroutines that fill the same slot at the same time never lose
+	// an entry, or add too many
+	add := func(shapes []*g.Shape) {
+		var wg sync.WaitGroup
+		for _, shape := range shapes {
+			for j := 0; j < 10; j++ {
+				wg.Add(1)
+				go func(shape *g.Shape) {
+					defer wg.Done()
+					tpl.AddFieldCache(0, FieldCache{Shape: shape, Index: 0})
+				}(shape)
+			}
+		}
+		wg.Wait()
+	}
+
+	shapes := []*g.Shape{}
+	for i := range pool.StructDefs {
+		shapes = append(shapes, pool.Shape(i))
+	}
+
+	add(shapes[:MaxFieldCaches-1])
+	caches := tpl.FieldCaches(0)
+	tassert(t, len(caches) == MaxFieldCaches-1)
+
+	add(shapes)
+	caches = tpl.FieldCaches(0)
+	tassert(t, len(caches) == MaxFieldCaches)
+
+	// the entries are distinct, and the first ones are still there
+	seen := map[*g.Shape]bool{}
+	for _, fc := range caches {
+		tassert(t, !seen[fc.Shape])
+		seen[fc.Shape] = true
+	}
+	for _, shape := range shapes[:MaxFieldCaches-1] {
+		tassert(t, seen[shape])
+	}
+}
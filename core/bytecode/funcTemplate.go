@@ -6,15 +6,21 @@ package bytecode
 
 import (
 	"fmt"
+	"sync/atomic"
 
 	g "github.com/mjarmy/golem-lang/core"
 )
 
 // FuncTemplate represents the information needed to invoke a function
-// instance.  Templates are created at compile time, and
-// are immutable at run time.
+// instance.  Templates are created at compile time.  At run time, the
+// only part of a template that changes is its inline field caches.
 type FuncTemplate struct {
-	Module          *Module
+	Module *Module
+
+	// Name is the name of the named function or struct method that
+	// contains the template's code.  It is empty for module-level code.
+	Name string
+
 	Arity           g.Arity
 	OptionalParams  []g.Value
 	NumCaptures     int
@@ -22,32 +28,58 @@ type FuncTemplate struct {
 	Bytecodes       []byte
 	LineNumberTable []LineNumberEntry
 	ErrorHandlers   []ErrorHandler
+	IsGenerator     bool
+
+	// LocalNames and CaptureNames are the names of the function's
+	// variables, for use by debuggers.  The name of a local that
+	// does not correspond to a variable in the source code is empty.
+	LocalNames   []string
+	CaptureNames []string
+
+	// FieldKeys are the inline cache slots of the function's GetField,
+	// InvokeField and TailInvokeField instructions, each of which has its own
+	// slot.  A slot holds the index of the constant that names the field.
+	FieldKeys []int
+
+	fieldCaches []atomic.Value
 }
 
-// LineNumberEntry tracks which sequence of opcodes are on a given source code line
+// LineNumberEntry tracks which sequence of opcodes are at a given
+// line and column of source code
 type LineNumberEntry struct {
 	Index   int
 	LineNum int
+	Col     int
 }
 
 func (ln LineNumberEntry) String() string {
 	return fmt.Sprintf(
-		"LineNumberEntry(Index: %d, LineNum: %d)",
-		ln.Index, ln.LineNum)
+		"LineNumberEntry(Index: %d, LineNum: %d, Col: %d)",
+		ln.Index, ln.LineNum, ln.Col)
 }
 
 // LineNumber returns the line number for the opcode at the given instruction pointer
 func (t *FuncTemplate) LineNumber(instPtr int) int {
+	return t.lineNumberEntry(instPtr).LineNum
+}
+
+// Position returns the line and column for the opcode at the given instruction pointer
+func (t *FuncTemplate) Position(instPtr int) (int, int) {
+	ln := t.lineNumberEntry(instPtr)
+	return ln.LineNum, ln.Col
+}
+
+func (t *FuncTemplate) lineNumberEntry(instPtr int) LineNumberEntry {
 
 	table := t.LineNumberTable
 	n := len(table) - 1
 
 	for i := 0; i < n; i++ {
 		if (instPtr >= table[i].Index) && (instPtr < table[i+1].Index) {
-			return table[i].LineNum
+			return table[i]
 		}
 	}
-	return table[n].LineNum
+	return table[n]
 }
 
 // ErrorHandler handles errors that are thrown for a given block of opcodes,
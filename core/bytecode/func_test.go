@@ -78,11 +78,12 @@ func TestLineNumber(t *testing.T) {
 		NumLocals:   0,
 		Bytecodes:   nil,
 		LineNumberTable: []LineNumberEntry{
-			{0, 0},
-			{1, 2},
-			{11, 3},
-			{20, 4},
-			{29, 0}},
+			{0, 0, 0},
+			{1, 2, 5},
+			{11, 3, 1},
+			{15, 3, 7},
+			{20, 4, 3},
+			{29, 0, 0}},
 		ErrorHandlers: nil,
 	}
 
@@ -94,4 +95,11 @@ func TestLineNumber(t *testing.T) {
 	tassert(t, tp.LineNumber(20) == 4)
 	tassert(t, tp.LineNumber(28) == 4)
 	tassert(t, tp.LineNumber(29) == 0)
+
+	line, col := tp.Position(14)
+	tassert(t, line == 3 && col == 1)
+	line, col = tp.Position(15)
+	tassert(t, line == 3 && col == 7)
+	line, col = tp.Position(19)
+	tassert(t, line == 3 && col == 7)
 }
@@ -31,6 +31,19 @@ type Module struct {
 	// Refs is a list of Refs for the values contained in the Contents.
 	// The Refs are populated when the Interpreter instantiates the module.
 	Refs []*Ref
+
+	// Exports is a list of the module-level variables that are
+	// made available as fields of the Contents.
+	Exports []*Export
+}
+
+// Export is a module-level variable that is made available to other modules.
+type Export struct {
+	Name    string
+	IsConst bool
+
+	// Index is the index of the Export's Ref in the Module's Refs.
+	Index int
 }
 
 func NewModule(name, path string) *Module {
@@ -67,3 +80,45 @@ func (m *Module) Contents() g.Struct {
 func (m *Module) SetContents(contents g.Struct) {
 	m.contents = contents
 }
+
+// InitContents populates the Contents with a property for each of the Exports.
+func (m *Module) InitContents() {
+
+	fields := make(map[string]g.Field)
+	for _, e := range m.Exports {
+		fields[e.Name] = m.makeProperty(e.Index, e.IsConst)
+	}
+
+	stc, err := g.NewStruct(fields)
+	g.Assert(err == nil)
+	m.contents = stc
+}
+
+func (m *Module) makeProperty(index int, isConst bool) g.Field {
+
+	get := g.NewFixedNativeFunc(
+		[]g.Type{}, false,
+		func(ev g.Eval, values []g.Value) (g.Value, g.Error) {
+			return m.Refs[index].Val, nil
+		})
+
+	if isConst {
+		prop, err := g.NewReadonlyProperty(get)
+		if err != nil {
+			panic("unreachable")
+		}
+		return prop
+	}
+
+	set := g.NewFixedNativeFunc(
+		[]g.Type{g.AnyType}, false,
+		func(ev g.Eval, values []g.Value) (g.Value, g.Error) {
+			m.Refs[index].Val = values[0]
+			return g.Null, nil
+		})
+	prop, err := g.NewProperty(get, set)
+	if err != nil {
+		panic("unreachable")
+	}
+	return prop
+}
@@ -12,12 +12,14 @@ import (
 )
 
 // Pool is a pool of the constants, function templates, and struct definitions
-// used by a given Module.  Pools are created at compile time, and
-// are immutable at run time.
+// used by a given Module.  Pools are created at compile time, and are
+// immutable at run time, apart from the inline field caches of their templates.
 type Pool struct {
 	Constants  []g.Basic
 	StructDefs [][]string
 	Templates  []*FuncTemplate
+
+	shapes []*g.Shape
 }
 
 func (p *Pool) String() string {
@@ -46,6 +48,7 @@ func (p *Pool) DebugString(printLine func(int) string) string {
 	buf.WriteString("Templates:\n")
 	for i, t := range p.Templates {
 		buf.WriteString(fmt.Sprintf("    %d: Template\n", i))
+		buf.WriteString(fmt.Sprintf("        Name: %s\n", t.Name))
 		buf.WriteString(fmt.Sprintf("        Arity: %s\n", t.Arity))
 		buf.WriteString(fmt.Sprintf("        OptionalParams: %v\n", t.OptionalParams))
 		buf.WriteString(fmt.Sprintf("        NumCaptures: %d\n", t.NumCaptures))
@@ -0,0 +1,409 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package bytecode
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	g "github.com/mjarmy/golem-lang/core"
+)
+
+//---------------------------------------------------------------
+// Compiled module files
+//
+// A compiled module file (.glmc) starts with the magic bytes "GLMC",
+// followed by the format version.  The rest of the file is a sequence of
+// varint-encoded integers, length-prefixed strings and length-prefixed
+// lists, in the order in which they are written by WriteModule.
+//---------------------------------------------------------------
+
+const fileMagic = "GLMC"
+
+// FileVersion is the version of the compiled module file format.  It must be
+// changed whenever the format changes, or whenever the meaning of the bytecode
+// changes, so that out-of-date files are rejected rather than misinterpreted.
+const FileVersion = 7
+
+// WriteModule writes a compiled Module.  The Module must have been compiled with
+// the given builtins, since its bytecode refers to the builtins by index.
+func WriteModule(w io.Writer, mod *Module, builtins []*g.Builtin) error {
+
+	bw := bufio.NewWriter(w)
+	e := &encoder{w: bw}
+
+	e.bytes([]byte(fileMagic))
+	e.uint(FileVersion)
+
+	e.string(mod.name)
+	e.string(mod.Path)
+
+	e.uint(uint64(len(builtins)))
+	for _, b := range builtins {
+		e.string(b.Name)
+	}
+
+	e.uint(uint64(len(mod.Exports)))
+	for _, x := range mod.Exports {
+		e.string(x.Name)
+		e.bool(x.IsConst)
+		e.int(x.Index)
+	}
+
+	pool := mod.Pool
+
+	e.uint(uint64(len(pool.Constants)))
+	for _, c := range pool.Constants {
+		e.basic(c)
+	}
+
+	e.uint(uint64(len(pool.StructDefs)))
+	for _, def := range pool.StructDefs {
+		e.strings(def)
+	}
+
+	e.uint(uint64(len(pool.Templates)))
+	for _, t := range pool.Templates {
+		e.template(t)
+	}
+
+	if e.err != nil {
+		return e.err
+	}
+	return bw.Flush()
+}
+
+// ReadModule reads a compiled Module that was written by WriteModule.  An error
+// is returned if the file was written by a different version of Golem, or
+// with a different list of builtins.
+func ReadModule(r io.Reader, builtins []*g.Builtin) (*Module, error) {
+
+	d := &decoder{r: bufio.NewReader(r)}
+
+	magic := make([]byte, len(fileMagic))
+	d.bytes(magic)
+	if d.err != nil || string(magic) != fileMagic {
+		return nil, fmt.Errorf("Not a compiled Golem module")
+	}
+	if v := d.uint(); d.err == nil && v != FileVersion {
+		return nil, fmt.Errorf(
+			"Compiled module has version %d, expected version %d", v, FileVersion)
+	}
+
+	mod := NewModule(d.string(), d.string())
+
+	n := d.len()
+	mismatch := n != len(builtins)
+	for i := 0; i < n; i++ {
+		name := d.string()
+		if i < len(builtins) && builtins[i].Name != name {
+			mismatch = true
+		}
+	}
+	if d.err == nil && mismatch {
+		return nil, fmt.Errorf(
+			"Compiled module '%s' was built with different builtins", mod.name)
+	}
+
+	mod.Exports = make([]*Export, d.len())
+	for i := range mod.Exports {
+		mod.Exports[i] = &Export{
+			Name:    d.string(),
+			IsConst: d.bool(),
+			Index:   d.int(),
+		}
+	}
+
+	pool := &Pool{}
+
+	pool.Constants = make([]g.Basic, d.len())
+	for i := range pool.Constants {
+		pool.Constants[i] = d.basic()
+	}
+
+	pool.StructDefs = make([][]string, d.len())
+	for i := range pool.StructDefs {
+		pool.StructDefs[i] = d.strings()
+	}
+
+	pool.Templates = make([]*FuncTemplate, d.len())
+	for i := range pool.Templates {
+		pool.Templates[i] = d.template(mod)
+	}
+
+	if d.err != nil {
+		if d.err == io.EOF {
+			d.err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("Invalid compiled module: %s", d.err.Error())
+	}
+
+	mod.Pool = pool
+	mod.Pool.InitCaches()
+	mod.InitContents()
+	return mod, nil
+}
+
+//---------------------------------------------------------------
+// encoder
+
+type encoder struct {
+	w   *bufio.Writer
+	err error
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (e *encoder) bytes(b []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(b)
+	}
+}
+
+func (e *encoder) uint(n uint64) {
+	e.bytes(e.buf[:binary.PutUvarint(e.buf[:], n)])
+}
+
+func (e *encoder) int64(n int64) {
+	e.bytes(e.buf[:binary.PutVarint(e.buf[:], n)])
+}
+
+func (e *encoder) int(n int) {
+	e.int64(int64(n))
+}
+
+func (e *encoder) bool(b bool) {
+	if b {
+		e.uint(1)
+	} else {
+		e.uint(0)
+	}
+}
+
+func (e *encoder) string(s string) {
+	e.uint(uint64(len(s)))
+	e.bytes([]byte(s))
+}
+
+func (e *encoder) strings(list []string) {
+	e.uint(uint64(len(list)))
+	for _, s := range list {
+		e.string(s)
+	}
+}
+
+func (e *encoder) basic(b g.Basic) {
+
+	e.uint(uint64(b.Type()))
+
+	switch b.Type() {
+	case g.NullType:
+	case g.BoolType:
+		e.bool(b.(g.Bool).BoolVal())
+	case g.IntType:
+		e.int64(b.(g.Int).ToInt())
+	case g.FloatType:
+		e.uint(math.Float64bits(b.(g.Float).ToFloat()))
+	case g.StrType:
+		e.string(b.(g.Str).String())
+	default:
+		panic("unreachable")
+	}
+}
+
+func (e *encoder) template(t *FuncTemplate) {
+
+	e.string(t.Name)
+
+	e.uint(uint64(t.Arity.Kind))
+	e.uint(uint64(t.Arity.Required))
+	e.uint(uint64(t.Arity.Optional))
+	e.uint(uint64(len(t.OptionalParams)))
+	for _, v := range t.OptionalParams {
+		e.basic(v.(g.Basic))
+	}
+
+	e.int(t.NumCaptures)
+	e.int(t.NumLocals)
+
+	e.uint(uint64(len(t.Bytecodes)))
+	e.bytes(t.Bytecodes)
+
+	e.uint(uint64(len(t.LineNumberTable)))
+	for _, ln := range t.LineNumberTable {
+		e.int(ln.Index)
+		e.int(ln.LineNum)
+		e.int(ln.Col)
+	}
+
+	e.uint(uint64(len(t.ErrorHandlers)))
+	for _, eh := range t.ErrorHandlers {
+		e.int(eh.Catch.Begin)
+		e.int(eh.Catch.End)
+		e.int(eh.Finally.Begin)
+		e.int(eh.Finally.End)
+	}
+
+	e.bool(t.IsGenerator)
+	e.strings(t.LocalNames)
+	e.strings(t.CaptureNames)
+
+	e.uint(uint64(len(t.FieldKeys)))
+	for _, k := range t.FieldKeys {
+		e.int(k)
+	}
+}
+
+//---------------------------------------------------------------
+// decoder
+
+// maxLen is a sanity check on the length of a list or string,
+// so that a corrupt file does not cause a huge allocation.
+const maxLen = 1 << 28
+
+type decoder struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (d *decoder) bytes(b []byte) {
+	if d.err == nil {
+		_, d.err = io.ReadFull(d.r, b)
+	}
+}
+
+func (d *decoder) uint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	n, err := binary.ReadUvarint(d.r)
+	d.err = err
+	return n
+}
+
+func (d *decoder) int64() int64 {
+	if d.err != nil {
+		return 0
+	}
+	n, err := binary.ReadVarint(d.r)
+	d.err = err
+	return n
+}
+
+func (d *decoder) int() int {
+	return int(d.int64())
+}
+
+func (d *decoder) len() int {
+	n := d.uint()
+	if n > maxLen {
+		d.fail("length %d is too large", n)
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) bool() bool {
+	return d.uint() != 0
+}
+
+func (d *decoder) string() string {
+	b := make([]byte, d.len())
+	d.bytes(b)
+	return string(b)
+}
+
+func (d *decoder) strings() []string {
+	list := make([]string, d.len())
+	for i := range list {
+		list[i] = d.string()
+	}
+	return list
+}
+
+func (d *decoder) fail(format string, args ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (d *decoder) basic() g.Basic {
+
+	switch t := g.Type(d.uint()); t {
+	case g.NullType:
+		return g.Null
+	case g.BoolType:
+		return g.NewBool(d.bool())
+	case g.IntType:
+		return g.NewInt(d.int64())
+	case g.FloatType:
+		return g.NewFloat(math.Float64frombits(d.uint()))
+	case g.StrType:
+		s, err := g.NewStr(d.string())
+		if err != nil {
+			d.fail("%s", err.Error())
+			return g.Null
+		}
+		return s
+	default:
+		d.fail("unknown constant type %d", t)
+		return g.Null
+	}
+}
+
+func (d *decoder) template(mod *Module) *FuncTemplate {
+
+	t := &FuncTemplate{Module: mod}
+
+	t.Name = d.string()
+
+	t.Arity = g.Arity{
+		Kind:     g.ArityKind(d.uint()),
+		Required: uint16(d.uint()),
+		Optional: uint16(d.uint()),
+	}
+	if n := d.len(); n > 0 {
+		t.OptionalParams = make([]g.Value, n)
+		for i := range t.OptionalParams {
+			t.OptionalParams[i] = d.basic()
+		}
+	}
+
+	t.NumCaptures = d.int()
+	t.NumLocals = d.int()
+
+	t.Bytecodes = make([]byte, d.len())
+	d.bytes(t.Bytecodes)
+
+	t.LineNumberTable = make([]LineNumberEntry, d.len())
+	for i := range t.LineNumberTable {
+		t.LineNumberTable[i] = LineNumberEntry{
+			Index:   d.int(),
+			LineNum: d.int(),
+			Col:     d.int(),
+		}
+	}
+
+	t.ErrorHandlers = make([]ErrorHandler, d.len())
+	for i := range t.ErrorHandlers {
+		t.ErrorHandlers[i] = ErrorHandler{
+			Catch:   TryClause{Begin: d.int(), End: d.int()},
+			Finally: TryClause{Begin: d.int(), End: d.int()},
+		}
+	}
+
+	t.IsGenerator = d.bool()
+	t.LocalNames = d.strings()
+	t.CaptureNames = d.strings()
+
+	t.FieldKeys = make([]int, d.len())
+	for i := range t.FieldKeys {
+		t.FieldKeys[i] = d.int()
+	}
+
+	return t
+}
@@ -0,0 +1,119 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package bytecode
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	g "github.com/mjarmy/golem-lang/core"
+)
+
+var testBuiltins = []*g.Builtin{
+	{"assert", g.BuiltinAssert},
+	{"println", g.BuiltinPrintln},
+}
+
+func testModule() *Module {
+
+	mod := NewModule("foo", "/tmp/foo.glm")
+	mod.Exports = []*Export{
+		{Name: "a", IsConst: false, Index: 0},
+		{Name: "b", IsConst: true, Index: 1},
+	}
+	mod.Pool = &Pool{
+		Constants: []g.Basic{
+			g.Null, g.True, g.NewInt(-42), g.NewFloat(1.5), g.MustStr("abc"),
+		},
+		StructDefs: [][]string{{"x", "y"}, {}},
+		Templates: []*FuncTemplate{
+			{
+				Module:          mod,
+				Arity:           g.Arity{Kind: g.FixedArity, Required: 0, Optional: 0},
+				NumLocals:       2,
+				Bytecodes:       []byte{LoadNull, LoadConst, 0, 2, Return},
+				LineNumberTable: []LineNumberEntry{{0, 0, 0}, {1, 1, 9}, {4, 2, 1}},
+				ErrorHandlers:   []ErrorHandler{},
+				LocalNames:      []string{"a", "b"},
+				CaptureNames:    []string{},
+				FieldKeys:       []int{},
+			},
+			{
+				Module:          mod,
+				Name:            "f",
+				Arity:           g.Arity{Kind: g.MultipleArity, Required: 1, Optional: 1},
+				OptionalParams:  []g.Value{g.NewInt(7)},
+				NumCaptures:     1,
+				NumLocals:       3,
+				Bytecodes:       []byte{LoadNull, Return},
+				LineNumberTable: []LineNumberEntry{{0, 0, 0}, {1, 3, 5}},
+				ErrorHandlers: []ErrorHandler{
+					{TryClause{1, 2}, TryClause{-1, -1}},
+				},
+				IsGenerator:  true,
+				LocalNames:   []string{"x", "y", ""},
+				CaptureNames: []string{"a"},
+				FieldKeys:    []int{4},
+			},
+		},
+	}
+	mod.Pool.InitCaches()
+	mod.InitContents()
+	return mod
+}
+
+func TestSerialize(t *testing.T) {
+
+	mod := testModule()
+
+	var buf bytes.Buffer
+	err := WriteModule(&buf, mod, testBuiltins)
+	tassert(t, err == nil)
+
+	result, err := ReadModule(bytes.NewReader(buf.Bytes()), testBuiltins)
+	tassert(t, err == nil)
+
+	tassert(t, result.Name() == "foo")
+	tassert(t, result.Path == mod.Path)
+	tassert(t, reflect.DeepEqual(result.Exports, mod.Exports))
+	tassert(t, reflect.DeepEqual(result.Pool.Constants, mod.Pool.Constants))
+	tassert(t, reflect.DeepEqual(result.Pool.StructDefs, mod.Pool.StructDefs))
+
+	tassert(t, len(result.Pool.Templates) == len(mod.Pool.Templates))
+	for i, tpl := range result.Pool.Templates {
+		tassert(t, tpl.Module == result)
+
+		// compare everything except the Module
+		a, b := *tpl, *mod.Pool.Templates[i]
+		a.Module, b.Module = nil, nil
+		tassert(t, reflect.DeepEqual(a, b))
+	}
+
+	names, gerr := result.Contents().FieldNames()
+	okNames(t, names, gerr, []string{"a", "b"})
+}
+
+func TestSerializeErrors(t *testing.T) {
+
+	var buf bytes.Buffer
+	err := WriteModule(&buf, testModule(), testBuiltins)
+	tassert(t, err == nil)
+	data := buf.Bytes()
+
+	_, err = ReadModule(bytes.NewReader([]byte("let a = 1")), testBuiltins)
+	tassert(t, err.Error() == "Not a compiled Golem module")
+
+	_, err = ReadModule(bytes.NewReader(data), testBuiltins[:1])
+	tassert(t, err.Error() == "Compiled module 'foo' was built with different builtins")
+
+	_, err = ReadModule(bytes.NewReader(data[:len(data)-3]), testBuiltins)
+	tassert(t, err.Error() == "Invalid compiled module: unexpected EOF")
+
+	old := append([]byte{}, data...)
+	old[len(fileMagic)] = FileVersion + 1
+	_, err = ReadModule(bytes.NewReader(old), testBuiltins)
+	tassert(t, err.Error() == "Compiled module has version 8, expected version 7")
+}
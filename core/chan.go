@@ -6,6 +6,7 @@ package core
 
 import (
 	"fmt"
+	"reflect"
 )
 
 /*doc
@@ -72,6 +73,10 @@ func (ch *channel) Recv() Value {
 	return <-ch.ch
 }
 
+func (ch *channel) ToChan() chan Value {
+	return ch.ch
+}
+
 //--------------------------------------------------------------
 // fields
 
@@ -97,7 +102,14 @@ var chanMethods = map[string]Method{
 		[]Type{AnyType}, true,
 		func(self interface{}, ev Eval, params []Value) (Value, Error) {
 			ch := self.(Chan)
-			ch.Send(params[0])
+			_, _, _, err := Select(ev, []reflect.SelectCase{{
+				Dir:  reflect.SelectSend,
+				Chan: reflect.ValueOf(ch.ToChan()),
+				Send: reflect.ValueOf(params[0]),
+			}})
+			if err != nil {
+				return nil, err
+			}
 			return Null, nil
 		}),
 
@@ -112,8 +124,17 @@ var chanMethods = map[string]Method{
 	"recv": NewNullaryMethod(
 		func(self interface{}, ev Eval) (Value, Error) {
 			ch := self.(Chan)
-			val := ch.Recv()
-			return val, nil
+			_, recv, recvOK, err := Select(ev, []reflect.SelectCase{{
+				Dir:  reflect.SelectRecv,
+				Chan: reflect.ValueOf(ch.ToChan()),
+			}})
+			if err != nil {
+				return nil, err
+			}
+			if !recvOK {
+				return Null, nil
+			}
+			return recv.Interface().(Value), nil
 		}),
 }
 
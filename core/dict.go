@@ -5,7 +5,6 @@
 package core
 
 import (
-	"bytes"
 	"fmt"
 )
 
@@ -53,7 +52,7 @@ func (d *dict) Frozen(ev Eval) (Bool, Error) {
 
 func (d *dict) ToStr(ev Eval) (Str, Error) {
 
-	var buf bytes.Buffer
+	buf := NewStrBuilder(ev)
 	buf.WriteString("dict {")
 	idx := 0
 	itr := d.hashMap.Iterator()
@@ -81,7 +80,7 @@ func (d *dict) ToStr(ev Eval) (Str, Error) {
 	}
 
 	buf.WriteString(" }")
-	return NewStr(buf.String())
+	return buf.ToStr()
 }
 
 func (d *dict) HashCode(ev Eval) (Int, Error) {
@@ -329,7 +328,7 @@ var dictMethods = map[string]Method{
 		func(self interface{}, ev Eval, params []Value) (Value, Error) {
 			d := self.(Dict)
 
-			ibl, ok := params[0].(Iterable)
+			ibl, ok := AsIterable(params[0])
 			if !ok {
 				return nil, IterableMismatch(params[0].Type())
 			}
@@ -481,6 +480,13 @@ var dictMethods = map[string]Method{
 	"values": NewNullaryMethod(
 		func(self interface{}, ev Eval) (Value, Error) {
 			d := self.(Dict)
+			n, err := d.Len(ev)
+			if err != nil {
+				return nil, err
+			}
+			if err := AllocateValues(ev, int(n.ToInt())); err != nil {
+				return nil, err
+			}
 			return d.Values(), nil
 		}),
 }
@@ -6,73 +6,195 @@ package core
 
 import (
 	"fmt"
+	"strings"
+	"unicode"
 )
 
 // Error is an error
 type Error error
 
+// TypedError is an Error that has a kind, such as "TypeMismatch", and an
+// optional message that provides further detail about what went wrong.
+type TypedError interface {
+	Error
+	Kind() string
+	Message() string
+}
+
+type typedError struct {
+	kind string
+	msg  string
+}
+
+// NewError creates a TypedError
+func NewError(kind string, msg string) TypedError {
+	return &typedError{kind, msg}
+}
+
+func (e *typedError) Kind() string    { return e.kind }
+func (e *typedError) Message() string { return e.msg }
+
+func (e *typedError) Error() string {
+	if e.msg == "" {
+		return e.kind
+	}
+	return e.kind + ": " + e.msg
+}
+
+// ErrorKind returns the kind of an Error.  If the Error is not a TypedError,
+// the kind is parsed from the text of the Error, which by convention has the
+// form "Kind: message".  If the text does not follow the convention,
+// the kind is "Error".
+func ErrorKind(err Error) string {
+	if te, ok := err.(TypedError); ok {
+		return te.Kind()
+	}
+	kind, _ := parseError(err.Error())
+	return kind
+}
+
+// ErrorMessage returns the message of an Error.  If the Error is not a
+// TypedError, the message is parsed from the text of the Error.
+func ErrorMessage(err Error) string {
+	if te, ok := err.(TypedError); ok {
+		return te.Message()
+	}
+	_, msg := parseError(err.Error())
+	return msg
+}
+
+func parseError(text string) (string, string) {
+
+	kind, msg := text, ""
+	if idx := strings.Index(text, ": "); idx != -1 {
+		kind, msg = text[:idx], text[idx+2:]
+	}
+
+	if isErrorKind(kind) {
+		return kind, msg
+	}
+	return "Error", text
+}
+
+// An error kind is a capitalized identifier, e.g. "NoSuchField".  Since
+// it is capitalized, it can never be a keyword.
+func isErrorKind(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if i == 0 {
+			if !unicode.IsUpper(r) {
+				return false
+			}
+		} else if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
 //-----------------------------------------------------------
 // miscellaneous
 //-----------------------------------------------------------
 
 // NullValueError creates an Error
 func NullValueError() Error {
-	return fmt.Errorf("NullValue")
+	return NewError("NullValue", "")
 }
 
 // AssertionFailed creates an Error
 func AssertionFailed() Error {
-	return fmt.Errorf("AssertionFailed")
+	return NewError("AssertionFailed", "")
 }
 
 // InvalidUtf8String creates an Error
 func InvalidUtf8String() Error {
-	return fmt.Errorf("InvalidUtf8String")
+	return NewError("InvalidUtf8String", "")
 }
 
 // NoSuchElement creates an Error
 func NoSuchElement() Error {
-	return fmt.Errorf("NoSuchElement")
+	return NewError("NoSuchElement", "")
 }
 
 // ImmutableValue creates an Error
 func ImmutableValue() Error {
-	return fmt.Errorf("ImmutableValue")
+	return NewError("ImmutableValue", "")
+}
+
+// GeneratorRunning creates an Error
+func GeneratorRunning() Error {
+	return NewError("GeneratorRunning", "")
 }
 
 // DivideByZero creates an Error
 func DivideByZero() Error {
-	return fmt.Errorf("DivideByZero")
+	return NewError("DivideByZero", "")
 }
 
 // InvalidArgument creates an Error
 func InvalidArgument(msg string) Error {
-	return fmt.Errorf("InvalidArgument: %s", msg)
+	return NewError("InvalidArgument", msg)
 }
 
 // IndexOutOfBounds creates an Error
 func IndexOutOfBounds(val int) Error {
-	return fmt.Errorf("IndexOutOfBounds: %d", val)
+	return NewError("IndexOutOfBounds", fmt.Sprintf("%d", val))
 }
 
 // ReadonlyField creates an Error
 func ReadonlyField(name string) Error {
-	return fmt.Errorf("ReadonlyField: Field '%s' is readonly", name)
+	return NewError("ReadonlyField", fmt.Sprintf("Field '%s' is readonly", name))
 }
 
 // NoSuchField creates an Error
 func NoSuchField(name string) Error {
-	return fmt.Errorf("NoSuchField: Field '%s' not found", name)
+	return NewError("NoSuchField", fmt.Sprintf("Field '%s' not found", name))
 }
 
 // InvalidStructKey creates an Error
 func InvalidStructKey(key string) Error {
-	return fmt.Errorf("InvalidStructKey: '%s' is not a valid struct key", key)
+	return NewError("InvalidStructKey", fmt.Sprintf("'%s' is not a valid struct key", key))
 }
 
 // UndefinedModule creates an Error
 func UndefinedModule(name string) Error {
-	return fmt.Errorf("UndefinedModule: Module '%s' is not defined", name)
+	return NewError("UndefinedModule", fmt.Sprintf("Module '%s' is not defined", name))
+}
+
+//--------------------------------------------------------------
+// execution limits
+//--------------------------------------------------------------
+
+// OpcodeLimitExceeded creates an Error
+func OpcodeLimitExceeded(limit int64) Error {
+	return NewError("OpcodeLimitExceeded", fmt.Sprintf("Exceeded the limit of %d opcodes", limit))
+}
+
+// StackOverflow creates an Error
+func StackOverflow(depth int) Error {
+	return NewError("StackOverflow", fmt.Sprintf("Exceeded the maximum frame depth of %d", depth))
+}
+
+// ResourceExhausted creates an Error
+func ResourceExhausted(limit int64) Error {
+	return NewError("ResourceExhausted", fmt.Sprintf("Exceeded the allocation limit of %d bytes", limit))
+}
+
+// DeadlineExceeded creates an Error
+func DeadlineExceeded() Error {
+	return NewError("DeadlineExceeded", "")
+}
+
+// Canceled creates an Error
+func Canceled() Error {
+	return NewError("Canceled", "")
+}
+
+// Interrupted creates an Error
+func Interrupted() Error {
+	return NewError("Interrupted", "")
 }
 
 //--------------------------------------------------------------
@@ -81,42 +203,42 @@ func UndefinedModule(name string) Error {
 
 // TypeMismatch creates an Error
 func TypeMismatch(typ, wrong Type) Error {
-	return fmt.Errorf("TypeMismatch: Expected %s, not %s", typ, wrong)
+	return NewError("TypeMismatch", fmt.Sprintf("Expected %s, not %s", typ, wrong))
 }
 
 // NumberMismatch creates an Error
 func NumberMismatch(wrong Type) Error {
-	return fmt.Errorf("TypeMismatch: Expected Int or Float, not %s", wrong)
+	return NewError("TypeMismatch", fmt.Sprintf("Expected Int or Float, not %s", wrong))
 }
 
 // IterableMismatch creates an Error
 func IterableMismatch(wrong Type) Error {
-	return fmt.Errorf("TypeMismatch: Type %s has no iter()", wrong)
+	return NewError("TypeMismatch", fmt.Sprintf("Type %s has no iter()", wrong))
 }
 
 // LenableMismatch creates an Error
 func LenableMismatch(wrong Type) Error {
-	return fmt.Errorf("TypeMismatch: Type %s has no len()", wrong)
+	return NewError("TypeMismatch", fmt.Sprintf("Type %s has no len()", wrong))
 }
 
 // IndexableMismatch creates an Error
 func IndexableMismatch(wrong Type) Error {
-	return fmt.Errorf("TypeMismatch: Type %s cannot be indexed", wrong)
+	return NewError("TypeMismatch", fmt.Sprintf("Type %s cannot be indexed", wrong))
 }
 
 // SliceableMismatch creates an Error
 func SliceableMismatch(wrong Type) Error {
-	return fmt.Errorf("TypeMismatch: Type %s cannot be sliced", wrong)
+	return NewError("TypeMismatch", fmt.Sprintf("Type %s cannot be sliced", wrong))
 }
 
 // ComparableMismatch creates an Error
 func ComparableMismatch(a, b Type) Error {
-	return fmt.Errorf("TypeMismatch: Types %s and %s cannot be compared", a, b)
+	return NewError("TypeMismatch", fmt.Sprintf("Types %s and %s cannot be compared", a, b))
 }
 
 // HashCodeMismatch creates an Error
 func HashCodeMismatch(wrong Type) Error {
-	return fmt.Errorf("TypeMismatch: Type %s cannot be hashed", wrong)
+	return NewError("TypeMismatch", fmt.Sprintf("Type %s cannot be hashed", wrong))
 }
 
 //--------------------------------------------------------------
@@ -127,31 +249,31 @@ func HashCodeMismatch(wrong Type) Error {
 func ArityMismatch(expected int, actual int) Error {
 
 	if expected == 1 {
-		return fmt.Errorf(
-			"ArityMismatch: Expected 1 parameter, got %d", actual)
+		return NewError(
+			"ArityMismatch", fmt.Sprintf("Expected 1 parameter, got %d", actual))
 	}
-	return fmt.Errorf(
-		"ArityMismatch: Expected %d parameters, got %d", expected, actual)
+	return NewError(
+		"ArityMismatch", fmt.Sprintf("Expected %d parameters, got %d", expected, actual))
 }
 
 // ArityMismatchAtLeast creates an Error
 func ArityMismatchAtLeast(expected int, actual int) Error {
 
 	if expected == 1 {
-		return fmt.Errorf(
-			"ArityMismatch: Expected at least 1 parameter, got %d", actual)
+		return NewError(
+			"ArityMismatch", fmt.Sprintf("Expected at least 1 parameter, got %d", actual))
 	}
-	return fmt.Errorf(
-		"ArityMismatch: Expected at least %d parameters, got %d", expected, actual)
+	return NewError(
+		"ArityMismatch", fmt.Sprintf("Expected at least %d parameters, got %d", expected, actual))
 }
 
 // ArityMismatchAtMost creates an Error
 func ArityMismatchAtMost(expected int, actual int) Error {
 
 	if expected == 1 {
-		return fmt.Errorf(
-			"ArityMismatch: Expected at most 1 parameter, got %d", actual)
+		return NewError(
+			"ArityMismatch", fmt.Sprintf("Expected at most 1 parameter, got %d", actual))
 	}
-	return fmt.Errorf(
-		"ArityMismatch: Expected at most %d parameters, got %d", expected, actual)
+	return NewError(
+		"ArityMismatch", fmt.Sprintf("Expected at most %d parameters, got %d", expected, actual))
 }
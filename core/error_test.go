@@ -0,0 +1,47 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package core
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestErrorKind(t *testing.T) {
+
+	err := NoSuchField("a")
+	tassert(t, err.Error() == "NoSuchField: Field 'a' not found")
+	tassert(t, ErrorKind(err) == "NoSuchField")
+	tassert(t, ErrorMessage(err) == "Field 'a' not found")
+
+	err = DivideByZero()
+	tassert(t, err.Error() == "DivideByZero")
+	tassert(t, ErrorKind(err) == "DivideByZero")
+	tassert(t, ErrorMessage(err) == "")
+
+	err = fmt.Errorf("FooError: abc: def")
+	tassert(t, ErrorKind(err) == "FooError")
+	tassert(t, ErrorMessage(err) == "abc: def")
+
+	err = fmt.Errorf("FooError")
+	tassert(t, ErrorKind(err) == "FooError")
+	tassert(t, ErrorMessage(err) == "")
+
+	err = fmt.Errorf("stream has already been collected")
+	tassert(t, ErrorKind(err) == "Error")
+	tassert(t, ErrorMessage(err) == "stream has already been collected")
+
+	err = fmt.Errorf("foo: bar")
+	tassert(t, ErrorKind(err) == "Error")
+	tassert(t, ErrorMessage(err) == "foo: bar")
+
+	err = fmt.Errorf("Not Found: bar")
+	tassert(t, ErrorKind(err) == "Error")
+	tassert(t, ErrorMessage(err) == "Not Found: bar")
+
+	err = fmt.Errorf("Ünknown_2: bar")
+	tassert(t, ErrorKind(err) == "Ünknown_2")
+	tassert(t, ErrorMessage(err) == "bar")
+}
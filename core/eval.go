@@ -4,6 +4,11 @@
 
 package core
 
+import (
+	"bytes"
+	"reflect"
+)
+
 // Eval evaluates Funcs.  In practice, an Eval is actually always a full-fledged instance
 // of the Golem Interpreter.
 type Eval interface {
@@ -12,3 +17,83 @@ type Eval interface {
 	// If the function is a bytecode.Func, then Eval will evaluate the function's bytecode.
 	Eval(Func, []Value) (Value, Error)
 }
+
+// Meter is an optional interface that an Eval can implement, in order to keep
+// track of how much memory is being allocated as values are created or grown.
+type Meter interface {
+	// Allocate is called when approximately n bytes are about to be allocated.
+	// If an error is returned, the allocation does not happen.
+	Allocate(n int64) Error
+}
+
+// Selector is an optional interface that an Eval can implement, in order to
+// be able to abandon channel operations that are blocked.
+type Selector interface {
+	// Select is like reflect.Select, except that it returns an error,
+	// rather than continuing to block, if evaluation has been stopped.
+	Select(cases []reflect.SelectCase) (int, reflect.Value, bool, Error)
+}
+
+// Select performs a reflect.Select.  If the Eval is a Selector, the Eval
+// does the select instead.
+func Select(ev Eval, cases []reflect.SelectCase) (int, reflect.Value, bool, Error) {
+	if s, ok := ev.(Selector); ok {
+		return s.Select(cases)
+	}
+	chosen, recv, recvOK := reflect.Select(cases)
+	return chosen, recv, recvOK, nil
+}
+
+// approximate sizes, in bytes, of the things that are metered
+const (
+	valueSize  = 16 // an element of a List
+	hentrySize = 48 // an entry in a HashMap
+)
+
+// Allocate tells the Eval that approximately n bytes are about to be allocated,
+// if the Eval is a Meter.
+func Allocate(ev Eval, n int64) Error {
+	if m, ok := ev.(Meter); ok {
+		return m.Allocate(n)
+	}
+	return nil
+}
+
+// AllocateValues tells the Eval that n Values are about to be allocated,
+// if the Eval is a Meter.
+func AllocateValues(ev Eval, n int) Error {
+	return Allocate(ev, int64(n)*valueSize)
+}
+
+// A StrBuilder builds up a Str, telling the Eval about the memory that is
+// allocated as it grows.  Once an allocation fails, further writes are ignored,
+// and the error is returned by ToStr.
+type StrBuilder struct {
+	ev  Eval
+	buf bytes.Buffer
+	err Error
+}
+
+// NewStrBuilder creates a StrBuilder
+func NewStrBuilder(ev Eval) *StrBuilder {
+	return &StrBuilder{ev: ev}
+}
+
+// WriteString appends a string to the StrBuilder.
+func (sb *StrBuilder) WriteString(s string) {
+	if sb.err != nil {
+		return
+	}
+	if sb.err = Allocate(sb.ev, int64(len(s))); sb.err == nil {
+		sb.buf.WriteString(s)
+	}
+}
+
+// ToStr returns the Str that has been built, or the error that
+// occurred while building it.
+func (sb *StrBuilder) ToStr() (Str, Error) {
+	if sb.err != nil {
+		return nil, sb.err
+	}
+	return NewStr(sb.buf.String())
+}
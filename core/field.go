@@ -105,11 +105,11 @@ type property struct {
 func NewProperty(get Func, set Func) (Field, Error) {
 
 	if !reflect.DeepEqual(Arity{FixedArity, 0, 0}, get.Arity()) {
-		return nil, fmt.Errorf("InvalidGetterArity: %s", get.Arity().String())
+		return nil, NewError("InvalidGetterArity", get.Arity().String())
 	}
 
 	if !reflect.DeepEqual(Arity{FixedArity, 1, 0}, set.Arity()) {
-		return nil, fmt.Errorf("InvalidSetterArity: %s", set.Arity().String())
+		return nil, NewError("InvalidSetterArity", set.Arity().String())
 	}
 
 	return &property{get, set}, nil
@@ -154,7 +154,7 @@ type readonlyProperty struct {
 func NewReadonlyProperty(get Func) (Field, Error) {
 
 	if !reflect.DeepEqual(Arity{FixedArity, 0, 0}, get.Arity()) {
-		return nil, fmt.Errorf("InvalidGetterArity: %s", get.Arity().String())
+		return nil, NewError("InvalidGetterArity", get.Arity().String())
 	}
 
 	return &readonlyProperty{get}, nil
@@ -5,8 +5,10 @@
 package core
 
 import (
-//"fmt"
-//"sync"
+	//"fmt"
+	//"sync"
+
+	"github.com/mjarmy/golem-lang/scanner"
 )
 
 type (
@@ -32,6 +34,10 @@ func mergeFieldMaps(fieldMaps []fieldMap) fieldMap {
 			for k, v := range t.fields {
 				fields[k] = v
 			}
+		case *shapedFieldMap:
+			for i, k := range t.shape.names {
+				fields[k] = t.fields[i]
+			}
 		case *methodFieldMap:
 			for k, v := range t.methods {
 				fn := v.ToFunc(t.self, k)
@@ -114,6 +120,93 @@ func (fm *hashFieldMap) replace(name string, field Field) {
 	fm.fields[name] = field
 }
 
+//--------------------------------------------------------------
+// shapedFieldMap
+//--------------------------------------------------------------
+
+// A Shape describes the layout of the fields of the Structs that are
+// created from a given struct definition.  Structs that share a Shape store
+// each of their fields at the same index, so once the index of a field
+// is known, it can be found again without looking up the field's name.
+type Shape struct {
+	names   []string
+	indexes map[string]int
+}
+
+// NewShape creates a new Shape for Structs that have the given field names.
+func NewShape(names []string) (*Shape, Error) {
+
+	indexes := make(map[string]int, len(names))
+	for i, name := range names {
+		if !scanner.IsFieldName(name) {
+			return nil, InvalidStructKey(name)
+		}
+		indexes[name] = i
+	}
+
+	return &Shape{names, indexes}, nil
+}
+
+// Names returns the field names of the Shape.
+func (s *Shape) Names() []string {
+	return s.names
+}
+
+type shapedFieldMap struct {
+	shape  *Shape
+	fields []Field
+}
+
+func (fm *shapedFieldMap) names() []string {
+
+	names := make([]string, len(fm.shape.names))
+	copy(names, fm.shape.names)
+	return names
+}
+
+func (fm *shapedFieldMap) has(name string) bool {
+
+	_, ok := fm.shape.indexes[name]
+	return ok
+}
+
+func (fm *shapedFieldMap) get(ev Eval, name string) (Value, Error) {
+
+	if i, ok := fm.shape.indexes[name]; ok {
+		return fm.fields[i].Get(ev)
+	}
+	return nil, NoSuchField(name)
+}
+
+func (fm *shapedFieldMap) invoke(ev Eval, name string, params []Value) (Value, Error) {
+
+	if i, ok := fm.shape.indexes[name]; ok {
+		return fm.fields[i].Invoke(ev, params)
+	}
+	return nil, NoSuchField(name)
+}
+
+func (fm *shapedFieldMap) set(ev Eval, name string, val Value) Error {
+	if i, ok := fm.shape.indexes[name]; ok {
+		f := fm.fields[i]
+		if f.IsReadonly() {
+			return ReadonlyField(name)
+		}
+		return f.Set(ev, val)
+	}
+	return NoSuchField(name)
+}
+
+func (fm *shapedFieldMap) replace(name string, field Field) {
+
+	i, ok := fm.shape.indexes[name]
+	if !ok {
+		panic("Internal Error")
+	}
+
+	fm.fields[i] = field
+}
+
 //--------------------------------------------------------------
 // methodFieldMap
 //--------------------------------------------------------------
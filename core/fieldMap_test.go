@@ -97,6 +97,112 @@ func TestMethodFieldMap(t *testing.T) {
 	fail(t, val, err, "NoSuchField: Field 'b' not found")
 }
 
+func TestShapedFieldMap(t *testing.T) {
+
+	shape, err := NewShape([]string{"a", "b"})
+	tassert(t, err == nil)
+
+	var fm fieldMap = &shapedFieldMap{
+		shape:  shape,
+		fields: []Field{NewField(Zero), NewReadonlyField(One)},
+	}
+
+	tassert(t, reflect.DeepEqual([]string{"a", "b"}, fm.names()))
+	tassert(t, fm.has("a"))
+	tassert(t, fm.has("b"))
+	tassert(t, !fm.has("c"))
+
+	val, err := fm.get(nil, "a")
+	ok(t, val, err, Zero)
+	val, err = fm.get(nil, "b")
+	ok(t, val, err, One)
+	val, err = fm.get(nil, "c")
+	fail(t, val, err, "NoSuchField: Field 'c' not found")
+
+	val, err = fm.invoke(nil, "a", []Value{})
+	fail(t, val, err, "TypeMismatch: Expected Func, not Int")
+	val, err = fm.invoke(nil, "c", []Value{})
+	fail(t, val, err, "NoSuchField: Field 'c' not found")
+
+	err = fm.set(nil, "a", One)
+	tassert(t, err == nil)
+	val, err = fm.get(nil, "a")
+	ok(t, val, err, One)
+	err = fm.set(nil, "b", One)
+	fail(t, nil, err, "ReadonlyField: Field 'b' is readonly")
+	err = fm.set(nil, "c", One)
+	fail(t, nil, err, "NoSuchField: Field 'c' not found")
+
+	fm.replace("a", NewField(MustStr("abc")))
+	val, err = fm.get(nil, "a")
+	ok(t, val, err, MustStr("abc"))
+
+	_, err = NewShape([]string{"a", "1"})
+	fail(t, nil, err, "InvalidStructKey: '1' is not a valid struct key")
+}
+
+func TestShapedField(t *testing.T) {
+
+	shape, err := NewShape([]string{"a", "b"})
+	tassert(t, err == nil)
+	other, err := NewShape([]string{"a", "b"})
+	tassert(t, err == nil)
+
+	stc := NewShapedStruct(shape)
+	err = stc.SetField(nil, "b", One)
+	tassert(t, err == nil)
+
+	s, i, has := FieldIndex(stc, "b")
+	tassert(t, has && s == shape && i == 1)
+	_, _, has = FieldIndex(stc, "c")
+	tassert(t, !has)
+	_, _, has = FieldIndex(Zero, "b")
+	tassert(t, !has)
+
+	f, has := ShapedField(stc, shape, 1)
+	tassert(t, has)
+	val, err := f.Get(nil)
+	ok(t, val, err, One)
+
+	_, has = ShapedField(stc, other, 1)
+	tassert(t, !has)
+
+	hashed, err := NewStruct(map[string]Field{"b": NewField(One)})
+	tassert(t, err == nil)
+	_, has = ShapedField(hashed, shape, 1)
+	tassert(t, !has)
+}
+
+func BenchmarkHashFieldMap(b *testing.B) {
+
+	stc, err := NewStruct(map[string]Field{
+		"alpha": NewField(Zero),
+		"beta":  NewField(One),
+		"gamma": NewField(NegOne),
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for i := 0; i < b.N; i++ {
+		stc.GetField(nil, "gamma")
+	}
+}
+
+func BenchmarkShapedField(b *testing.B) {
+
+	shape, err := NewShape([]string{"alpha", "beta", "gamma"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	stc := NewShapedStruct(shape)
+
+	for i := 0; i < b.N; i++ {
+		f, _ := ShapedField(stc, shape, 2)
+		f.Get(nil)
+	}
+}
+
 var counter int64
 
 func next() Int {
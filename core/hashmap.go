@@ -158,6 +158,9 @@ func (hm *HashMap) Put(ev Eval, key Value, value Value) (err Error) {
 	h := hm.lookupBucket(ev, key)
 	n := hm.indexOf(ev, hm.buckets[h], key)
 	if n == -1 {
+		if err := Allocate(ev, hentrySize); err != nil {
+			return err
+		}
 		if hm.tooFull() {
 			hm.rehash(ev)
 			h = hm.lookupBucket(ev, key)
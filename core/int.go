@@ -299,7 +299,7 @@ func (i _int) ToChar() (Str, Error) {
 	r := rune(i.ToInt())
 
 	if !utf8.ValidRune(r) {
-		return nil, fmt.Errorf("InvalidUtf8Rune: %d is not a valid rune", r)
+		return nil, NewError("InvalidUtf8Rune", fmt.Sprintf("%d is not a valid rune", r))
 	}
 
 	buf := make([]byte, utf8.RuneLen(r))
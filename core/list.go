@@ -5,10 +5,8 @@
 package core
 
 import (
-	"bytes"
 	"fmt"
 	"sort"
-	"strings"
 )
 
 /*doc
@@ -57,7 +55,7 @@ func (ls *list) Frozen(ev Eval) (Bool, Error) {
 
 func (ls *list) ToStr(ev Eval) (Str, Error) {
 
-	var buf bytes.Buffer
+	buf := NewStrBuilder(ev)
 	buf.WriteString("[")
 	for idx, v := range ls.values {
 		if idx > 0 {
@@ -74,7 +72,7 @@ func (ls *list) ToStr(ev Eval) (Str, Error) {
 	}
 	buf.WriteString(" ]")
 
-	return NewStr(buf.String())
+	return buf.ToStr()
 }
 
 func (ls *list) HashCode(ev Eval) (Int, Error) {
@@ -123,6 +121,9 @@ func (ls *list) Slice(ev Eval, from Value, to Value) (Value, Error) {
 		return nil, err
 	}
 
+	if err := AllocateValues(ev, t-f); err != nil {
+		return nil, err
+	}
 	result := NewList(CopyValues(ls.values[f:t]))
 	if ls.frozen {
 		result.(*list).frozen = true
@@ -178,17 +179,20 @@ func (ls *list) IsEmpty() Bool {
 
 func (ls *list) Join(ev Eval, delim Str) (Str, Error) {
 
-	result := make([]string, len(ls.values))
+	buf := NewStrBuilder(ev)
 	for i, v := range ls.values {
+		if i > 0 {
+			buf.WriteString(delim.String())
+		}
 
 		s, err := v.ToStr(ev)
 		if err != nil {
 			return nil, err
 		}
-		result[i] = s.String()
+		buf.WriteString(s.String())
 	}
 
-	return NewStr(strings.Join(result, delim.String()))
+	return buf.ToStr()
 }
 
 func (ls *list) Copy() List {
@@ -204,6 +208,9 @@ func (ls *list) ToTuple() (Tuple, Error) {
 
 func (ls *list) Map(ev Eval, mapper Mapper) (List, Error) {
 
+	if err := AllocateValues(ev, len(ls.values)); err != nil {
+		return nil, err
+	}
 	vals := make([]Value, len(ls.values))
 
 	var err Error
@@ -247,6 +254,9 @@ func (ls *list) Filter(ev Eval, pred Predicate) (List, Error) {
 			return nil, err
 		}
 		if eq.BoolVal() {
+			if err := AllocateValues(ev, 1); err != nil {
+				return nil, err
+			}
 			vals = append(vals, v)
 		}
 	}
@@ -259,6 +269,9 @@ func (ls *list) Add(ev Eval, val Value) (List, Error) {
 		return nil, ImmutableValue()
 	}
 
+	if err := AllocateValues(ev, 1); err != nil {
+		return nil, err
+	}
 	ls.values = append(ls.values, val)
 	return ls, nil
 }
@@ -282,6 +295,10 @@ func (ls *list) AddAll(ev Eval, ibl Iterable) (List, Error) {
 		if err != nil {
 			return nil, err
 		}
+
+		if err := AllocateValues(ev, 1); err != nil {
+			return nil, err
+		}
 		ls.values = append(ls.values, v)
 
 		b, err = itr.IterNext(ev)
@@ -328,12 +345,12 @@ var DefaultLesser = func(ev Eval, a Value, b Value) (Bool, Error) {
 
 	ca, ok := a.(Comparable)
 	if !ok {
-		return nil, Error(fmt.Errorf("TypeMismatch: Type %s cannot be sorted", a.Type()))
+		return nil, NewError("TypeMismatch", fmt.Sprintf("Type %s cannot be sorted", a.Type()))
 	}
 
 	cb, ok := b.(Comparable)
 	if !ok {
-		return nil, Error(fmt.Errorf("TypeMismatch: Type %s cannot be sorted", b.Type()))
+		return nil, NewError("TypeMismatch", fmt.Sprintf("Type %s cannot be sorted", b.Type()))
 	}
 
 	n, err := ca.Cmp(ev, cb)
@@ -469,7 +486,7 @@ var listMethods = map[string]Method{
 		func(self interface{}, ev Eval, params []Value) (Value, Error) {
 			ls := self.(List)
 
-			ibl, ok := params[0].(Iterable)
+			ibl, ok := AsIterable(params[0])
 			if !ok {
 				return nil, IterableMismatch(params[0].Type())
 			}
@@ -534,6 +551,9 @@ var listMethods = map[string]Method{
 	"copy": NewNullaryMethod(
 		func(self interface{}, ev Eval) (Value, Error) {
 			ls := self.(List)
+			if err := AllocateValues(ev, len(ls.Values())); err != nil {
+				return nil, err
+			}
 			return ls.Copy(), nil
 		}),
 
@@ -565,8 +585,8 @@ var listMethods = map[string]Method{
 			fn := params[0].(Func)
 			expected := Arity{FixedArity, 1, 0}
 			if fn.Arity() != expected {
-				return nil, fmt.Errorf(
-					"ArityMismatch: filter function must have 1 parameter")
+				return nil, NewError(
+					"ArityMismatch", "filter function must have 1 parameter")
 			}
 
 			// invoke
@@ -578,8 +598,8 @@ var listMethods = map[string]Method{
 
 				result, ok := val.(Bool)
 				if !ok {
-					return nil, fmt.Errorf(
-						"TypeMismatch: filter function must return Bool, not %s", val.Type())
+					return nil, NewError(
+						"TypeMismatch", fmt.Sprintf("filter function must return Bool, not %s", val.Type()))
 				}
 				return result, nil
 			})
@@ -685,8 +705,8 @@ var listMethods = map[string]Method{
 			fn := params[0].(Func)
 			expected := Arity{FixedArity, 1, 0}
 			if fn.Arity() != expected {
-				return nil, fmt.Errorf(
-					"ArityMismatch: map function must have 1 parameter")
+				return nil, NewError(
+					"ArityMismatch", "map function must have 1 parameter")
 			}
 
 			// invoke
@@ -731,8 +751,8 @@ var listMethods = map[string]Method{
 			fn := params[1].(Func)
 			expected := Arity{FixedArity, 2, 0}
 			if fn.Arity() != expected {
-				return nil, fmt.Errorf(
-					"ArityMismatch: reduce function must have 2 parameters")
+				return nil, NewError(
+					"ArityMismatch", "reduce function must have 2 parameters")
 			}
 
 			// invoke
@@ -793,8 +813,8 @@ var listMethods = map[string]Method{
 			fn := params[0].(Func)
 			expected := Arity{FixedArity, 2, 0}
 			if fn.Arity() != expected {
-				return nil, fmt.Errorf(
-					"ArityMismatch: sort function must have 2 parameters")
+				return nil, NewError(
+					"ArityMismatch", "sort function must have 2 parameters")
 			}
 
 			// invoke
@@ -806,8 +826,8 @@ var listMethods = map[string]Method{
 
 				result, ok := val.(Bool)
 				if !ok {
-					return nil, fmt.Errorf(
-						"TypeMismatch: sort function must return Bool, not %s", val.Type())
+					return nil, NewError(
+						"TypeMismatch", fmt.Sprintf("sort function must return Bool, not %s", val.Type()))
 				}
 				return result, nil
 			})
@@ -825,7 +845,11 @@ var listMethods = map[string]Method{
 	*/
 	"toTuple": NewNullaryMethod(
 		func(self interface{}, ev Eval) (Value, Error) {
-			return self.(List).ToTuple()
+			ls := self.(List)
+			if err := AllocateValues(ev, len(ls.Values())); err != nil {
+				return nil, err
+			}
+			return ls.ToTuple()
 		}),
 }
 
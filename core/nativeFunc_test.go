@@ -146,7 +146,10 @@ func TestMultipleNativeFunc(t *testing.T) {
 					return nil, err
 				}
 
-				s = s.Concat(val)
+				s, err = s.Concat(ev, val)
+				if err != nil {
+					return nil, err
+				}
 			}
 			return s, nil
 		})
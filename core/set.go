@@ -4,10 +4,6 @@
 
 package core
 
-import (
-	"bytes"
-)
-
 /*doc
 ## Set
 
@@ -57,7 +53,7 @@ func (s *set) Frozen(ev Eval) (Bool, Error) {
 
 func (s *set) ToStr(ev Eval) (Str, Error) {
 
-	var buf bytes.Buffer
+	buf := NewStrBuilder(ev)
 	buf.WriteString("set {")
 	idx := 0
 	itr := s.hashMap.Iterator()
@@ -80,7 +76,7 @@ func (s *set) ToStr(ev Eval) (Str, Error) {
 	}
 
 	buf.WriteString(" }")
-	return NewStr(buf.String())
+	return buf.ToStr()
 }
 
 func (s *set) HashCode(ev Eval) (Int, Error) {
@@ -361,7 +357,7 @@ var setMethods = map[string]Method{
 		func(self interface{}, ev Eval, params []Value) (Value, Error) {
 			s := self.(Set)
 
-			ibl, ok := params[0].(Iterable)
+			ibl, ok := AsIterable(params[0])
 			if !ok {
 				return nil, IterableMismatch(params[0].Type())
 			}
@@ -430,7 +426,7 @@ var setMethods = map[string]Method{
 		func(self interface{}, ev Eval, params []Value) (Value, Error) {
 			s := self.(Set)
 
-			ibl, ok := params[0].(Iterable)
+			ibl, ok := AsIterable(params[0])
 			if !ok {
 				return nil, IterableMismatch(params[0].Type())
 			}
@@ -458,7 +454,7 @@ var setMethods = map[string]Method{
 		func(self interface{}, ev Eval, params []Value) (Value, Error) {
 			s := self.(Set)
 
-			ibl, ok := params[0].(Iterable)
+			ibl, ok := AsIterable(params[0])
 			if !ok {
 				return nil, IterableMismatch(params[0].Type())
 			}
@@ -139,7 +139,7 @@ func (s str) Slice(ev Eval, from Value, to Value) (Value, Error) {
 		return nil, err
 	}
 
-	return str(string(runes[f:t])), nil
+	return runesToStr(ev, runes[f:t])
 }
 
 func (s str) SliceFrom(ev Eval, from Value) (Value, Error) {
@@ -150,7 +150,7 @@ func (s str) SliceFrom(ev Eval, from Value) (Value, Error) {
 		return nil, err
 	}
 
-	return str(string(runes[f:])), nil
+	return runesToStr(ev, runes[f:])
 }
 
 func (s str) SliceTo(ev Eval, to Value) (Value, Error) {
@@ -161,7 +161,21 @@ func (s str) SliceTo(ev Eval, to Value) (Value, Error) {
 		return nil, err
 	}
 
-	return str(string(runes[:t])), nil
+	return runesToStr(ev, runes[:t])
+}
+
+// runesToStr creates a Str from a slice of runes.
+func runesToStr(ev Eval, runes []rune) (Str, Error) {
+
+	n := 0
+	for _, r := range runes {
+		n += utf8.RuneLen(r)
+	}
+	if err := Allocate(ev, int64(n)); err != nil {
+		return nil, err
+	}
+
+	return str(string(runes)), nil
 }
 
 //---------------------------------------------------------------
@@ -199,10 +213,13 @@ func (i *strIterator) IterGet(ev Eval) (Value, Error) {
 
 //--------------------------------------------------------------
 
-func (s str) Concat(that Str) Str {
+func (s str) Concat(ev Eval, that Str) (Str, Error) {
 	a := string(s)
 	b := string(that.(str))
-	return str(strcpy(a) + strcpy(b))
+	if err := Allocate(ev, int64(len(a)+len(b))); err != nil {
+		return nil, err
+	}
+	return str(strcpy(a) + strcpy(b)), nil
 }
 
 func strcpy(s string) string {
@@ -259,6 +276,15 @@ func (s str) Replace(old, new Str, n Int) Str {
 	return str(strings.Replace(a, b, c, d))
 }
 
+// replacedLen returns the length, in bytes, of the result of Replace.
+func replacedLen(s, old, new string, n int) int {
+	m := strings.Count(s, old)
+	if n >= 0 && n < m {
+		m = n
+	}
+	return len(s) + m*(len(new)-len(old))
+}
+
 func (s str) Split(sep Str) List {
 	a := string(s)
 	b := string(sep.(str))
@@ -293,16 +319,16 @@ func (s str) Map(ev Eval, mapper StrMapper) (Str, Error) {
 
 	runes := []rune(string(s))
 
-	result := make([]string, len(runes))
-	for i, r := range runes {
+	buf := NewStrBuilder(ev)
+	for _, r := range runes {
 		m, err := mapper(ev, str(r))
 		if err != nil {
 			return nil, err
 		}
-		result[i] = m.String()
+		buf.WriteString(m.String())
 	}
 
-	return NewStr(strings.Join(result, ""))
+	return buf.ToStr()
 }
 
 func (s str) ToRunes() List {
@@ -467,8 +493,8 @@ var strMethods = map[string]Method{
 			fn := params[0].(Func)
 			expected := Arity{FixedArity, 1, 0}
 			if fn.Arity() != expected {
-				return nil, fmt.Errorf(
-					"ArityMismatch: map function must have 1 parameter")
+				return nil, NewError(
+					"ArityMismatch", "map function must have 1 parameter")
 			}
 
 			// invoke
@@ -480,8 +506,8 @@ var strMethods = map[string]Method{
 
 				result, ok := val.(Str)
 				if !ok {
-					return nil, fmt.Errorf(
-						"TypeMismatch: map function must return Str, not %s", val.Type())
+					return nil, NewError(
+						"TypeMismatch", fmt.Sprintf("map function must return Str, not %s", val.Type()))
 				}
 				return result, nil
 			})
@@ -554,7 +580,13 @@ var strMethods = map[string]Method{
 			if len(params) == 3 {
 				n = params[2].(Int)
 			}
-			return self.(Str).Replace(old, new, n), nil
+
+			s := self.(Str)
+			size := replacedLen(s.String(), old.String(), new.String(), int(n.ToInt()))
+			if err := Allocate(ev, int64(size)); err != nil {
+				return nil, err
+			}
+			return s.Replace(old, new, n), nil
 		}),
 
 	/*doc
@@ -576,7 +608,12 @@ var strMethods = map[string]Method{
 	"split": NewFixedMethod(
 		[]Type{StrType}, false,
 		func(self interface{}, ev Eval, params []Value) (Value, Error) {
-			return self.(Str).Split(params[0].(Str)), nil
+			s := self.(Str)
+			sep := params[0].(Str)
+			if err := AllocateValues(ev, strings.Count(s.String(), sep.String())+1); err != nil {
+				return nil, err
+			}
+			return s.Split(sep), nil
 		}),
 
 	/*doc
@@ -591,6 +628,9 @@ var strMethods = map[string]Method{
 	"toChars": NewNullaryMethod(
 		func(self interface{}, ev Eval) (Value, Error) {
 			s := self.(Str)
+			if err := AllocateValues(ev, utf8.RuneCountInString(s.String())); err != nil {
+				return nil, err
+			}
 			return s.ToChars(), nil
 		}),
 
@@ -606,6 +646,9 @@ var strMethods = map[string]Method{
 	"toRunes": NewNullaryMethod(
 		func(self interface{}, ev Eval) (Value, Error) {
 			s := self.(Str)
+			if err := AllocateValues(ev, utf8.RuneCountInString(s.String())); err != nil {
+				return nil, err
+			}
 			return s.ToRunes(), nil
 		}),
 
@@ -207,6 +207,9 @@ func (s *stream) ToList(ev Eval) (List, Error) {
 	for val != nil {
 
 		//--------------
+		if err := AllocateValues(ev, 1); err != nil {
+			return nil, err
+		}
 		values = append(values, val)
 		//--------------
 
@@ -5,7 +5,6 @@
 package core
 
 import (
-	"bytes"
 	"fmt"
 	//"sync"
 
@@ -23,21 +22,62 @@ Valid operators for Struct are:
 
 Structs do not have any pre-defined fields.
 
-Structs can have the following magic fields:
+Structs can have the following magic fields, which override the
+default behaviour of a struct:
 
-* `__eq__` overrides the `==` operator
+* `$add`, `$sub` and `$mul` override the `+`, `-` and `*` operators.  The hook
+must be defined on the left operand: `v + 2` invokes `v.$add(2)`, but the hooks
+of the right operand are never invoked, so `2 + v` fails with a `TypeMismatch`
+error.  If the left operand is a Str, `+` concatenates it with the struct's
+string value instead.
 
-	* signature: `__eq__(x <Value>) <Bool>`
+	* signature: `$add(x <Value>) <Value>`
 
-* `__hashCode__` causes a struct to be [hashable](interfaces.html#hashable), so it can be
-used as a key in a dict, or an entry in a set.  Note that if you define `__hashCode__`,
-you *must* also always define `__eq__`.  Values that are equal must have the same hashCode.
+* `$eq` overrides the `==` operator
 
-	* signature: `__hashCode__() <Int>`
+	* signature: `$eq(x <Value>) <Bool>`
 
-* `__str__` overrides the value returned by the builtin function [`str`](builtins.html#str)
+* `$cmp` causes a struct to be [comparable](interfaces.html#comparable), so it can be
+used with the comparison operators `<`, `<=`, `>`, `>=`, `<=>`, and sorted.  The
+function must return a negative Int, zero, or a positive Int.
 
-	* signature: `__str__() <Str>`
+	* signature: `$cmp(x <Value>) <Int>`
+
+* `$hashCode` causes a struct to be [hashable](interfaces.html#hashable), so it can be
+used as a key in a dict, or an entry in a set.  Note that if you define `$hashCode`,
+you *must* also always define `$eq`.  Values that are equal must have the same hashCode.
+
+	* signature: `$hashCode() <Int>`
+
+* `$toStr` overrides the value returned by the builtin function [`str`](builtins.html#str)
+
+	* signature: `$toStr() <Str>`
+
+* `$get` and `$set` cause a struct to be [indexable](interfaces.html#indexable),
+so it can be used with the index operator `[]`
+
+	* signature: `$get(index <Value>) <Value>`
+	* signature: `$set(index <Value>, x <Value>)`
+
+* `$len` causes a struct to be [lenable](interfaces.html#lenable)
+
+	* signature: `$len() <Int>`
+
+* `$iter` causes a struct to be [iterable](interfaces.html#iterable), so it can be
+used in a `for` loop, passed to [`stream`](builtins.html#stream), and so forth.  The
+function must return an iterable value, or a struct that has `next` and `get` fields.
+
+	* signature: `$iter() <Iterable>`
+
+A struct that has `next` and `get` fields is also iterable.  `next` advances to the
+next value, returning false when there are no more values, and `get` returns the
+current value.
+
+	* signature: `next() <Bool>`
+	* signature: `get() <Value>`
+
+The older names `__eq__`, `__hashCode__` and `__str__` are still supported,
+as aliases for `$eq`, `$hashCode` and `$toStr`.
 
 */
 
@@ -54,7 +94,7 @@ type _struct struct {
 func NewStruct(fields map[string]Field) (Struct, Error) {
 
 	for key := range fields {
-		if !scanner.IsIdentifier(key) {
+		if !scanner.IsFieldName(key) {
 			return nil, InvalidStructKey(key)
 		}
 	}
@@ -72,7 +112,7 @@ func NewStruct(fields map[string]Field) (Struct, Error) {
 func NewFrozenStruct(fields map[string]Field) (Struct, Error) {
 
 	for key := range fields {
-		if !scanner.IsIdentifier(key) {
+		if !scanner.IsFieldName(key) {
 			return nil, InvalidStructKey(key)
 		}
 	}
@@ -86,11 +126,29 @@ func NewFrozenStruct(fields map[string]Field) (Struct, Error) {
 	}, nil
 }
 
+// NewShapedStruct creates a new Struct that has a field for each of the
+// names in the given Shape.  The fields are all initialized to null.
+func NewShapedStruct(shape *Shape) Struct {
+
+	fields := make([]Field, len(shape.names))
+	for i := range fields {
+		fields[i] = NewField(Null)
+	}
+
+	return &_struct{
+		fieldMap: &shapedFieldMap{
+			shape:  shape,
+			fields: fields,
+		},
+		frozen: false,
+	}
+}
+
 // NewMethodStruct create a new Struct backed by Methods.
 func NewMethodStruct(self interface{}, methods map[string]Method) (Struct, Error) {
 
 	for key := range methods {
-		if !scanner.IsIdentifier(key) {
+		if !scanner.IsFieldName(key) {
 			return nil, InvalidStructKey(key)
 		}
 	}
@@ -150,17 +208,13 @@ func (st *_struct) Frozen(ev Eval) (Bool, Error) {
 
 func (st *_struct) ToStr(ev Eval) (Str, Error) {
 
-	magic, err := st.HasField("__str__")
-	if err != nil {
-		return nil, err
-	}
-	if magic {
-		return st.magicStr(ev)
+	if name, ok := st.magicField("$toStr", "__str__"); ok {
+		return st.magicStr(ev, name)
 	}
 
 	//---------------------------------------
 
-	var buf bytes.Buffer
+	buf := NewStrBuilder(ev)
 	buf.WriteString("struct {")
 
 	names := st.fieldMap.names()
@@ -186,38 +240,20 @@ func (st *_struct) ToStr(ev Eval) (Str, Error) {
 	}
 
 	buf.WriteString(" }")
-	return NewStr(buf.String())
+	return buf.ToStr()
 }
 
-func (st *_struct) magicStr(ev Eval) (Str, Error) {
+func (st *_struct) magicStr(ev Eval, name string) (Str, Error) {
 
-	fv, err := st.GetField(ev, "__str__")
-	if err != nil {
-		return nil, err
-	}
-
-	fn, ok := fv.(Func)
-	if !ok {
-		return nil, fmt.Errorf(
-			"TypeMismatch: __str__ must be a Func, not %s", fv.Type())
-	}
-
-	// check arity
-	expected := Arity{FixedArity, 0, 0}
-	if fn.Arity() != expected {
-		return nil, fmt.Errorf(
-			"ArityMismatch: __str__ function must have 0 parameters")
-	}
-
-	result, err := fn.Invoke(ev, nil)
+	result, err := st.invokeMagic(ev, name, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	s, ok := result.(Str)
 	if !ok {
-		return nil, fmt.Errorf(
-			"TypeMismatch: __str__ must return a Str, not %s", result.Type())
+		return nil, NewError(
+			"TypeMismatch", fmt.Sprintf("%s must return a Str, not %s", name, result.Type()))
 	}
 
 	return s, nil
@@ -225,12 +261,8 @@ func (st *_struct) magicStr(ev Eval) (Str, Error) {
 
 func (st *_struct) HashCode(ev Eval) (Int, Error) {
 
-	magic, err := st.HasField("__hashCode__")
-	if err != nil {
-		return nil, err
-	}
-	if magic {
-		return st.magicHashCode(ev)
+	if name, ok := st.magicField("$hashCode", "__hashCode__"); ok {
+		return st.magicHashCode(ev, name)
 	}
 
 	//---------------------------------------
@@ -238,35 +270,17 @@ func (st *_struct) HashCode(ev Eval) (Int, Error) {
 	return nil, HashCodeMismatch(StructType)
 }
 
-func (st *_struct) magicHashCode(ev Eval) (Int, Error) {
-
-	fv, err := st.GetField(ev, "__hashCode__")
-	if err != nil {
-		return nil, err
-	}
-
-	fn, ok := fv.(Func)
-	if !ok {
-		return nil, fmt.Errorf(
-			"TypeMismatch: __hashCode__ must be a Func, not %s", fv.Type())
-	}
+func (st *_struct) magicHashCode(ev Eval, name string) (Int, Error) {
 
-	// check arity
-	expected := Arity{FixedArity, 0, 0}
-	if fn.Arity() != expected {
-		return nil, fmt.Errorf(
-			"ArityMismatch: __hashCode__ function must have 0 parameters")
-	}
-
-	result, err := fn.Invoke(ev, nil)
+	result, err := st.invokeMagic(ev, name, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	i, ok := result.(Int)
 	if !ok {
-		return nil, fmt.Errorf(
-			"TypeMismatch: __hashCode__ must return an Int, not %s", result.Type())
+		return nil, NewError(
+			"TypeMismatch", fmt.Sprintf("%s must return an Int, not %s", name, result.Type()))
 	}
 
 	return i, nil
@@ -274,12 +288,8 @@ func (st *_struct) magicHashCode(ev Eval) (Int, Error) {
 
 func (st *_struct) Eq(ev Eval, val Value) (Bool, Error) {
 
-	magic, err := st.HasField("__eq__")
-	if err != nil {
-		return nil, err
-	}
-	if magic {
-		return st.magicEq(ev, val)
+	if name, ok := st.magicField("$eq", "__eq__"); ok {
+		return st.magicEq(ev, name, val)
 	}
 
 	//---------------------------------------
@@ -337,38 +347,79 @@ func (st *_struct) Eq(ev Eval, val Value) (Bool, Error) {
 	return True, nil
 }
 
-func (st *_struct) magicEq(ev Eval, val Value) (Bool, Error) {
+func (st *_struct) magicEq(ev Eval, name string, val Value) (Bool, Error) {
 
-	fv, err := st.GetField(ev, "__eq__")
+	result, err := st.invokeMagic(ev, name, []Value{val})
 	if err != nil {
 		return nil, err
 	}
 
-	fn, ok := fv.(Func)
+	b, ok := result.(Bool)
 	if !ok {
-		return nil, fmt.Errorf(
-			"TypeMismatch: __eq__ must be a Func, not %s", fv.Type())
+		return nil, NewError(
+			"TypeMismatch", fmt.Sprintf("%s must return a Bool, not %s", name, result.Type()))
 	}
 
-	// check arity
-	expected := Arity{FixedArity, 1, 0}
-	if fn.Arity() != expected {
-		return nil, fmt.Errorf(
-			"ArityMismatch: __eq__ function must have 1 parameter")
+	return b, nil
+}
+
+func (st *_struct) Cmp(ev Eval, c Comparable) (Int, Error) {
+
+	if _, ok := st.magicField("$cmp"); !ok {
+		return nil, ComparableMismatch(StructType, c.(Value).Type())
 	}
 
-	result, err := fn.Invoke(ev, []Value{val})
+	result, err := st.invokeMagic(ev, "$cmp", []Value{c.(Value)})
 	if err != nil {
 		return nil, err
 	}
 
-	b, ok := result.(Bool)
+	i, ok := result.(Int)
 	if !ok {
-		return nil, fmt.Errorf(
-			"TypeMismatch: __eq__ must return a Bool, not %s", result.Type())
+		return nil, NewError(
+			"TypeMismatch", fmt.Sprintf("$cmp must return an Int, not %s", result.Type()))
 	}
 
-	return b, nil
+	return i, nil
+}
+
+func (st *_struct) Get(ev Eval, index Value) (Value, Error) {
+
+	if _, ok := st.magicField("$get"); !ok {
+		return nil, IndexableMismatch(StructType)
+	}
+
+	return st.invokeMagic(ev, "$get", []Value{index})
+}
+
+func (st *_struct) Set(ev Eval, index Value, val Value) Error {
+
+	if _, ok := st.magicField("$set"); !ok {
+		return IndexableMismatch(StructType)
+	}
+
+	_, err := st.invokeMagic(ev, "$set", []Value{index, val})
+	return err
+}
+
+func (st *_struct) Len(ev Eval) (Int, Error) {
+
+	if _, ok := st.magicField("$len"); !ok {
+		return nil, LenableMismatch(StructType)
+	}
+
+	result, err := st.invokeMagic(ev, "$len", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	i, ok := result.(Int)
+	if !ok {
+		return nil, NewError(
+			"TypeMismatch", fmt.Sprintf("$len must return an Int, not %s", result.Type()))
+	}
+
+	return i, nil
 }
 
 func (st *_struct) ToDict(ev Eval) (Dict, Error) {
@@ -425,3 +476,169 @@ func (st *_struct) SetField(ev Eval, name string, val Value) Error {
 
 	return st.fieldMap.set(ev, name, val)
 }
+
+// FieldIndex returns the Shape of a value, and the index of one of its
+// fields within that Shape.  It returns false if the value is not a Struct
+// that was created via NewShapedStruct, or if the Struct has no such field.
+func FieldIndex(val Value, name string) (*Shape, int, bool) {
+
+	if st, ok := val.(*_struct); ok {
+		if fm, ok := st.fieldMap.(*shapedFieldMap); ok {
+			if i, ok := fm.shape.indexes[name]; ok {
+				return fm.shape, i, true
+			}
+		}
+	}
+	return nil, 0, false
+}
+
+// ShapedField returns the field at the given index of a value, without
+// looking up the field by name.  It returns false unless the value is a
+// Struct that has the given Shape.
+func ShapedField(val Value, shape *Shape, index int) (Field, bool) {
+
+	if st, ok := val.(*_struct); ok {
+		if fm, ok := st.fieldMap.(*shapedFieldMap); ok && fm.shape == shape {
+			return fm.fields[index], true
+		}
+	}
+	return nil, false
+}
+
+//--------------------------------------------------------------
+// Iterator
+
+// NewIterator creates an Iterator for a struct that has an '$iter' field,
+// or that has 'next' and 'get' fields.
+func (st *_struct) NewIterator(ev Eval) (Iterator, Error) {
+
+	if _, ok := st.magicField("$iter"); ok {
+
+		val, err := st.invokeMagic(ev, "$iter", nil)
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := val.(type) {
+		case *_struct:
+			if t.hasIteratorFields() {
+				return newStructIterator(ev, t), nil
+			}
+		case Iterator:
+			return t, nil
+		case Iterable:
+			return t.NewIterator(ev)
+		}
+		return nil, NewError(
+			"TypeMismatch", fmt.Sprintf("$iter must return an Iterable, not %s", val.Type()))
+	}
+
+	if st.hasIteratorFields() {
+		return newStructIterator(ev, st), nil
+	}
+	return nil, IterableMismatch(StructType)
+}
+
+func (st *_struct) isIterable() bool {
+	_, ok := st.magicField("$iter")
+	return ok || st.hasIteratorFields()
+}
+
+func (st *_struct) hasIteratorFields() bool {
+	return st.fieldMap.has("next") && st.fieldMap.has("get")
+}
+
+// A structIterator iterates by invoking the 'next' and 'get'
+// fields of a struct that was defined in golem code.
+type structIterator struct {
+	Struct
+	src *_struct
+}
+
+func newStructIterator(ev Eval, src *_struct) Iterator {
+
+	itr := &structIterator{iteratorStruct(), src}
+	InitIteratorFields(ev, itr)
+	return itr
+}
+
+func (i *structIterator) IterNext(ev Eval) (Bool, Error) {
+
+	val, err := i.src.InvokeField(ev, "next", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	b, ok := val.(Bool)
+	if !ok {
+		return nil, NewError(
+			"TypeMismatch", fmt.Sprintf("next must return a Bool, not %s", val.Type()))
+	}
+	return b, nil
+}
+
+func (i *structIterator) IterGet(ev Eval) (Value, Error) {
+	return i.src.InvokeField(ev, "get", nil)
+}
+
+//--------------------------------------------------------------
+// magic fields
+
+// magicField returns the first of the given magic field names
+// that the struct has.
+func (st *_struct) magicField(names ...string) (string, bool) {
+
+	for _, name := range names {
+		if st.fieldMap.has(name) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// invokeMagic invokes a magic field, which must be a function
+// that accepts exactly the given parameters.
+func (st *_struct) invokeMagic(ev Eval, name string, params []Value) (Value, Error) {
+
+	fv, err := st.GetField(ev, name)
+	if err != nil {
+		return nil, err
+	}
+
+	fn, ok := fv.(Func)
+	if !ok {
+		return nil, NewError(
+			"TypeMismatch", fmt.Sprintf("%s must be a Func, not %s", name, fv.Type()))
+	}
+
+	// check arity
+	expected := Arity{FixedArity, uint16(len(params)), 0}
+	if fn.Arity() != expected {
+		plural := "s"
+		if len(params) == 1 {
+			plural = ""
+		}
+		return nil, NewError(
+			"ArityMismatch",
+			fmt.Sprintf("%s function must have %d parameter%s", name, len(params), plural))
+	}
+
+	return fn.Invoke(ev, params)
+}
+
+// InvokeMagicField invokes one of the magic fields of a value, if the
+// value is a Struct that has the field.  It returns false if the value
+// does not have the magic field.
+func InvokeMagicField(ev Eval, val Value, name string, params []Value) (Value, bool, Error) {
+
+	st, ok := val.(*_struct)
+	if !ok {
+		return nil, false, nil
+	}
+	if _, ok := st.magicField(name); !ok {
+		return nil, false, nil
+	}
+
+	result, err := st.invokeMagic(ev, name, params)
+	return result, true, err
+}
@@ -5,7 +5,6 @@
 package core
 
 import (
-	"bytes"
 	"fmt"
 )
 
@@ -53,7 +52,7 @@ func (tp tuple) Frozen(ev Eval) (Bool, Error) {
 
 func (tp tuple) ToStr(ev Eval) (Str, Error) {
 
-	var buf bytes.Buffer
+	buf := NewStrBuilder(ev)
 	buf.WriteString("(")
 	for idx, v := range tp {
 		if idx > 0 {
@@ -66,7 +65,7 @@ func (tp tuple) ToStr(ev Eval) (Str, Error) {
 		buf.WriteString(s.String())
 	}
 	buf.WriteString(")")
-	return NewStr(buf.String())
+	return buf.ToStr()
 }
 
 func (tp tuple) HashCode(ev Eval) (Int, Error) {
@@ -176,7 +175,11 @@ var tupleMethods = map[string]Method{
 	*/
 	"toList": NewNullaryMethod(
 		func(self interface{}, ev Eval) (Value, Error) {
-			return self.(Tuple).ToList(), nil
+			tp := self.(tuple)
+			if err := AllocateValues(ev, len(tp)); err != nil {
+				return nil, err
+			}
+			return tp.ToList(), nil
 		}),
 }
 
@@ -113,6 +113,31 @@ func valuesEq(ev Eval, as []Value, bs []Value) (Bool, Error) {
 	return True, nil
 }
 
+// AsIterable returns a value as an Iterable, if it can be iterated.  A Struct
+// can be iterated if it has an '$iter' field, or 'next' and 'get' fields.
+// An Iterator can also be iterated, in which case the Iterator of the
+// Iterable is always the Iterator itself.
+func AsIterable(val Value) (Iterable, bool) {
+
+	switch t := val.(type) {
+	case *_struct:
+		return t, t.isIterable()
+	case Iterable:
+		return t, true
+	case Iterator:
+		return iteratorIterable{t}, true
+	}
+	return nil, false
+}
+
+type iteratorIterable struct {
+	itr Iterator
+}
+
+func (i iteratorIterable) NewIterator(ev Eval) (Iterator, Error) {
+	return i.itr, nil
+}
+
 func iteratorStruct() Struct {
 
 	stc, err := NewFrozenStruct(
@@ -126,6 +151,20 @@ func iteratorStruct() Struct {
 	return stc
 }
 
+// NewIteratorStruct creates the Struct that an Iterator is built upon.
+// Once the Iterator has been created, its fields must be initialized via
+// InitIteratorFields().
+func NewIteratorStruct() Struct {
+	return iteratorStruct()
+}
+
+// InitIteratorFields initializes the 'next' and 'get' fields of an Iterator.
+func InitIteratorFields(ev Eval, itr Iterator) {
+	next, get := iteratorFields(ev, itr)
+	itr.Internal("next", next)
+	itr.Internal("get", get)
+}
+
 func iteratorFields(ev Eval, itr Iterator) (next Field, get Field) {
 
 	next = NewReadonlyField(
@@ -100,7 +100,7 @@ type (
 		Sliceable
 		Iterable
 
-		Concat(Str) Str
+		Concat(Eval, Str) (Str, Error)
 
 		Contains(Str) Bool
 		HasPrefix(Str) Bool
@@ -345,4 +345,7 @@ type Chan interface {
 
 	Send(Value)
 	Recv() Value
+
+	// ToChan returns the underlying Go channel
+	ToChan() chan Value
 }
@@ -0,0 +1,660 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dap implements a Debug Adapter Protocol server for Golem,
+// so that Golem programs can be debugged from within an editor.
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mjarmy/golem-lang/compiler"
+	g "github.com/mjarmy/golem-lang/core"
+	bc "github.com/mjarmy/golem-lang/core/bytecode"
+	"github.com/mjarmy/golem-lang/internal/jsonrpc"
+	"github.com/mjarmy/golem-lang/interpreter"
+	"github.com/mjarmy/golem-lang/scanner"
+)
+
+// Goroutines are not debugged, so there is only ever one thread.
+const threadID = 1
+
+// The scopes of each stack frame
+const (
+	localScope = iota
+	captureScope
+	globalScope
+	numScopes
+)
+
+// Server is a Debug Adapter Protocol server that debugs a single Golem program.
+//
+// Requests are read from the Server's input by Serve().  The program runs in
+// its own goroutine.  While the program is paused, requests that inspect it are
+// handed to the program's goroutine, so that the Interpreter is only ever used
+// from one goroutine at a time.
+type Server struct {
+	in       *bufio.Reader
+	out      io.Writer
+	builtins []*g.Builtin
+	importer interpreter.Importer
+	debugger *interpreter.Debugger
+
+	mx     sync.Mutex // guards out, seq and paused
+	seq    int
+	paused chan *command
+
+	launch     *launchArguments
+	configured bool
+	started    bool
+	mod        *bc.Module
+
+	// these are only used from the program's goroutine
+	frames []*interpreter.StackFrame
+	entry  bool
+}
+
+// A command is run on the program's goroutine while the program is paused.
+// If the command returns true, the program resumes according to the Action.
+type command struct {
+	fn   func(d *interpreter.Debugger) (interpreter.Action, bool)
+	done chan struct{}
+}
+
+// NewServer creates a new Server.
+func NewServer(
+	in io.Reader,
+	out io.Writer,
+	builtins []*g.Builtin,
+	importer interpreter.Importer) *Server {
+
+	s := &Server{
+		in:         bufio.NewReader(in),
+		out:        out,
+		builtins:   builtins,
+		importer:   importer,
+		seq:        0,
+		paused:     nil,
+		launch:     nil,
+		configured: false,
+		started:    false,
+		mod:        nil,
+		frames:     nil,
+		entry:      false,
+	}
+	s.debugger = interpreter.NewDebugger(s.onStop)
+	return s
+}
+
+// Serve reads requests until the client disconnects, or the input is closed.
+func (s *Server) Serve() error {
+
+	for {
+		buf, err := jsonrpc.ReadMessage(s.in)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(buf, &req); err != nil {
+			return err
+		}
+		if req.Type != "request" {
+			continue
+		}
+
+		if req.Command == "disconnect" {
+			s.respond(&req, nil)
+			return nil
+		}
+		s.handle(&req)
+	}
+}
+
+// Output sends some text to the client, to be shown in the editor's debug console.
+// The category is usually "stdout" or "stderr".  The Server calls Output itself
+// whenever the program uses 'print' or 'println'.  Output is safe to call from
+// any goroutine.
+func (s *Server) Output(category string, text string) {
+	s.sendEvent("output", map[string]interface{}{
+		"category": category,
+		"output":   text,
+	})
+}
+
+//---------------------------------------------------------------
+// Requests
+//---------------------------------------------------------------
+
+func (s *Server) handle(req *request) {
+
+	switch req.Command {
+
+	case "initialize":
+		s.respond(req, &capabilities{
+			SupportsConfigurationDoneRequest: true,
+			SupportsEvaluateForHovers:        true,
+		})
+		s.sendEvent("initialized", nil)
+
+	case "launch":
+		var args launchArguments
+		if !s.decode(req, &args) {
+			return
+		}
+		mod, err := compileProgram(args.Program, s.builtins)
+		if err != nil {
+			s.fail(req, err.Error())
+			return
+		}
+		s.launch = &args
+		s.mod = mod
+		s.respond(req, nil)
+		s.start()
+
+	case "configurationDone":
+		s.configured = true
+		s.respond(req, nil)
+		s.start()
+
+	case "setBreakpoints":
+		var args setBreakpointsArguments
+		if !s.decode(req, &args) {
+			return
+		}
+		s.respond(req, map[string]interface{}{
+			"breakpoints": s.setBreakpoints(args.Source.Path, args.Breakpoints),
+		})
+
+	case "threads":
+		s.respond(req, map[string]interface{}{
+			"threads": []thread{{threadID, "main"}},
+		})
+
+	case "pause":
+		s.debugger.Pause()
+		s.respond(req, nil)
+
+	case "continue":
+		s.resume(req, interpreter.Continue, map[string]interface{}{
+			"allThreadsContinued": true,
+		})
+	case "next":
+		s.resume(req, interpreter.StepOver, nil)
+	case "stepIn":
+		s.resume(req, interpreter.StepInto, nil)
+	case "stepOut":
+		s.resume(req, interpreter.StepOut, nil)
+
+	case "stackTrace":
+		s.inspect(req, func(d *interpreter.Debugger) interface{} {
+			return s.stackTrace()
+		})
+
+	case "scopes":
+		var args frameArguments
+		if !s.decode(req, &args) {
+			return
+		}
+		s.inspect(req, func(d *interpreter.Debugger) interface{} {
+			return s.scopes(args.FrameID)
+		})
+
+	case "variables":
+		var args variablesArguments
+		if !s.decode(req, &args) {
+			return
+		}
+		s.inspect(req, func(d *interpreter.Debugger) interface{} {
+			return s.variables(d, args.VariablesReference)
+		})
+
+	case "evaluate":
+		var args evaluateArguments
+		if !s.decode(req, &args) {
+			return
+		}
+		s.inspect(req, func(d *interpreter.Debugger) interface{} {
+			return s.evaluate(d, args.FrameID, args.Expression)
+		})
+
+	default:
+		s.fail(req, fmt.Sprintf("Unsupported command '%s'", req.Command))
+	}
+}
+
+func (s *Server) decode(req *request, args interface{}) bool {
+	if err := json.Unmarshal(req.Arguments, args); err != nil {
+		s.fail(req, err.Error())
+		return false
+	}
+	return true
+}
+
+func (s *Server) setBreakpoints(path string, bps []sourceBreakpoint) []breakpoint {
+
+	result := []breakpoint{}
+	lines := []int{}
+
+	for _, bp := range bps {
+		line := bp.Line
+		verified := true
+
+		// move the breakpoint to a line that can actually be hit
+		if s.mod != nil && s.mod.Path == path {
+			line, verified = interpreter.BreakableLine(s.mod, bp.Line)
+			if !verified {
+				line = bp.Line
+			}
+		}
+
+		result = append(result, breakpoint{verified, line})
+		lines = append(lines, line)
+	}
+
+	s.debugger.SetBreakpoints(path, lines)
+	return result
+}
+
+//---------------------------------------------------------------
+// Running the program
+//---------------------------------------------------------------
+
+// start runs the program once it has been launched, and the
+// client has finished configuring the breakpoints.
+func (s *Server) start() {
+
+	if s.started || s.launch == nil || !s.configured {
+		return
+	}
+	s.started = true
+
+	if s.launch.StopOnEntry {
+		s.entry = true
+		s.debugger.Pause()
+	}
+
+	go func() {
+		itp := interpreter.NewInterpreter(
+			s.outputBuiltins(), s.importer, interpreter.WithDebugger(s.debugger))
+		itp.SetGoErrorHandler(func(es interpreter.ErrorStruct) {
+			s.Output("stderr", es.String())
+		})
+
+		exitCode := 0
+		if err := runProgram(itp, s.mod, s.launch.Args); err != nil {
+			if es, ok := err.(interpreter.ErrorStruct); ok {
+				s.Output("stderr", es.String())
+			} else {
+				s.Output("stderr", err.Error()+"\n")
+			}
+			exitCode = 1
+		}
+
+		s.sendEvent("exited", map[string]interface{}{"exitCode": exitCode})
+		s.sendEvent("terminated", nil)
+	}()
+}
+
+// outputBuiltins replaces the builtins that print to stdout with
+// builtins that send 'output' events instead.
+func (s *Server) outputBuiltins() []*g.Builtin {
+
+	builtins := make([]*g.Builtin, len(s.builtins))
+	for i, b := range s.builtins {
+		switch b.Name {
+		case "print":
+			builtins[i] = &g.Builtin{Name: b.Name, Value: s.printFunc("")}
+		case "println":
+			builtins[i] = &g.Builtin{Name: b.Name, Value: s.printFunc("\n")}
+		default:
+			builtins[i] = b
+		}
+	}
+	return builtins
+}
+
+func (s *Server) printFunc(suffix string) g.NativeFunc {
+	return g.NewVariadicNativeFunc(
+		[]g.Type{}, g.AnyType, true,
+		func(ev g.Eval, params []g.Value) (g.Value, g.Error) {
+			var buf strings.Builder
+			for _, v := range params {
+				str, err := v.ToStr(ev)
+				if err != nil {
+					return nil, err
+				}
+				buf.WriteString(str.String())
+			}
+			buf.WriteString(suffix)
+
+			s.Output("stdout", buf.String())
+			return g.Null, nil
+		})
+}
+
+func compileProgram(program string, builtins []*g.Builtin) (*bc.Module, error) {
+
+	path, err := filepath.Abs(program)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	source := &scanner.Source{
+		Name: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		Path: path,
+		Code: string(buf),
+	}
+	return compiler.CompileSource(source, builtins)
+}
+
+// runProgram evaluates a module, and then runs its main() function if it has one.
+func runProgram(itp *interpreter.Interpreter, mod *bc.Module, args []string) error {
+
+	if _, es := itp.EvalModule(mod); es != nil {
+		return es
+	}
+
+	main, err := mod.Contents().GetField(itp, "main")
+	if err != nil {
+		// its ok for there to be no 'main'
+		if g.ErrorKind(err) == "NoSuchField" {
+			return nil
+		}
+		return err
+	}
+
+	mainFn, ok := main.(bc.Func)
+	if !ok {
+		return fmt.Errorf("'main' is not a function")
+	}
+	expected := g.Arity{Kind: g.FixedArity, Required: 1, Optional: 0}
+	if mainFn.Arity() != expected {
+		return fmt.Errorf("ArityMismatch: main function must have 1 parameter")
+	}
+
+	vals := make([]g.Value, len(args))
+	for i, a := range args {
+		s, err := g.NewStr(a)
+		if err != nil {
+			return err
+		}
+		vals[i] = s
+	}
+	if _, es := itp.EvalBytecode(mainFn, []g.Value{g.NewList(vals)}); es != nil {
+		return es
+	}
+	return nil
+}
+
+//---------------------------------------------------------------
+// Pausing
+//---------------------------------------------------------------
+
+// onStop is called on the program's goroutine whenever the program pauses.
+// It runs commands until one of them resumes the program.
+func (s *Server) onStop(d *interpreter.Debugger, reason interpreter.StopReason) interpreter.Action {
+
+	s.frames = d.Frames()
+
+	cmds := make(chan *command)
+	s.mx.Lock()
+	s.paused = cmds
+	s.mx.Unlock()
+
+	desc := reason.String()
+	if reason == interpreter.StopPause && s.entry {
+		desc = "entry"
+	}
+	s.entry = false
+
+	s.sendEvent("stopped", map[string]interface{}{
+		"reason":            desc,
+		"threadId":          threadID,
+		"allThreadsStopped": true,
+	})
+
+	for cmd := range cmds {
+		action, resume := cmd.fn(d)
+		if resume {
+			s.mx.Lock()
+			s.paused = nil
+			s.mx.Unlock()
+		}
+		close(cmd.done)
+
+		if resume {
+			return action
+		}
+	}
+	panic("unreachable")
+}
+
+// whilePaused runs a function on the program's goroutine, and waits
+// for it to finish.  It returns false if the program is not paused.
+func (s *Server) whilePaused(fn func(d *interpreter.Debugger) (interpreter.Action, bool)) bool {
+
+	s.mx.Lock()
+	cmds := s.paused
+	s.mx.Unlock()
+
+	if cmds == nil {
+		return false
+	}
+
+	cmd := &command{fn, make(chan struct{})}
+	cmds <- cmd
+	<-cmd.done
+	return true
+}
+
+// resume responds to a request, and then resumes the program.
+func (s *Server) resume(req *request, action interpreter.Action, body interface{}) {
+
+	ok := s.whilePaused(func(d *interpreter.Debugger) (interpreter.Action, bool) {
+		s.respond(req, body)
+		return action, true
+	})
+	if !ok {
+		s.fail(req, "The program is not paused")
+	}
+}
+
+// inspect responds to a request with information about the paused program.
+func (s *Server) inspect(req *request, fn func(d *interpreter.Debugger) interface{}) {
+
+	ok := s.whilePaused(func(d *interpreter.Debugger) (interpreter.Action, bool) {
+		switch body := fn(d).(type) {
+		case error:
+			s.fail(req, body.Error())
+		default:
+			s.respond(req, body)
+		}
+		return interpreter.Continue, false
+	})
+	if !ok {
+		s.fail(req, "The program is not paused")
+	}
+}
+
+//---------------------------------------------------------------
+// Inspection
+//---------------------------------------------------------------
+
+func (s *Server) stackTrace() interface{} {
+
+	frames := []stackFrame{}
+	for i, f := range s.frames {
+		frames = append(frames, stackFrame{
+			ID:     i + 1,
+			Name:   frameName(f),
+			Source: source{filepath.Base(f.Path), f.Path},
+			Line:   f.Line,
+			Column: f.Col,
+		})
+	}
+
+	return map[string]interface{}{
+		"stackFrames": frames,
+		"totalFrames": len(frames),
+	}
+}
+
+func frameName(f *interpreter.StackFrame) string {
+	tpl := f.Func.Template()
+	if tpl.Name != "" {
+		return tpl.Name
+	}
+	if tpl == tpl.Module.Pool.Templates[0] {
+		return tpl.Module.Name()
+	}
+	return "fn"
+}
+
+func (s *Server) frame(frameID int) (*interpreter.StackFrame, error) {
+	if frameID < 1 || frameID > len(s.frames) {
+		return nil, fmt.Errorf("Invalid frame %d", frameID)
+	}
+	return s.frames[frameID-1], nil
+}
+
+func (s *Server) scopes(frameID int) interface{} {
+
+	if _, err := s.frame(frameID); err != nil {
+		return err
+	}
+
+	ref := func(kind int) int {
+		return (frameID-1)*numScopes + kind + 1
+	}
+
+	return map[string]interface{}{
+		"scopes": []scope{
+			{"Locals", ref(localScope), false},
+			{"Captures", ref(captureScope), false},
+			{"Globals", ref(globalScope), false},
+		},
+	}
+}
+
+func (s *Server) variables(d *interpreter.Debugger, ref int) interface{} {
+
+	f, err := s.frame((ref-1)/numScopes + 1)
+	if err != nil {
+		return err
+	}
+
+	var vars []*interpreter.Variable
+	switch (ref - 1) % numScopes {
+	case localScope:
+		vars = f.Locals
+	case captureScope:
+		vars = f.Captures
+	case globalScope:
+		vars = interpreter.Globals(f.Func.Template().Module)
+	}
+
+	result := []variable{}
+	for _, v := range vars {
+		result = append(result, variable{v.Name, format(d, v.Value), 0})
+	}
+	return map[string]interface{}{
+		"variables": result,
+	}
+}
+
+func (s *Server) evaluate(d *interpreter.Debugger, frameID int, expr string) interface{} {
+
+	// evaluate in the innermost frame if there is no frame
+	if frameID == 0 {
+		frameID = 1
+	}
+	f, err := s.frame(frameID)
+	if err != nil {
+		return err
+	}
+
+	val, err := d.Evaluate(f, expr)
+	if err != nil {
+		return err
+	}
+	return map[string]interface{}{
+		"result":             format(d, val),
+		"variablesReference": 0,
+	}
+}
+
+func format(d *interpreter.Debugger, val g.Value) string {
+	if val == nil {
+		return "<undefined>"
+	}
+	s, err := val.ToStr(d.Interpreter())
+	if err != nil {
+		return fmt.Sprintf("<%s>", err.Error())
+	}
+	return s.String()
+}
+
+//---------------------------------------------------------------
+// Sending
+//---------------------------------------------------------------
+
+func (s *Server) respond(req *request, body interface{}) {
+	s.send(&response{
+		Type:       "response",
+		RequestSeq: req.Seq,
+		Success:    true,
+		Command:    req.Command,
+		Body:       body,
+	})
+}
+
+func (s *Server) fail(req *request, msg string) {
+	s.send(&response{
+		Type:       "response",
+		RequestSeq: req.Seq,
+		Success:    false,
+		Command:    req.Command,
+		Message:    msg,
+	})
+}
+
+func (s *Server) sendEvent(name string, body interface{}) {
+	s.send(&event{
+		Type:  "event",
+		Event: name,
+		Body:  body,
+	})
+}
+
+func (s *Server) send(msg interface{}) {
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.seq++
+	switch m := msg.(type) {
+	case *response:
+		m.Seq = s.seq
+	case *event:
+		m.Seq = s.seq
+	}
+
+	// there is nobody to report a write error to, since the client is gone
+	jsonrpc.WriteMessage(s.out, msg)
+}
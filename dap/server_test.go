@@ -0,0 +1,111 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dap
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	g "github.com/mjarmy/golem-lang/core"
+	"github.com/mjarmy/golem-lang/internal/jsonrpc/jsonrpctest"
+)
+
+var builtins = []*g.Builtin{
+	{"assert", g.BuiltinAssert},
+	{"println", g.BuiltinPrintln},
+}
+
+const program = `fn add(x, y) {
+    let z = x + y
+    return z
+}
+let a = 1
+let b = add(a, 2)
+println(b)
+`
+
+// A recorded session between an editor and the server.  Lines that start
+// with '->' are sent to the server, and lines that start with '<-' are the
+// messages we expect to receive.  The expected messages only have to match
+// the parts of the received messages that they mention.
+const session = `
+-> {"seq":1,"type":"request","command":"initialize","arguments":{"adapterID":"golem"}}
+<- {"type":"response","request_seq":1,"command":"initialize","success":true,"body":{"supportsConfigurationDoneRequest":true}}
+<- {"type":"event","event":"initialized"}
+
+-> {"seq":2,"type":"request","command":"launch","arguments":{"program":"${program}","stopOnEntry":true}}
+<- {"type":"response","command":"launch","success":true}
+
+-> {"seq":3,"type":"request","command":"setBreakpoints","arguments":{"source":{"path":"${program}"},"breakpoints":[{"line":3},{"line":99}]}}
+<- {"type":"response","command":"setBreakpoints","success":true,"body":{"breakpoints":[{"verified":true,"line":3},{"verified":false,"line":99}]}}
+
+-> {"seq":4,"type":"request","command":"configurationDone"}
+<- {"type":"response","command":"configurationDone","success":true}
+<- {"type":"event","event":"stopped","body":{"reason":"entry","threadId":1}}
+
+-> {"seq":5,"type":"request","command":"threads"}
+<- {"type":"response","command":"threads","body":{"threads":[{"id":1,"name":"main"}]}}
+
+-> {"seq":6,"type":"request","command":"continue","arguments":{"threadId":1}}
+<- {"type":"response","command":"continue","success":true}
+<- {"type":"event","event":"stopped","body":{"reason":"breakpoint","threadId":1}}
+
+-> {"seq":7,"type":"request","command":"stackTrace","arguments":{"threadId":1}}
+<- {"type":"response","command":"stackTrace","success":true,"body":{"totalFrames":2,"stackFrames":[{"id":1,"name":"add","line":3,"column":12,"source":{"path":"${program}"}},{"id":2,"name":"prog","line":6}]}}
+
+-> {"seq":8,"type":"request","command":"scopes","arguments":{"frameId":2}}
+<- {"type":"response","command":"scopes","success":true,"body":{"scopes":[{"name":"Locals","variablesReference":4},{"name":"Captures","variablesReference":5},{"name":"Globals","variablesReference":6}]}}
+
+-> {"seq":9,"type":"request","command":"variables","arguments":{"variablesReference":1}}
+<- {"type":"response","command":"variables","success":true,"body":{"variables":[{"name":"x","value":"1"},{"name":"y","value":"2"},{"name":"z","value":"3"}]}}
+
+-> {"seq":10,"type":"request","command":"variables","arguments":{"variablesReference":6}}
+<- {"type":"response","command":"variables","success":true,"body":{"variables":[{"name":"add"},{"name":"a","value":"1"},{"name":"b","value":"null"}]}}
+
+-> {"seq":11,"type":"request","command":"evaluate","arguments":{"expression":"z * 10 + a","frameId":1}}
+<- {"type":"response","command":"evaluate","success":true,"body":{"result":"31"}}
+
+-> {"seq":12,"type":"request","command":"evaluate","arguments":{"expression":"w","frameId":1}}
+<- {"type":"response","command":"evaluate","success":false,"message":"Symbol 'w' is not defined at <eval>:1:1"}
+
+-> {"seq":13,"type":"request","command":"next","arguments":{"threadId":1}}
+<- {"type":"response","command":"next","success":true}
+<- {"type":"event","event":"stopped","body":{"reason":"step","threadId":1}}
+
+-> {"seq":14,"type":"request","command":"next","arguments":{"threadId":1}}
+<- {"type":"response","command":"next","success":true}
+<- {"type":"event","event":"output","body":{"category":"stdout","output":"3\n"}}
+<- {"type":"event","event":"exited","body":{"exitCode":0}}
+<- {"type":"event","event":"terminated"}
+
+-> {"seq":15,"type":"request","command":"evaluate","arguments":{"expression":"a"}}
+<- {"type":"response","command":"evaluate","success":false,"message":"The program is not paused"}
+
+-> {"seq":16,"type":"request","command":"disconnect"}
+<- {"type":"response","request_seq":16,"command":"disconnect","success":true}
+`
+
+func TestSession(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "golem-dap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "prog.glm")
+	if err := ioutil.WriteFile(path, []byte(program), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	session := strings.Replace(session, "${program}", path, -1)
+	jsonrpctest.Replay(t, session, func(in io.Reader, out io.Writer) error {
+		return NewServer(in, out, builtins, nil).Serve()
+	})
+}
@@ -10,7 +10,9 @@ package main
 //--------------------------------------------------------------
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	// Its easier to read the code if we alias the 'core' package.
 	// By convention we use 'g', as in 'golem'.
@@ -398,6 +400,51 @@ return [
 	fmt.Printf("\n")
 }
 
+//--------------------------------------------------------------
+// Limit the amount of work that an interpreter can do.
+//--------------------------------------------------------------
+
+func example10() {
+
+	// compile some code that never finishes
+	code := `
+while true {
+	try {
+		while true {}
+	} catch e {
+		// the limits cannot be caught
+	}
+}
+`
+	mod, err := interpreter.CompileCode(code, g.SandboxBuiltins)
+	check(err)
+
+	// limit the number of opcodes that can be executed
+	itp := interpreter.NewInterpreter(
+		g.SandboxBuiltins, nil,
+		interpreter.WithMaxOpcodes(10000))
+	_, es := itp.EvalModule(mod)
+	fmt.Printf("example 10: %s\n", es.Error())
+
+	// stop after a timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	itp = interpreter.NewInterpreter(
+		g.SandboxBuiltins, nil,
+		interpreter.WithContext(ctx))
+	_, es = itp.EvalModule(mod)
+	fmt.Printf("example 10: %s\n", es.Error())
+
+	// interrupt from another goroutine
+	itp = interpreter.NewInterpreter(g.SandboxBuiltins, nil)
+	go (func() {
+		time.Sleep(10 * time.Millisecond)
+		itp.Interrupt()
+	})()
+	_, es = itp.EvalModule(mod)
+	fmt.Printf("example 10: %s\n", es.Error())
+}
+
 //--------------------------------------------------------------
 // main
 //--------------------------------------------------------------
@@ -412,4 +459,5 @@ func main() {
 	example7()
 	example8()
 	example9()
+	example10()
 }
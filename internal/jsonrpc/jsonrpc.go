@@ -0,0 +1,70 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package jsonrpc reads and writes the messages that are exchanged by the
+// Debug Adapter Protocol and Language Server Protocol servers.  In both
+// protocols, each message is a JSON value that is preceded by a header with
+// a 'Content-Length' field.
+package jsonrpc
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ReadMessage reads a message that is preceded by a 'Content-Length' header.
+func ReadMessage(r *bufio.Reader) ([]byte, error) {
+
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+
+		// a blank line ends the header
+		if line == "" {
+			break
+		}
+
+		const prefix = "Content-Length:"
+		if strings.HasPrefix(line, prefix) {
+			n, err := strconv.Atoi(strings.TrimSpace(line[len(prefix):]))
+			if err != nil {
+				return nil, fmt.Errorf("Invalid header '%s'", line)
+			}
+			length = n
+		}
+	}
+
+	if length < 0 {
+		return nil, fmt.Errorf("Missing Content-Length header")
+	}
+
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// WriteMessage writes a message, preceded by a 'Content-Length' header.
+func WriteMessage(w io.Writer, msg interface{}) error {
+
+	buf, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(buf)); err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
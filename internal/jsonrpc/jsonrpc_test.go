@@ -0,0 +1,53 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestMessages(t *testing.T) {
+
+	var buf bytes.Buffer
+	if err := WriteMessage(&buf, map[string]int{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteMessage(&buf, []string{"é"}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "Content-Length: 7\r\n\r\n{\"a\":1}Content-Length: 6\r\n\r\n[\"é\"]" {
+		t.Fatalf("%q", buf.String())
+	}
+
+	r := bufio.NewReader(&buf)
+	for _, expect := range []string{`{"a":1}`, `["é"]`} {
+		msg, err := ReadMessage(r)
+		if err != nil || string(msg) != expect {
+			t.Fatalf("%q, %v != %q", msg, err, expect)
+		}
+	}
+	if _, err := ReadMessage(r); err != io.EOF {
+		t.Fatalf("%v != EOF", err)
+	}
+}
+
+func TestBadHeaders(t *testing.T) {
+
+	for in, expect := range map[string]string{
+		"Content-Length: x\r\n\r\n{}":       "Invalid header 'Content-Length: x'",
+		"Content-Type: json\r\n\r\n{}":      "Missing Content-Length header",
+		"Content-Length: 10\r\n\r\n{}":      "unexpected EOF",
+		"Content-Length: 2\r\nContent-Type": "EOF",
+	} {
+		_, err := ReadMessage(bufio.NewReader(strings.NewReader(in)))
+		if err == nil || err.Error() != expect {
+			t.Errorf("%q: %v != %s", in, err, expect)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package jsonrpctest replays recorded sessions against the Debug Adapter
+// Protocol and Language Server Protocol servers, for use in their tests.
+package jsonrpctest
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mjarmy/golem-lang/internal/jsonrpc"
+)
+
+// Timeout is how long Replay waits for each of the expected messages,
+// and for the server to stop at the end of the session.
+const Timeout = 5 * time.Second
+
+// Replay plays the part of the client in a recorded session with a server.
+// Lines in the session that start with '->' are sent to the server, and lines
+// that start with '<-' are the messages we expect to receive.  The expected
+// messages only have to match the parts of the received messages that they
+// mention.  Any other lines are ignored.
+//
+// The serve function runs the server, which reads from 'in' and writes to
+// 'out'.  The last message in the session must make the server stop.
+func Replay(t testing.TB, session string, serve func(in io.Reader, out io.Writer) error) {
+
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+	defer clientOut.Close()
+
+	done := make(chan struct{})
+	go func() {
+		if err := serve(serverIn, serverOut); err != nil {
+			t.Error(err)
+		}
+		close(done)
+	}()
+
+	received := make(chan []byte)
+	go func() {
+		r := bufio.NewReader(clientIn)
+		for {
+			buf, err := jsonrpc.ReadMessage(r)
+			if err != nil {
+				close(received)
+				return
+			}
+			received <- buf
+		}
+	}()
+
+	for _, line := range strings.Split(session, "\n") {
+		line = strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(line, "->"):
+			msg := json.RawMessage(strings.TrimSpace(line[2:]))
+			if err := jsonrpc.WriteMessage(clientOut, msg); err != nil {
+				t.Fatal(err)
+			}
+
+		case strings.HasPrefix(line, "<-"):
+			var expect interface{}
+			if err := json.Unmarshal([]byte(line[2:]), &expect); err != nil {
+				t.Fatal(err)
+			}
+
+			select {
+			case buf, ok := <-received:
+				if !ok {
+					t.Fatalf("connection closed, expected %s", line)
+				}
+				var actual interface{}
+				if err := json.Unmarshal(buf, &actual); err != nil {
+					t.Fatal(err)
+				}
+				if !Matches(expect, actual) {
+					t.Fatalf("\nexpected %s\nreceived %s", line[2:], string(buf))
+				}
+			case <-time.After(Timeout):
+				t.Fatalf("timed out, expected %s", line)
+			}
+		}
+	}
+
+	select {
+	case <-done:
+	case <-time.After(Timeout):
+		t.Fatalf("server did not stop")
+	}
+}
+
+// Matches returns whether the actual value contains the expected value.
+// Both values are unmarshalled JSON.
+func Matches(expect interface{}, actual interface{}) bool {
+
+	switch e := expect.(type) {
+
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for k, v := range e {
+			if !Matches(v, a[k]) {
+				return false
+			}
+		}
+		return true
+
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok || len(a) != len(e) {
+			return false
+		}
+		for i := range e {
+			if !Matches(e[i], a[i]) {
+				return false
+			}
+		}
+		return true
+
+	default:
+		return expect == actual
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package interpreter
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	g "github.com/mjarmy/golem-lang/core"
+)
+
+// An Option configures an Interpreter.
+type Option func(*Interpreter)
+
+// WithMaxOpcodes limits the total number of opcodes that an Interpreter will
+// execute over its lifetime, including the opcodes executed by any goroutines
+// that it starts.  Once the limit is reached, evaluation fails with
+// an 'OpcodeLimitExceeded' error.  A limit of zero means there is no limit.
+func WithMaxOpcodes(n int64) Option {
+	return func(itp *Interpreter) {
+		itp.budget.maxOpcodes = n
+	}
+}
+
+// DefaultMaxFrameDepth is the maximum depth of an Interpreter's call stack,
+// unless it is overridden via WithMaxFrameDepth.
+const DefaultMaxFrameDepth = 10000
+
+// WithMaxFrameDepth limits the depth of an Interpreter's call stack.  Invoking
+// a function when the call stack is already at its maximum depth fails
+// with a 'StackOverflow' error.  A limit of zero means there is no limit.
+func WithMaxFrameDepth(n int) Option {
+	return func(itp *Interpreter) {
+		itp.budget.maxFrameDepth = n
+	}
+}
+
+// WithMaxAllocation limits the approximate number of bytes that an Interpreter,
+// and any goroutines it starts, can allocate for Lists, Tuples, Dicts, Sets and Strs
+// over its lifetime.  Memory that has been garbage collected is still counted.
+// Once the limit is reached, evaluation fails with a 'ResourceExhausted' error.
+// A limit of zero means there is no limit.
+func WithMaxAllocation(n int64) Option {
+	return func(itp *Interpreter) {
+		itp.budget.maxAllocation = n
+	}
+}
+
+// WithContext makes an Interpreter stop evaluating once the context is done.
+// Evaluation then fails with a 'DeadlineExceeded' error if the context's deadline
+// has passed, or a 'Canceled' error otherwise.
+func WithContext(ctx context.Context) Option {
+	return func(itp *Interpreter) {
+		itp.budget.ctx = ctx
+	}
+}
+
+// Interrupt stops the Interpreter, and any goroutines it has started, as
+// soon as possible.  Evaluation then fails with an 'Interrupted' error.
+// An Interpreter that has been interrupted stays interrupted.  An Interpreter
+// which is blocked on a channel is woken up.  Interrupt is safe to call from
+// any goroutine.
+func (itp *Interpreter) Interrupt() {
+	b := itp.budget
+	b.interruptOnce.Do(func() {
+		atomic.StoreInt32(&b.interrupted, 1)
+		close(b.interrupt)
+	})
+}
+
+// A budget keeps track of the limits on how much work an Interpreter can do.
+// The budget is shared with the Interpreters of any goroutines.
+type budget struct {
+	ctx           context.Context
+	maxOpcodes    int64
+	maxFrameDepth int
+	maxAllocation int64
+	opcodes       int64 // accessed atomically
+	allocated     int64 // accessed atomically
+	interrupted   int32 // accessed atomically
+
+	// closed when the budget is interrupted
+	interrupt     chan struct{}
+	interruptOnce sync.Once
+}
+
+// How often the context and interrupts are checked, in opcodes.  Unless
+// there is a limit on the number of opcodes, this is also how many opcodes
+// an Interpreter executes before it updates the shared count.
+const contextInterval = 1024
+
+func newBudget() *budget {
+	return &budget{
+		ctx:           nil,
+		maxOpcodes:    0,
+		maxFrameDepth: DefaultMaxFrameDepth,
+		maxAllocation: 0,
+		opcodes:       0,
+		allocated:     0,
+		interrupted:   0,
+		interrupt:     make(chan struct{}),
+	}
+}
+
+// spend is called before each opcode is executed.  If there is no limit on
+// the number of opcodes, then the opcodes are counted locally, and the budget
+// is only checked once per interval, so that the shared count is not
+// contended for on every opcode.
+func (itp *Interpreter) spend() g.Error {
+
+	b := itp.budget
+	if b.maxOpcodes > 0 {
+		return b.spend(1)
+	}
+
+	itp.unspent++
+	if itp.unspent < contextInterval {
+		return nil
+	}
+	n := itp.unspent
+	itp.unspent = 0
+	return b.spend(n)
+}
+
+// spend records that n opcodes have been executed.
+func (b *budget) spend(n int64) g.Error {
+
+	if atomic.LoadInt32(&b.interrupted) != 0 {
+		return fatalError{g.Interrupted()}
+	}
+
+	total := atomic.AddInt64(&b.opcodes, n)
+	if b.maxOpcodes > 0 && total > b.maxOpcodes {
+		return fatalError{g.OpcodeLimitExceeded(b.maxOpcodes)}
+	}
+
+	if b.ctx != nil && (total-n)/contextInterval != total/contextInterval {
+		return b.checkContext()
+	}
+
+	return nil
+}
+
+// checkContext returns an error if the budget's context is done.
+func (b *budget) checkContext() g.Error {
+	select {
+	case <-b.ctx.Done():
+		return b.contextError()
+	default:
+		return nil
+	}
+}
+
+func (b *budget) contextError() g.Error {
+	if b.ctx.Err() == context.DeadlineExceeded {
+		return fatalError{g.DeadlineExceeded()}
+	}
+	return fatalError{g.Canceled()}
+}
+
+// Select causes Interpreter to implement the core.Selector interface.
+// Blocking channel operations are woken up if the Interpreter is interrupted,
+// or if its context is done.
+func (itp *Interpreter) Select(cases []reflect.SelectCase) (int, reflect.Value, bool, g.Error) {
+
+	b := itp.budget
+	if atomic.LoadInt32(&b.interrupted) != 0 {
+		return -1, reflect.Value{}, false, fatalError{g.Interrupted()}
+	}
+
+	n := len(cases)
+	cases = append(cases, reflect.SelectCase{
+		Dir:  reflect.SelectRecv,
+		Chan: reflect.ValueOf(b.interrupt),
+	})
+	if b.ctx != nil {
+		cases = append(cases, reflect.SelectCase{
+			Dir:  reflect.SelectRecv,
+			Chan: reflect.ValueOf(b.ctx.Done()),
+		})
+	}
+
+	chosen, recv, recvOK := reflect.Select(cases)
+	switch {
+	case chosen < n:
+		return chosen, recv, recvOK, nil
+	case chosen == n:
+		return -1, reflect.Value{}, false, fatalError{g.Interrupted()}
+	default:
+		return -1, reflect.Value{}, false, b.contextError()
+	}
+}
+
+// Allocate causes Interpreter to implement the core.Meter interface.
+func (itp *Interpreter) Allocate(n int64) g.Error {
+
+	b := itp.budget
+	if b.maxAllocation == 0 {
+		return nil
+	}
+
+	if atomic.AddInt64(&b.allocated, n) > b.maxAllocation {
+		return fatalError{g.ResourceExhausted(b.maxAllocation)}
+	}
+	return nil
+}
+
+// checkFrameDepth is called before a new frame is pushed.
+func (itp *Interpreter) checkFrameDepth() ErrorStruct {
+
+	max := itp.budget.maxFrameDepth
+	if max > 0 && itp.frameStack.num() >= max {
+		frames, omitted := itp.frameStack.truncatedStackTrace()
+		return newTruncatedErrorStruct(g.StackOverflow(max), frames, omitted)
+	}
+	return nil
+}
+
+// A fatalError is an Error that cannot be caught.  Deferred
+// invocations are not run when a fatalError is thrown.
+type fatalError struct {
+	err g.Error
+}
+
+func (e fatalError) Error() string {
+	return e.err.Error()
+}
+
+func isFatal(es ErrorStruct) bool {
+	_, ok := es.(*errorStruct).err.(fatalError)
+	return ok
+}
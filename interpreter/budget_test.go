@@ -0,0 +1,345 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package interpreter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	g "github.com/mjarmy/golem-lang/core"
+)
+
+// an infinite loop that tries its best to keep going
+const foreverCode = `
+fn forever() {
+	defer forever()
+	while true {
+		try {
+			while true {}
+		} catch e {
+		} finally {
+			forever()
+		}
+	}
+}
+forever()
+`
+
+func TestMaxOpcodes(t *testing.T) {
+
+	mod := compile(t, "let a = 1 + 2; assert(a == 3);")
+	itp := NewInterpreter(builtins, nil, WithMaxOpcodes(100))
+	_, es := itp.EvalModule(mod)
+	tassert(t, es == nil)
+
+	mod = compile(t, foreverCode)
+	itp = NewInterpreter(builtins, nil, WithMaxOpcodes(1000))
+	_, es = itp.EvalModule(mod)
+	tassert(t, es != nil)
+	tassert(t, es.Kind() == "OpcodeLimitExceeded")
+	tassert(t, es.Error() == "OpcodeLimitExceeded: Exceeded the limit of 1000 opcodes")
+	tassert(t, itp.frameStack.num() == 0)
+}
+
+func TestContext(t *testing.T) {
+
+	mod := compile(t, foreverCode)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	itp := NewInterpreter(builtins, nil, WithContext(ctx))
+	_, es := itp.EvalModule(mod)
+	tassert(t, es != nil)
+	tassert(t, es.Kind() == "DeadlineExceeded")
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	itp = NewInterpreter(builtins, nil, WithContext(ctx))
+	_, es = itp.EvalModule(mod)
+	tassert(t, es != nil)
+	tassert(t, es.Kind() == "Canceled")
+}
+
+func TestInterrupt(t *testing.T) {
+
+	mod := compile(t, `
+fn forever() {
+	while true {}
+}
+let h = go forever()
+h.join()
+`)
+	itp := NewInterpreter(builtins, nil)
+	go (func() {
+		time.Sleep(10 * time.Millisecond)
+		itp.Interrupt()
+	})()
+
+	_, es := itp.EvalModule(mod)
+	tassert(t, es != nil)
+	tassert(t, es.Kind() == "Interrupted")
+
+	// a thrown error of the same kind can be caught
+	mod = compile(t, `
+try {
+	throw struct { kind: 'Interrupted' }
+} catch e {
+	assert(e.kind == 'Interrupted')
+}
+`)
+	itp = NewInterpreter(builtins, nil)
+	_, es = itp.EvalModule(mod)
+	tassert(t, es == nil)
+}
+
+func TestBlockedChan(t *testing.T) {
+
+	// a recv that blocks forever is woken when the context ends
+	mod := compile(t, "chan().recv()")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	itp := NewInterpreter(builtins, nil, WithContext(ctx))
+	_, es := itp.EvalModule(mod)
+	tassert(t, es != nil)
+	tassert(t, es.Kind() == "DeadlineExceeded")
+
+	// so is a send
+	mod = compile(t, "chan().send(1)")
+	ctx, cancel = context.WithCancel(context.Background())
+	go (func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	})()
+	itp = NewInterpreter(builtins, nil, WithContext(ctx))
+	_, es = itp.EvalModule(mod)
+	tassert(t, es != nil)
+	tassert(t, es.Kind() == "Canceled")
+
+	// a select is woken by an interrupt, as is a goroutine that is blocked
+	mod = compile(t, `
+let c = chan()
+let h = go fn() { c.recv(); }()
+select {
+case chan().recv():
+	assert(false)
+case chan().send(1):
+	assert(false)
+}
+`)
+	itp = NewInterpreter(builtins, nil)
+	go (func() {
+		time.Sleep(10 * time.Millisecond)
+		itp.Interrupt()
+	})()
+	_, es = itp.EvalModule(mod)
+	tassert(t, es != nil)
+	tassert(t, es.Kind() == "Interrupted")
+}
+
+func TestFatalInNative(t *testing.T) {
+
+	// fatal errors pass through native functions
+	mod := compile(t, `
+try {
+	[1, 2, 3].map(fn(x) {
+		while true {}
+	})
+} catch e {
+}
+`)
+	itp := NewInterpreter(builtins, nil, WithMaxOpcodes(500))
+	_, es := itp.EvalModule(mod)
+	tassert(t, es != nil)
+	tassert(t, es.Kind() == "OpcodeLimitExceeded")
+	tassert(t, itp.frameStack.num() == 0)
+}
+
+func TestStackOverflow(t *testing.T) {
+
+	mod := compile(t, `
+fn a(n) {
+	return a(n + 1) + 1
+}
+a(0)
+`)
+	itp := NewInterpreter(builtins, nil)
+	_, es := itp.EvalModule(mod)
+	tassert(t, es != nil)
+	tassert(t, es.Error() == "StackOverflow: Exceeded the maximum frame depth of 10000")
+	tassert(t, len(es.StackTrace()) == 21)
+	tassert(t, es.StackTrace()[0] == "    at a (foo.glm:3:9)")
+	tassert(t, es.StackTrace()[10] == "    ... 9980 more")
+	tassert(t, es.StackTrace()[20] == "    at foo.glm:5:1")
+	tassert(t, itp.frameStack.num() == 0)
+
+	// overflows can be caught, and go through native functions
+	mod = compile(t, `
+fn b() {
+	return [1].map(|x| => b())
+}
+try {
+	b()
+} catch e {
+	return e.kind
+}
+`)
+	itp = NewInterpreter(builtins, nil, WithMaxFrameDepth(50))
+	val, es := itp.EvalModule(mod)
+	tassert(t, es == nil)
+	tassert(t, val.(g.Str).String() == "StackOverflow")
+
+	// resuming a generator pushes a frame too
+	mod = compile(t, `
+fn c() {
+	for x in c() {
+		yield x
+	}
+	yield 1
+}
+try {
+	for x in c() {}
+} catch e {
+	return e.kind
+}
+`)
+	itp = NewInterpreter(builtins, nil, WithMaxFrameDepth(50))
+	val, es = itp.EvalModule(mod)
+	tassert(t, es == nil)
+	tassert(t, val.(g.Str).String() == "StackOverflow")
+	tassert(t, itp.frameStack.num() == 0)
+}
+
+func TestMaxAllocation(t *testing.T) {
+
+	mod := compile(t, "let a = [1, 2, 3]; a.add(4); assert(a == [1, 2, 3, 4]);")
+	itp := NewInterpreter(builtins, nil, WithMaxAllocation(1000))
+	_, es := itp.EvalModule(mod)
+	tassert(t, es == nil)
+
+	codes := []string{
+		`
+let a = []
+while true {
+	try {
+		a.add(0)
+	} catch e {
+	}
+}`,
+		`
+let s = 'a'
+while true {
+	s = s + s
+}`,
+		`
+let d = dict {}
+let n = 0
+while true {
+	d[n] = n
+	n++
+}`,
+		`
+let s = set {}
+let n = 0
+while true {
+	s.add(n)
+	n++
+}`,
+		`
+while true {
+	let a = [1, 2, 3, 4, 5, 6, 7, 8]
+}`,
+		`
+while true {
+	let t = (1, 2, 3, 4, 5, 6, 7, 8)
+}`,
+
+		// List functions
+		`
+let a = [1]
+while true {
+	a.addAll(a)
+}`,
+		`
+let a = [1, 2, 3, 4, 5, 6, 7, 8]
+while true {
+	a.map(|x| => x)
+}`,
+		`
+let a = [1, 2, 3, 4, 5, 6, 7, 8]
+while true {
+	a.filter(|x| => true)
+}`,
+		`
+let a = [1, 2, 3, 4, 5, 6, 7, 8]
+while true {
+	a.copy()
+}`,
+		`
+let a = [1, 2, 3, 4, 5, 6, 7, 8]
+while true {
+	a[1:]
+}`,
+		`
+let s = 'x'
+while true {
+	s = [s, s].join('')
+}`,
+		`
+stream(range(0, 1000000000)).toList()`,
+
+		// Dict and Set functions
+		`
+fn pairs() {
+	let n = 0
+	while true {
+		yield (n, n)
+		n++
+	}
+}
+let d = dict {}
+d.addAll(pairs())`,
+		`
+let s = set {}
+s.addAll(range(0, 1000000000))`,
+
+		// Str functions, and string building
+		`
+let s = 'x'
+while true {
+	s = s.replace('x', 'xx')
+}`,
+		`
+let s = 'abcdefgh'
+while true {
+	s[1:]
+}`,
+		`
+let s = 'x'
+while true {
+	s = s.map(|c| => c + c)
+}`,
+		`
+let s = 'abcdefgh'
+while true {
+	s.toChars()
+}`,
+		"let s = 'x'; while true { s = `${s}${s}`; }",
+		`
+let s = 'x'
+while true {
+	s = str([s, s])
+}`,
+	}
+
+	for _, code := range codes {
+		mod = compile(t, code)
+		itp = NewInterpreter(builtins, nil, WithMaxAllocation(1<<20))
+		_, es = itp.EvalModule(mod)
+		tassert(t, es != nil)
+		tassert(t, es.Error() == "ResourceExhausted: Exceeded the allocation limit of 1048576 bytes")
+		tassert(t, itp.frameStack.num() == 0)
+	}
+}
@@ -0,0 +1,378 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package interpreter
+
+import (
+	"sync"
+
+	"github.com/mjarmy/golem-lang/compiler"
+	g "github.com/mjarmy/golem-lang/core"
+	bc "github.com/mjarmy/golem-lang/core/bytecode"
+	"github.com/mjarmy/golem-lang/scanner"
+)
+
+//---------------------------------------------------------------
+// Debugger
+//---------------------------------------------------------------
+
+// Action tells a paused Interpreter how to proceed.
+type Action int
+
+// Actions
+const (
+	// Continue runs until the next breakpoint.
+	Continue Action = iota
+	// StepOver runs until the next line in the current function.
+	StepOver
+	// StepInto runs until the next line, including lines in any functions
+	// that are invoked from the current line.
+	StepInto
+	// StepOut runs until the current function returns.
+	StepOut
+)
+
+// StopReason describes why an Interpreter was paused.
+type StopReason int
+
+// StopReasons
+const (
+	StopBreakpoint StopReason = iota
+	StopStep
+	StopPause
+)
+
+func (r StopReason) String() string {
+	switch r {
+	case StopBreakpoint:
+		return "breakpoint"
+	case StopStep:
+		return "step"
+	case StopPause:
+		return "pause"
+	default:
+		panic("unreachable")
+	}
+}
+
+// A StopHandler is called whenever a Debugger pauses its Interpreter.
+// The Interpreter stays paused until the handler returns, and then
+// proceeds according to the Action that the handler returned.
+// The handler is called from the Interpreter's goroutine, so it is safe
+// to inspect the Interpreter from within the handler via Debugger.Frames().
+type StopHandler func(d *Debugger, reason StopReason) Action
+
+// A Debugger can pause an Interpreter at breakpoints, step through source code
+// one line at a time, and inspect the Interpreter's call stack while it is paused.
+//
+// A Debugger only controls the Interpreter that it was given to via WithDebugger.
+// Goroutines that are started via 'go' are not debugged.
+type Debugger struct {
+	onStop StopHandler
+
+	mx          sync.Mutex
+	breakpoints map[string]map[int]bool
+	pause       bool
+
+	itp     *Interpreter
+	action  Action
+	callers []*frame // the call stack when the current action began
+	stopped bool     // whether the StopHandler is currently running
+}
+
+// NewDebugger creates a new Debugger.
+func NewDebugger(onStop StopHandler) *Debugger {
+	return &Debugger{
+		onStop:      onStop,
+		breakpoints: map[string]map[int]bool{},
+		pause:       false,
+		itp:         nil,
+		action:      Continue,
+		callers:     nil,
+		stopped:     false,
+	}
+}
+
+// WithDebugger attaches a Debugger to an Interpreter.
+func WithDebugger(d *Debugger) Option {
+	return func(itp *Interpreter) {
+		itp.debugger = d
+		d.itp = itp
+	}
+}
+
+// Interpreter returns the Interpreter that the Debugger is attached to.
+// While the Interpreter is paused, it can be used as a g.Eval to
+// inspect values.  Code that is run this way does not stop at breakpoints.
+func (d *Debugger) Interpreter() *Interpreter {
+	return d.itp
+}
+
+// SetBreakpoints replaces all of the breakpoints for the source file with
+// the given path.  SetBreakpoints is safe to call from any goroutine.
+func (d *Debugger) SetBreakpoints(path string, lines []int) {
+
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	if len(lines) == 0 {
+		delete(d.breakpoints, path)
+		return
+	}
+
+	bps := map[int]bool{}
+	for _, ln := range lines {
+		bps[ln] = true
+	}
+	d.breakpoints[path] = bps
+}
+
+// SetBreakpoint adds a breakpoint.  SetBreakpoint is safe to call from any goroutine.
+func (d *Debugger) SetBreakpoint(path string, line int) {
+
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	if _, ok := d.breakpoints[path]; !ok {
+		d.breakpoints[path] = map[int]bool{}
+	}
+	d.breakpoints[path][line] = true
+}
+
+// ClearBreakpoint removes a breakpoint.  ClearBreakpoint is safe to call from any goroutine.
+func (d *Debugger) ClearBreakpoint(path string, line int) {
+
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	if bps, ok := d.breakpoints[path]; ok {
+		delete(bps, line)
+	}
+}
+
+// Pause makes the Interpreter stop at the next line it encounters.
+// Pause is safe to call from any goroutine.
+func (d *Debugger) Pause() {
+
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	d.pause = true
+}
+
+func (d *Debugger) isBreakpoint(path string, line int) bool {
+
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	return d.breakpoints[path][line]
+}
+
+func (d *Debugger) takePause() bool {
+
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	p := d.pause
+	d.pause = false
+	return p
+}
+
+// BreakableLine returns the first line, at or after the given line,
+// on which a breakpoint in the module could be hit.
+func BreakableLine(mod *bc.Module, line int) (int, bool) {
+
+	result := -1
+	for _, tpl := range mod.Pool.Templates {
+		for _, ln := range tpl.LineNumberTable {
+			if ln.LineNum >= line && (result == -1 || ln.LineNum < result) {
+				result = ln.LineNum
+			}
+		}
+	}
+	return result, result != -1
+}
+
+// check is called before each opcode is executed, to see if
+// the Interpreter should be paused.
+func (d *Debugger) check(f *frame) {
+
+	// don't stop while the handler is inspecting values
+	if d.stopped {
+		return
+	}
+
+	// only stop at the beginning of a line
+	tpl := f.fn.Template()
+	line, ok := lineStart(tpl, f.ip)
+	if !ok {
+		return
+	}
+
+	depth := d.itp.frameStack.num()
+
+	var reason StopReason
+	switch {
+	case d.takePause():
+		reason = StopPause
+	case d.isBreakpoint(tpl.Module.Path, line):
+		reason = StopBreakpoint
+	case d.action == StepInto,
+		d.action == StepOver && d.isCaller(f, depth, len(d.callers)),
+		d.action == StepOut && d.isCaller(f, depth, len(d.callers)-1):
+		reason = StopStep
+	default:
+		return
+	}
+
+	d.stopped = true
+	d.action = d.onStop(d, reason)
+	d.stopped = false
+	d.callers = d.callers[:0]
+	for i := 0; i < depth; i++ {
+		d.callers = append(d.callers, d.itp.frameStack.get(i))
+	}
+}
+
+// isCaller returns whether a frame was on the call stack, at
+// or below the given depth, when the current action began.
+func (d *Debugger) isCaller(f *frame, depth int, maxDepth int) bool {
+	return depth <= maxDepth && d.callers[depth-1] == f
+}
+
+// lineStart returns whether the instruction pointer is at the beginning of a
+// sequence of opcodes for a line of source code.
+func lineStart(tpl *bc.FuncTemplate, ip int) (int, bool) {
+	prev := -1
+	for _, ln := range tpl.LineNumberTable {
+		if ln.Index == ip {
+			return ln.LineNum, ln.LineNum > 0 && ln.LineNum != prev
+		}
+		if ln.Index > ip {
+			break
+		}
+		prev = ln.LineNum
+	}
+	return 0, false
+}
+
+//---------------------------------------------------------------
+// Inspection
+//---------------------------------------------------------------
+
+// A StackFrame describes a frame in the call stack of a paused Interpreter.
+type StackFrame struct {
+	// Path is the path of the module that contains the frame's function
+	Path string
+	// Line and Col are the current position in the source code
+	Line int
+	Col  int
+
+	// Func is the function that the frame is executing
+	Func bc.Func
+
+	Locals   []*Variable
+	Captures []*Variable
+
+	// Stack is the frame's operand stack, from bottom to top
+	Stack []g.Value
+}
+
+// A Variable is a named value.
+type Variable struct {
+	Name  string
+	Value g.Value
+}
+
+// Frames returns the call stack of the Interpreter, starting with
+// the innermost frame.  Frames should only be called while the Interpreter
+// is paused.
+func (d *Debugger) Frames() []*StackFrame {
+
+	fs := d.itp.frameStack
+	frames := []*StackFrame{}
+
+	for i := fs.num() - 1; i >= 0; i-- {
+		f := fs.get(i)
+		tpl := f.fn.Template()
+
+		line, col := tpl.Position(f.ip)
+		frames = append(frames, &StackFrame{
+			Path:     tpl.Module.Path,
+			Line:     line,
+			Col:      col,
+			Func:     f.fn,
+			Locals:   namedRefs(tpl.LocalNames, f.locals),
+			Captures: namedRefs(tpl.CaptureNames, captures(f.fn)),
+			Stack:    g.CopyValues(f.stack),
+		})
+	}
+
+	return frames
+}
+
+// Globals returns the module-level variables of a module.
+func Globals(mod *bc.Module) []*Variable {
+	return namedRefs(mod.Pool.Templates[0].LocalNames, mod.Refs)
+}
+
+func captures(fn bc.Func) []*bc.Ref {
+	refs := make([]*bc.Ref, fn.Template().NumCaptures)
+	for i := range refs {
+		refs[i] = fn.GetCapture(i)
+	}
+	return refs
+}
+
+func namedRefs(names []string, refs []*bc.Ref) []*Variable {
+	vars := []*Variable{}
+	for i, ref := range refs {
+		if i < len(names) && names[i] != "" {
+			vars = append(vars, &Variable{names[i], ref.Val})
+		}
+	}
+	return vars
+}
+
+// Evaluate evaluates an expression in the scope of a stack frame.  The frame's
+// variables are visible to the expression, but they cannot be assigned to.
+// Evaluate should only be called while the Interpreter is paused.
+func (d *Debugger) Evaluate(sf *StackFrame, expr string) (g.Value, g.Error) {
+
+	defined := map[string]bool{}
+	globals := []*compiler.Global{}
+	refs := []*bc.Ref{}
+
+	// inner variables shadow outer ones
+	define := func(vars []*Variable) {
+		for i := len(vars) - 1; i >= 0; i-- {
+			v := vars[i]
+			if defined[v.Name] || v.Value == nil {
+				continue
+			}
+			defined[v.Name] = true
+			globals = append(globals, &compiler.Global{
+				Name:    v.Name,
+				IsConst: true,
+				Index:   len(refs),
+			})
+			refs = append(refs, bc.NewRef(v.Value))
+		}
+	}
+	define(sf.Locals)
+	define(sf.Captures)
+	define(Globals(sf.Func.Template().Module))
+
+	source := &scanner.Source{Name: "eval", Path: "<eval>", Code: expr}
+	mod, _, err := compiler.CompileSourceWithGlobals(source, d.itp.builtins, globals)
+	if err != nil {
+		return nil, g.Error(err)
+	}
+
+	val, es := d.itp.EvalModuleWithRefs(mod, refs)
+	if es != nil {
+		return nil, es
+	}
+	return val, nil
+}
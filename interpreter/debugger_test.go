@@ -0,0 +1,163 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package interpreter
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	g "github.com/mjarmy/golem-lang/core"
+)
+
+const debugCode = `let a = 1
+fn b(x) {
+	let y = x * 2
+	return y + a
+}
+let c = b(3)
+let d = fn() {
+	return c + 1
+}
+d()
+`
+
+// run the debug code, recording where the interpreter stops
+func debugRun(t *testing.T, bps []int, actions []Action) []string {
+
+	mod := compile(t, debugCode)
+
+	stops := []string{}
+	n := 0
+	d := NewDebugger(func(d *Debugger, reason StopReason) Action {
+		frames := d.Frames()
+		stops = append(stops, fmt.Sprintf("%s %d %d", reason, frames[0].Line, len(frames)))
+
+		action := Continue
+		if n < len(actions) {
+			action = actions[n]
+		}
+		n++
+		return action
+	})
+	d.SetBreakpoints("foo.glm", bps)
+
+	itp := NewInterpreter(builtins, nil, WithDebugger(d))
+	_, es := itp.EvalModule(mod)
+	tassert(t, es == nil)
+
+	return stops
+}
+
+func TestBreakpoints(t *testing.T) {
+
+	stops := debugRun(t, []int{3, 8}, nil)
+	tassert(t, reflect.DeepEqual(stops, []string{
+		"breakpoint 3 2",
+		"breakpoint 8 2",
+	}))
+
+	stops = debugRun(t, []int{1}, []Action{StepOver, StepOver, StepOver, StepOver})
+	tassert(t, reflect.DeepEqual(stops, []string{
+		"breakpoint 1 1",
+		"step 2 1",
+		"step 6 1",
+		"step 7 1",
+		"step 10 1",
+	}))
+
+	stops = debugRun(t, []int{6}, []Action{StepInto, StepInto, StepOut})
+	tassert(t, reflect.DeepEqual(stops, []string{
+		"breakpoint 6 1",
+		"step 3 2",
+		"step 4 2",
+		"step 7 1",
+	}))
+
+	// stepping over a return resumes in the caller
+	stops = debugRun(t, []int{3}, []Action{StepOver, StepOver})
+	tassert(t, reflect.DeepEqual(stops, []string{
+		"breakpoint 3 2",
+		"step 4 2",
+		"step 7 1",
+	}))
+}
+
+func TestFrames(t *testing.T) {
+
+	mod := compile(t, debugCode)
+
+	var frames []*StackFrame
+	d := NewDebugger(func(d *Debugger, reason StopReason) Action {
+		if frames == nil {
+			frames = d.Frames()
+		}
+		return Continue
+	})
+	d.SetBreakpoint("foo.glm", 4)
+	d.SetBreakpoint("foo.glm", 8)
+	d.ClearBreakpoint("foo.glm", 8)
+
+	itp := NewInterpreter(builtins, nil, WithDebugger(d))
+	_, es := itp.EvalModule(mod)
+	tassert(t, es == nil)
+
+	tassert(t, len(frames) == 2)
+	tassert(t, frames[0].Path == "foo.glm")
+	tassert(t, frames[0].Line == 4)
+	tassert(t, reflect.DeepEqual(frames[0].Locals, []*Variable{
+		{"x", g.NewInt(3)},
+		{"y", g.NewInt(6)},
+	}))
+	tassert(t, reflect.DeepEqual(frames[0].Captures, []*Variable{
+		{"a", g.NewInt(1)},
+	}))
+	tassert(t, frames[1].Line == 6)
+
+	tassert(t, reflect.DeepEqual(Globals(mod), []*Variable{
+		{"b", Globals(mod)[0].Value},
+		{"a", g.NewInt(1)},
+		{"c", g.NewInt(7)},
+		{"d", Globals(mod)[3].Value},
+	}))
+
+	line, ok := BreakableLine(mod, 5)
+	tassert(t, ok && line == 6)
+	_, ok = BreakableLine(mod, 11)
+	tassert(t, !ok)
+}
+
+func TestEvaluate(t *testing.T) {
+
+	mod := compile(t, debugCode)
+
+	results := []string{}
+	d := NewDebugger(func(d *Debugger, reason StopReason) Action {
+		frame := d.Frames()[0]
+		for _, expr := range []string{"x + y + a", "[c, b(1)]", "y = 2", "z"} {
+			val, err := d.Evaluate(frame, expr)
+			if err != nil {
+				results = append(results, err.Error())
+			} else {
+				s, err := val.ToStr(d.Interpreter())
+				tassert(t, err == nil)
+				results = append(results, s.String())
+			}
+		}
+		return Continue
+	})
+	d.SetBreakpoint("foo.glm", 4)
+
+	itp := NewInterpreter(builtins, nil, WithDebugger(d))
+	_, es := itp.EvalModule(mod)
+	tassert(t, es == nil)
+
+	tassert(t, reflect.DeepEqual(results, []string{
+		"10",
+		"[ null, 3 ]",
+		"Symbol 'y' is constant at <eval>:1:1",
+		"Symbol 'z' is not defined at <eval>:1:1",
+	}))
+}
@@ -17,43 +17,201 @@ type (
 		g.Struct
 		String() string
 		Error() string
+		Kind() string
 		StackTrace() []string
+		Frames() []TraceFrame
 	}
 
 	errorStruct struct {
 		g.Struct
-		err        g.Error
-		stackTrace []string
+		err    g.Error
+		frames []TraceFrame
+
+		// the number of frames that were omitted from the
+		// middle of the stack trace
+		omitted int
 	}
 )
 
-func newErrorStruct(err g.Error, stackTrace []string) ErrorStruct {
+// A TraceFrame is a frame in the stack trace of an error.
+type TraceFrame struct {
+	File string
+	Line int
+	Col  int
+
+	// Func is the name of the named function or struct method that
+	// was executing.  It is empty for module-level code.
+	Func string
+
+	// Elided is the number of frames that were discarded, because their
+	// function ended with a tail call.  The discarded frames would have
+	// been between this frame and the next one in the stack trace.
+	Elided int
+}
+
+func (tf TraceFrame) String() string {
+	if tf.Func == "" {
+		return fmt.Sprintf("at %s:%d:%d", tf.File, tf.Line, tf.Col)
+	}
+	return fmt.Sprintf("at %s (%s:%d:%d)", tf.Func, tf.File, tf.Line, tf.Col)
+}
+
+// toStruct turns a TraceFrame into a Struct, so that it can be
+// used by Golem code.
+func (tf TraceFrame) toStruct() g.Struct {
 
-	// make List-of-Str
-	vals := make([]g.Value, len(stackTrace))
-	for i, s := range stackTrace {
-		vals[i] = g.MustStr(s)
+	var fn g.Value = g.Null
+	if tf.Func != "" {
+		fn = g.MustStr(tf.Func)
+	}
+
+	stc, err := g.NewFrozenStruct(map[string]g.Field{
+		"file": g.NewReadonlyField(g.MustStr(tf.File)),
+		"line": g.NewReadonlyField(g.NewInt(int64(tf.Line))),
+		"col":  g.NewReadonlyField(g.NewInt(int64(tf.Col))),
+		"func": g.NewReadonlyField(fn),
+
+		"elided": g.NewReadonlyField(g.NewInt(int64(tf.Elided))),
+	})
+	g.Assert(err == nil)
+	return stc
+}
+
+// A thrownError is the Error that is created when a value is thrown.
+// The value is kept so that it can be made available in the resulting
+// ErrorStruct, along with the fields of the value if it is a Struct.
+type thrownError struct {
+	err    g.Error
+	value  g.Value
+	fields map[string]g.Value
+}
+
+func (e *thrownError) Error() string   { return e.err.Error() }
+func (e *thrownError) Kind() string    { return g.ErrorKind(e.err) }
+func (e *thrownError) Message() string { return g.ErrorMessage(e.err) }
+
+// newThrownValue creates an Error from a thrown value that is not a Struct.
+// The kind and message are parsed from the value's string representation.
+func newThrownValue(ev g.Eval, val g.Value) (g.Error, g.Error) {
+
+	s, err := val.ToStr(ev)
+	if err != nil {
+		return nil, err
+	}
+
+	return &thrownError{fmt.Errorf("%s", s.String()), val, nil}, nil
+}
+
+// newThrownError creates an Error from a thrown Struct.  The kind
+// of the Error is the Struct's 'kind' field, if there is one,
+// and the message is its 'msg' field.
+func newThrownError(ev g.Eval, stc g.Struct) (g.Error, g.Error) {
+
+	names, err := stc.FieldNames()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]g.Value, len(names))
+	for _, name := range names {
+		fields[name], err = stc.GetField(ev, name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	kind := "Error"
+	if val, ok := fields["kind"]; ok {
+		s, ok := val.(g.Str)
+		if !ok {
+			return nil, g.TypeMismatch(g.StrType, val.Type())
+		}
+		kind = s.String()
+	}
+
+	msg := ""
+	if val, ok := fields["msg"]; ok {
+		s, err := val.ToStr(ev)
+		if err != nil {
+			return nil, err
+		}
+		msg = s.String()
+	}
+
+	return &thrownError{g.NewError(kind, msg), stc, fields}, nil
+}
+
+func newErrorStruct(err g.Error, frames []TraceFrame) ErrorStruct {
+	return newTruncatedErrorStruct(err, frames, 0)
+}
+
+// newTruncatedErrorStruct creates an ErrorStruct whose stack trace has had
+// some frames omitted from the middle.
+func newTruncatedErrorStruct(err g.Error, frames []TraceFrame, omitted int) ErrorStruct {
+
+	// make List-of-Struct
+	vals := make([]g.Value, len(frames))
+	for i, f := range frames {
+		vals[i] = f.toStruct()
 	}
 	list, e := g.NewList(vals).Freeze(nil)
 	g.Assert(e == nil)
 
-	stc, e := g.NewFrozenStruct(
-		map[string]g.Field{
-			"error":      g.NewReadonlyField(g.MustStr(err.Error())),
-			"stackTrace": g.NewReadonlyField(list),
-			// TODO $toStr for convenience when printing stack trace
-		})
+	fields := map[string]g.Field{
+		"kind":       g.NewReadonlyField(g.MustStr(g.ErrorKind(err))),
+		"msg":        g.NewReadonlyField(g.MustStr(g.ErrorMessage(err))),
+		"error":      g.NewReadonlyField(g.MustStr(err.Error())),
+		"stackTrace": g.NewReadonlyField(list),
+		// TODO $toStr for convenience when printing stack trace
+	}
+
+	// Include the thrown value, and the fields of a thrown Struct.
+	// The error fields take precedence.
+	if te, ok := err.(*thrownError); ok {
+		fields["value"] = g.NewReadonlyField(te.value)
+		for name, val := range te.fields {
+			if _, has := fields[name]; !has {
+				fields[name] = g.NewReadonlyField(val)
+			}
+		}
+	}
+
+	stc, e := g.NewFrozenStruct(fields)
 	g.Assert(e == nil)
 
-	return &errorStruct{stc, err, stackTrace}
+	return &errorStruct{stc, err, frames, omitted}
 }
 
 func (e *errorStruct) Error() string {
 	return e.err.Error()
 }
 
+func (e *errorStruct) Kind() string {
+	return g.ErrorKind(e.err)
+}
+
+// StackTrace returns the lines of the stack trace, starting with the innermost frame.
 func (e *errorStruct) StackTrace() []string {
-	return e.stackTrace
+
+	lines := []string{}
+	for i, f := range e.frames {
+		if e.omitted > 0 && i == truncatedTraceEdge {
+			lines = append(lines, fmt.Sprintf("    ... %d more", e.omitted))
+		}
+		lines = append(lines, "    "+f.String())
+		if f.Elided == 1 {
+			lines = append(lines, "    ... 1 tail call elided")
+		} else if f.Elided > 1 {
+			lines = append(lines, fmt.Sprintf("    ... %d tail calls elided", f.Elided))
+		}
+	}
+	return lines
+}
+
+// Frames returns the frames of the stack trace, starting with the innermost frame.
+// Frames that were omitted from a truncated stack trace are not included.
+func (e *errorStruct) Frames() []TraceFrame {
+	return e.frames
 }
 
 func (e *errorStruct) String() string {
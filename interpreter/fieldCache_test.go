@@ -0,0 +1,259 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/mjarmy/golem-lang/compiler"
+	g "github.com/mjarmy/golem-lang/core"
+	bc "github.com/mjarmy/golem-lang/core/bytecode"
+	"github.com/mjarmy/golem-lang/scanner"
+)
+
+// withoutCaches replaces a Module's Pool with one that has no Shapes,
+// so that none of the Module's field lookups are cached.
+func withoutCaches(mod *bc.Module) *bc.Module {
+
+	mod.Pool = &bc.Pool{
+		Constants:  mod.Pool.Constants,
+		StructDefs: mod.Pool.StructDefs,
+		Templates:  mod.Pool.Templates,
+	}
+	return mod
+}
+
+func TestFieldCache(t *testing.T) {
+
+	code := `
+fn point(x, y) {
+    return struct {
+        x: x,
+        y: y,
+        sum: fn() { return this.x + this.y; }
+    }
+}
+
+fn getX(s) { return s.x; }
+fn sum(s) { return s.sum(); }
+
+let a = point(1, 2)
+let b = point(3, 4)
+let c = struct { y: 5, x: 6, sum: || => 7 }
+let d = merge(struct { z: 8 }, a)
+let e = struct { x: prop { || => 9 }, sum: prop { || => || => 10 } }
+let f = [1, 2, 3]
+
+assert(getX(a) == 1)
+assert(getX(b) == 3)
+assert(getX(c) == 6)
+assert(getX(d) == 1)
+assert(getX(e) == 9)
+assert(getX(a) == 1)
+
+assert(sum(a) == 3)
+assert(sum(b) == 7)
+assert(sum(c) == 7)
+assert(sum(d) == 3)
+assert(sum(e) == 10)
+assert(sum(b) == 7)
+
+try {
+    getX(f)
+    assert(false)
+} catch e {
+    assert(e.kind == 'NoSuchField')
+}
+assert(getX(a) == 1)
+
+a.x = 11
+assert(getX(a) == 11)
+assert(sum(a) == 13)
+`
+	blt := []*g.Builtin{
+		{"assert", g.BuiltinAssert},
+		{"merge", g.BuiltinMerge},
+	}
+
+	source := &scanner.Source{Name: "foo", Path: "foo.glm", Code: code}
+	mod, err := compiler.CompileSource(source, blt)
+	tassert(t, err == nil)
+
+	_, es := NewInterpreter(blt, nil).EvalModule(mod)
+	tassert(t, es == nil)
+
+	// the lookups of 'x' in getX() have been cached, for each of
+	// the three struct definitions that have an 'x'
+	var getX *bc.FuncTemplate
+	for _, tpl := range mod.Pool.Templates {
+		if tpl.Name == "getX" {
+			getX = tpl
+		}
+	}
+	tassert(t, len(getX.FieldKeys) == 1)
+	cached := 0
+	for _, fc := range getX.FieldCaches(0) {
+		tassert(t, fc.Shape.Names()[fc.Index] == "x")
+		cached++
+	}
+	tassert(t, cached == 3)
+
+	// a megamorphic lookup stops updating its cache once the cache is full
+	mod = compile(t, `
+fn getX(s) { return s.x; }
+let structs = [
+    struct { x: 0 },
+    struct { x: 1, a: 0 },
+    struct { x: 2, b: 0 },
+    struct { x: 3, c: 0 },
+    struct { x: 4, d: 0 },
+    struct { x: 5, e: 0 }
+]
+for i in range(0, 3) {
+    for s in structs {
+        assert(getX(s) == s.x)
+    }
+}
+`)
+	_, es = NewInterpreter(builtins, nil).EvalModule(mod)
+	tassert(t, es == nil)
+	for _, tpl := range mod.Pool.Templates {
+		if tpl.Name == "getX" {
+			caches := tpl.FieldCaches(0)
+			tassert(t, len(caches) == bc.MaxFieldCaches)
+			for i, fc := range caches {
+				tassert(t, fc.Shape == mod.Pool.Shape(i))
+			}
+		}
+	}
+
+	// the same code works without any caches
+	mod = withoutCaches(compile(t, `
+let a = struct { x: 1, f: fn() { return this.x; } }
+assert(a.x == 1)
+assert(a.f() == 1)
+`))
+	_, es = NewInterpreter(builtins, nil).EvalModule(mod)
+	tassert(t, es == nil)
+}
+
+//--------------------------------------------------------------
+// benchmarks
+//--------------------------------------------------------------
+
+// benchmarkFields runs some code with and without the inline caches.
+func benchmarkFields(b *testing.B, code string) {
+
+	run := func(b *testing.B, cached bool) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			mod := compile(b, code)
+			if !cached {
+				mod = withoutCaches(mod)
+			}
+			b.StartTimer()
+
+			if _, es := NewInterpreter(builtins, nil).EvalModule(mod); es != nil {
+				b.Fatal(es)
+			}
+		}
+	}
+
+	b.Run("cached", func(b *testing.B) { run(b, true) })
+	b.Run("uncached", func(b *testing.B) { run(b, false) })
+}
+
+func BenchmarkGetField(b *testing.B) {
+	benchmarkFields(b, `
+let s = struct { a: 1, b: 2, c: 3, d: 4, e: 5, f: 6 }
+let n = 0
+let i = 0
+while i < 10000 {
+    n = n + s.a + s.c + s.f
+    i++
+}
+assert(n == 100000)
+`)
+}
+
+func BenchmarkInvokeField(b *testing.B) {
+	benchmarkFields(b, `
+let counter = struct {
+    count: 0,
+    incr: fn() { this.count++; },
+    get: fn() { return this.count; }
+}
+let i = 0
+while i < 10000 {
+    counter.incr()
+    i++
+}
+assert(counter.get() == 10000)
+`)
+}
+
+func BenchmarkMethods(b *testing.B) {
+	benchmarkFields(b, `
+fn vector(x, y) {
+    return struct {
+        x: x,
+        y: y,
+        plus: fn(v) { return vector(this.x + v.x, this.y + v.y); },
+        dot: fn(v) { return this.x * v.x + this.y * v.y; }
+    }
+}
+let v = vector(0, 0)
+let u = vector(1, 2)
+let i = 0
+while i < 2000 {
+    v = v.plus(u)
+    i++
+}
+assert(v.dot(u) == 10000)
+`)
+}
+
+func BenchmarkPolymorphicField(b *testing.B) {
+	benchmarkFields(b, `
+let shapes = [
+    struct { name: 'a', area: || => 1 },
+    struct { area: || => 2, name: 'b' },
+    struct { name: 'c', sides: 3, area: || => 3 }
+]
+let n = 0
+let i = 0
+while i < 3000 {
+    for s in shapes {
+        n = n + s.area()
+    }
+    i++
+}
+assert(n == 18000)
+`)
+}
+
+func BenchmarkMegamorphicField(b *testing.B) {
+	benchmarkFields(b, `
+let shapes = [
+    struct { a: 0, x: 1 },
+    struct { b: 0, x: 1 },
+    struct { c: 0, x: 1 },
+    struct { d: 0, x: 1 },
+    struct { e: 0, x: 1 },
+    struct { f: 0, x: 1 },
+    struct { g: 0, x: 1 },
+    struct { h: 0, x: 1 }
+]
+let n = 0
+let i = 0
+while i < 1000 {
+    for s in shapes {
+        n = n + s.x
+    }
+    i++
+}
+assert(n == 8000)
+`)
+}
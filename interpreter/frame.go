@@ -18,6 +18,7 @@ type frame struct {
 
 	stack    []g.Value
 	handlers []bc.ErrorHandler
+	defers   []*deferred
 	ip       int // instruction pointer
 
 	// isBase specifies whether this is the base frame
@@ -27,6 +28,14 @@ type frame struct {
 	// isHandlingError specifies whether this frame is
 	// currently handling and error.
 	isHandlingError bool
+
+	// isSuspended specifies whether this frame belongs to a
+	// generator, and has been suspended by a 'yield'.
+	isSuspended bool
+
+	// tailCalls is the number of times that this frame has been
+	// reused for a tail call.
+	tailCalls int
 }
 
 func newFrame(fn bc.Func, locals []*bc.Ref, isBase bool) *frame {
@@ -40,10 +49,13 @@ func newFrame(fn bc.Func, locals []*bc.Ref, isBase bool) *frame {
 
 		stack:    make([]g.Value, 0, 10),
 		handlers: []bc.ErrorHandler{},
+		defers:   nil,
 		ip:       0,
 
 		isBase:          isBase,
 		isHandlingError: false,
+		isSuspended:     false,
+		tailCalls:       0,
 	}
 }
 
@@ -61,3 +73,16 @@ func (f *frame) popHandler() bc.ErrorHandler {
 	f.handlers = f.handlers[:n]
 	return h
 }
+
+// A deferred is an invocation that will be run when its frame is exited.
+type deferred struct {
+	fn     g.Func
+	params []g.Value
+}
+
+func (f *frame) popDeferred() *deferred {
+	n := len(f.defers) - 1
+	d := f.defers[n]
+	f.defers = f.defers[:n]
+	return d
+}
@@ -5,7 +5,6 @@
 package interpreter
 
 import (
-	"fmt"
 	bc "github.com/mjarmy/golem-lang/core/bytecode"
 )
 
@@ -58,16 +57,60 @@ func (fs *frameStack) popErrorHandler() (bc.ErrorHandler, bool) {
 	return bc.ErrorHandler{}, false
 }
 
-func (fs *frameStack) stackTrace() []string {
+// popToBase pops all of the frames of the current Eval(),
+// including the base frame.
+func (fs *frameStack) popToBase() {
+	for !fs.peek().isBase {
+		fs.pop()
+	}
+	fs.pop()
+}
+
+func (fs *frameStack) stackTrace() []TraceFrame {
 
-	stack := []string{}
+	stack := []TraceFrame{}
 
 	for i := fs.num() - 1; i >= 0; i-- {
-		f := fs.get(i)
-		tpl := f.fn.Template()
-		lineNum := tpl.LineNumber(f.ip)
-		stack = append(stack, fmt.Sprintf("    at %s:%d", tpl.Module.Path, lineNum))
+		stack = append(stack, fs.traceFrame(i))
 	}
 
 	return stack
 }
+
+// the number of frames that are kept at each end of a truncated stack trace
+const truncatedTraceEdge = 10
+
+// truncatedStackTrace is like stackTrace, except that when there are a lot of
+// frames, only the innermost and outermost frames are included.  The number of
+// frames that were omitted from the middle of the trace is also returned.
+func (fs *frameStack) truncatedStackTrace() ([]TraceFrame, int) {
+
+	n := fs.num()
+	if n <= truncatedTraceEdge*2 {
+		return fs.stackTrace(), 0
+	}
+
+	stack := []TraceFrame{}
+	for i := n - 1; i >= n-truncatedTraceEdge; i-- {
+		stack = append(stack, fs.traceFrame(i))
+	}
+	for i := truncatedTraceEdge - 1; i >= 0; i-- {
+		stack = append(stack, fs.traceFrame(i))
+	}
+
+	return stack, n - truncatedTraceEdge*2
+}
+
+func (fs *frameStack) traceFrame(idx int) TraceFrame {
+	f := fs.get(idx)
+	tpl := f.fn.Template()
+	line, col := tpl.Position(f.ip)
+	return TraceFrame{
+		File: tpl.Module.Path,
+		Line: line,
+		Col:  col,
+		Func: tpl.Name,
+
+		Elided: f.tailCalls,
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package interpreter
+
+import (
+	g "github.com/mjarmy/golem-lang/core"
+	bc "github.com/mjarmy/golem-lang/core/bytecode"
+)
+
+// A generator is the Iterator that is produced by invoking a Func which
+// contains a 'yield' statement.  Each call to IterNext() resumes the
+// generator's suspended frame, which then runs until it either yields
+// another value, or returns.
+//
+// A generator is also an Iterable, so that it can be used in a 'for'
+// statement, or passed to stream().  Since the generator's frame can only be
+// run once, the Iterator of a generator is always the generator itself.
+//
+// The deferred invocations and 'finally' clauses of a generator are run when
+// its frame returns or throws an error.  If the generator is abandoned
+// before then, e.g. by breaking out of a 'for' loop, the frame is simply
+// discarded, and they are never run.
+type generator struct {
+	g.Struct
+	f       *frame
+	val     g.Value
+	running bool
+	done    bool
+}
+
+func newGenerator(ev g.Eval, fn bc.Func, locals []*bc.Ref) g.Iterator {
+
+	gen := &generator{
+		Struct:  g.NewIteratorStruct(),
+		f:       newFrame(fn, locals, true),
+		val:     nil,
+		running: false,
+		done:    false,
+	}
+	g.InitIteratorFields(ev, gen)
+
+	return gen
+}
+
+func (gen *generator) NewIterator(ev g.Eval) (g.Iterator, g.Error) {
+	return gen, nil
+}
+
+func (gen *generator) IterNext(ev g.Eval) (g.Bool, g.Error) {
+
+	if gen.done {
+		return g.False, nil
+	}
+	if gen.running {
+		return nil, g.GeneratorRunning()
+	}
+
+	itp, ok := ev.(*Interpreter)
+	g.Assert(ok)
+
+	// resume the frame
+	if es := itp.checkFrameDepth(); es != nil {
+		return nil, es
+	}
+	gen.running = true
+	itp.frameStack.push(gen.f)
+	val, es := itp.eval()
+	gen.running = false
+
+	// an error was thrown
+	if es != nil {
+		gen.val = nil
+		gen.done = true
+		return nil, es
+	}
+
+	// the frame yielded a value
+	if gen.f.isSuspended {
+		gen.f.isSuspended = false
+		gen.val = val
+		return g.True, nil
+	}
+
+	// the frame returned
+	gen.val = nil
+	gen.done = true
+	return g.False, nil
+}
+
+func (gen *generator) IterGet(ev g.Eval) (g.Value, g.Error) {
+
+	if gen.val == nil {
+		return nil, g.NoSuchElement()
+	}
+	return gen.val, nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package interpreter
+
+import (
+	g "github.com/mjarmy/golem-lang/core"
+)
+
+// A goroutine keeps track of the outcome of a function
+// that was invoked via a 'go' expression.
+type goroutine struct {
+	onError func(ErrorStruct)
+	done    chan struct{}
+	result  g.Value
+	es      ErrorStruct
+}
+
+func newGoroutine(onError func(ErrorStruct)) *goroutine {
+	return &goroutine{
+		onError: onError,
+		done:    make(chan struct{}),
+		result:  nil,
+		es:      nil,
+	}
+}
+
+// finish records the outcome of the goroutine.  If the goroutine
+// failed, the error is also passed along to the error handler, if there is one.
+// Only detached goroutines have an error handler.
+func (gr *goroutine) finish(result g.Value, es ErrorStruct) {
+
+	gr.result = result
+	gr.es = es
+	close(gr.done)
+
+	if es != nil && gr.onError != nil {
+		gr.onError(es)
+	}
+}
+
+// handle creates the Struct that is the result of a 'go' expression.
+func (gr *goroutine) handle() g.Struct {
+	stc, err := g.NewMethodStruct(gr, goroutineMethods)
+	g.Assert(err == nil)
+	return stc
+}
+
+var goroutineMethods = map[string]g.Method{
+
+	// join waits for the goroutine to finish, and then either returns its
+	// result, or throws the error that it failed with.
+	"join": g.NewNullaryMethod(
+		func(self interface{}, ev g.Eval) (g.Value, g.Error) {
+			gr := self.(*goroutine)
+			<-gr.done
+			if gr.es != nil {
+				return nil, gr.es
+			}
+			return gr.result, nil
+		}),
+}
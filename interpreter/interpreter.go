@@ -20,14 +20,15 @@ import (
 func EvalCode(
 	code string,
 	builtins []*g.Builtin,
-	importer Importer) (g.Value, g.Error) {
+	importer Importer,
+	options ...Option) (g.Value, g.Error) {
 
 	mod, err := CompileCode(code, builtins)
 	if err != nil {
 		return nil, err
 	}
 
-	itp := NewInterpreter(builtins, importer)
+	itp := NewInterpreter(builtins, importer, options...)
 	return itp.EvalModule(mod)
 }
 
@@ -51,27 +52,64 @@ type Interpreter struct {
 	builtins   []*g.Builtin
 	importer   Importer
 	frameStack *frameStack
+
+	goErrorHandler func(ErrorStruct)
+	budget         *budget
+	debugger       *Debugger
+
+	// the number of opcodes that have not yet been counted by the budget
+	unspent int64
 }
 
 // NewInterpreter creates a new Interpreter.  If the importer is nil,
 // then no modules can be imported.
-func NewInterpreter(builtins []*g.Builtin, importer Importer) *Interpreter {
+func NewInterpreter(builtins []*g.Builtin, importer Importer, options ...Option) *Interpreter {
 
-	return &Interpreter{
+	itp := &Interpreter{
 		builtins:   builtins,
 		importer:   importer,
 		frameStack: newFrameStack(),
+
+		goErrorHandler: nil,
+		budget:         newBudget(),
+		debugger:       nil,
+		unspent:        0,
 	}
+
+	for _, opt := range options {
+		opt(itp)
+	}
+	return itp
+}
+
+// SetGoErrorHandler sets a function that will be called whenever a goroutine
+// that was started via a 'go' statement fails with an error that it did not catch.
+// Goroutines whose handle is kept, e.g. 'let h = go f()', do not report their errors,
+// since the error is thrown again by 'h.join()'.
+// The handler is inherited by any goroutines that are started by those
+// goroutines in turn.  Note that the handler is called from within the failed
+// goroutine, so it must be safe for concurrent use.
+func (itp *Interpreter) SetGoErrorHandler(handler func(ErrorStruct)) {
+	itp.goErrorHandler = handler
 }
 
 // EvalModule evaluates a bytecode.Module by calling its wrapper "init" Func.
 func (itp *Interpreter) EvalModule(mod *bc.Module) (g.Value, ErrorStruct) {
+	return itp.EvalModuleWithRefs(mod, nil)
+}
+
+// EvalModuleWithRefs is like EvalModule, except that the given Refs are
+// used as the first of the module's Refs.  This allows a module
+// that was compiled via compiler.CompileSourceWithGlobals to share
+// the values of its globals with other modules.
+func (itp *Interpreter) EvalModuleWithRefs(mod *bc.Module, refs []*bc.Ref) (g.Value, ErrorStruct) {
 
 	// the 'init' function is always the first template in the pool
 	initTpl := mod.Pool.Templates[0]
 
 	// create empty locals
 	mod.Refs = newLocals(initTpl.NumLocals, nil)
+	copy(mod.Refs, refs)
 
 	// make init function from template
 	initFn := bc.NewBytecodeFunc(initTpl)
@@ -110,6 +148,13 @@ func (itp *Interpreter) Eval(fn g.Func, params []g.Value) (g.Value, g.Error) {
 func (itp *Interpreter) EvalBytecode(fn bc.Func, params []g.Value) (g.Value, ErrorStruct) {
 
 	locals := newLocals(fn.Template().NumLocals, params)
+	if fn.Template().IsGenerator {
+		return newGenerator(itp, fn, locals), nil
+	}
+
+	if es := itp.checkFrameDepth(); es != nil {
+		return nil, es
+	}
 	itp.frameStack.push(newFrame(fn, locals, true))
 	val, es := itp.eval()
 	if es != nil {
@@ -170,6 +215,19 @@ func (itp *Interpreter) eval() (g.Value, ErrorStruct) {
 // deal with an error that was generated by a bytecode operation
 func (itp *Interpreter) handleError(res g.Value, es ErrorStruct) (g.Value, ErrorStruct) {
 
+	//-------------------------------------------
+	// fatal errors unwind straight back to the base frame
+
+	if isFatal(es) {
+		itp.frameStack.popToBase()
+		return res, es
+	}
+
+	//-------------------------------------------
+	// run the deferred invocations of any frames that are about to be unwound
+
+	es = itp.runUnwoundDefers(es)
+
 	//-------------------------------------------
 	// find an error handler
 
@@ -263,18 +321,68 @@ func (itp *Interpreter) runTryClause(tc bc.TryClause) *response {
 		res, err := itp.advance()
 		g.Assert(res == nil)
 		if err != nil {
-			es := newErrorStruct(err, itp.frameStack.stackTrace())
+			es, ok := err.(ErrorStruct)
+			if !ok {
+				es = newErrorStruct(err, itp.frameStack.stackTrace())
+			}
 			return &response{nil, -1, es}
 		}
 	}
 
 }
 
+// Run the deferred invocations of a frame, in last-in-first-out order.
+// If any of the invocations fails, the most recent error is returned.
+func (itp *Interpreter) runDefers(f *frame) ErrorStruct {
+
+	var es ErrorStruct
+	for len(f.defers) > 0 {
+		d := f.popDeferred()
+
+		_, err := itp.Eval(d.fn, d.params)
+		if err != nil {
+			var ok bool
+			es, ok = err.(ErrorStruct)
+			if !ok {
+				es = newErrorStruct(err, itp.frameStack.stackTrace())
+			}
+		}
+	}
+	return es
+}
+
+// Run the deferred invocations of every frame that will be discarded while
+// searching for an error handler.  If a deferred invocation fails, its error
+// replaces the one that is currently being handled.
+func (itp *Interpreter) runUnwoundDefers(es ErrorStruct) ErrorStruct {
+
+	fs := itp.frameStack
+	for i := fs.num() - 1; i >= 0; i-- {
+		f := fs.get(i)
+		if f.numHandlers() > 0 {
+			break
+		}
+
+		if e := itp.runDefers(f); e != nil {
+			es = e
+		}
+
+		if f.isBase {
+			break
+		}
+	}
+	return es
+}
+
 // advance the interpreter forwards by one opcode.
 func (itp *Interpreter) advance() (g.Value, g.Error) {
 
 	f := itp.frameStack.peek()
 
+	if itp.debugger != nil {
+		itp.debugger.check(f)
+	}
+
 	//if debugInterpreter {
 	//	fmt.Printf("=========================================\n")
 	//	fmt.Printf("ip: %s\n", bc.FmtBytecode(f.btc, f.ip))
@@ -282,6 +390,10 @@ func (itp *Interpreter) advance() (g.Value, g.Error) {
 	//	itp.frameStack.debug()
 	//}
 
+	if err := itp.spend(); err != nil {
+		return nil, err
+	}
+
 	op := ops[f.btc[f.ip]]
 	return op(itp, f)
 }
@@ -10,6 +10,7 @@ import (
 
 	"github.com/mjarmy/golem-lang/compiler"
 	g "github.com/mjarmy/golem-lang/core"
+	bc "github.com/mjarmy/golem-lang/core/bytecode"
 	"github.com/mjarmy/golem-lang/scanner"
 )
 
@@ -23,6 +24,21 @@ func tassert(t *testing.T, flag bool) {
 var builtins = []*g.Builtin{
 	{"assert", g.BuiltinAssert},
 	{"println", g.BuiltinPrintln},
+	{"chan", g.BuiltinChan},
+	{"str", g.BuiltinStr},
+	{"range", g.BuiltinRange},
+	{"stream", g.BuiltinStream},
+}
+
+// compile compiles some code with the test builtins
+func compile(t testing.TB, code string) *bc.Module {
+
+	source := &scanner.Source{Name: "foo", Path: "foo.glm", Code: code}
+	mod, err := compiler.CompileSource(source, builtins)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mod
 }
 
 func ok(t *testing.T, val interface{}, err g.Error, expect interface{}) {
@@ -174,15 +190,184 @@ func TestImport(t *testing.T) {
 			g.NewInt(1), g.NewInt(2), g.NewInt(3)})))
 }
 
+func TestGlobals(t *testing.T) {
+
+	compile := func(code string, globals []*compiler.Global) (*bc.Module, []*compiler.Global) {
+		source := &scanner.Source{Name: "foo", Path: "foo.glm", Code: code}
+		mod, globals, err := compiler.CompileSourceWithGlobals(source, builtins, globals)
+		tassert(t, err == nil)
+		return mod, globals
+	}
+
+	itp := NewInterpreter(builtins, nil)
+
+	mod, globals := compile(`
+let a = 1
+const b = 2
+fn c() { return a + b; }
+if true {
+	let d = 3
+}
+let e = 4
+`, nil)
+	_, es := itp.EvalModule(mod)
+	tassert(t, es == nil)
+
+	tassert(t, reflect.DeepEqual(globals, []*compiler.Global{
+		{"a", false, 1},
+		{"b", true, 2},
+		{"c", true, 0},
+		{"e", false, 4},
+	}))
+
+	refs := []*bc.Ref{}
+	for i, gl := range globals {
+		refs = append(refs, mod.Refs[gl.Index])
+		gl.Index = i
+	}
+
+	mod, globals = compile(`
+a = 10
+let f = c() + e
+f
+`, globals)
+	val, es := itp.EvalModuleWithRefs(mod, refs)
+	tassert(t, es == nil)
+	tassert(t, reflect.DeepEqual(val, g.NewInt(16)))
+	tassert(t, len(globals) == 5)
+	tassert(t, reflect.DeepEqual(refs[0].Val, g.NewInt(10)))
+
+	source := &scanner.Source{Name: "foo", Path: "foo.glm", Code: "b = 3"}
+	_, _, err := compiler.CompileSourceWithGlobals(source, builtins, globals)
+	tassert(t, err.Error() == "Symbol 'b' is constant at foo.glm:1:1")
+}
+
+func TestGoErrorHandler(t *testing.T) {
+
+	source := &scanner.Source{
+		Name: "foo",
+		Path: "foo.glm",
+		Code: `
+		fn a() {
+			1/0
+		}
+		fn b() {
+			throw 'FooError: foo'
+		}
+		let h = go a()
+		try {
+			h.join()
+		} catch e {
+			go b()
+			return e
+		}
+		`}
+	mod, err := compiler.CompileSource(source, builtins)
+	tassert(t, err == nil)
+
+	errors := make(chan ErrorStruct, 2)
+	itp := NewInterpreter(builtins, nil)
+	itp.SetGoErrorHandler(func(es ErrorStruct) {
+		errors <- es
+	})
+
+	val, err := itp.EvalModule(mod)
+	tassert(t, err == nil)
+
+	// the error from the joined goroutine is caught, and not reported
+	tassert(t, reflect.DeepEqual(val, newErrorStruct(
+		g.DivideByZero(),
+		[]TraceFrame{
+			{"foo.glm", 3, 5, "a", 0}})))
+
+	// the error from the detached goroutine is reported
+	es := <-errors
+	tassert(t, es.Kind() == "FooError")
+	tassert(t, es.Frames()[0].Func == "b")
+	tassert(t, len(errors) == 0)
+}
+
+func TestThrowValue(t *testing.T) {
+
+	catch := func(code string) ErrorStruct {
+		mod := compile(t, code)
+
+		val, es := NewInterpreter(builtins, nil).EvalModule(mod)
+		tassert(t, es == nil)
+		return val.(ErrorStruct)
+	}
+
+	value := func(es ErrorStruct) g.Value {
+		val, err := es.GetField(nil, "value")
+		tassert(t, err == nil)
+		return val
+	}
+
+	// the original value is available, with its own type
+	es := catch("try { throw [1, 2]; } catch e { return e; }")
+	tassert(t, es.Kind() == "Error")
+	tassert(t, es.Error() == "[ 1, 2 ]")
+	ls, ok := value(es).(g.List)
+	tassert(t, ok)
+	tassert(t, reflect.DeepEqual(ls.Values(), []g.Value{g.NewInt(1), g.NewInt(2)}))
+
+	es = catch("try { throw 42; } catch e { return e; }")
+	tassert(t, es.Error() == "42")
+	tassert(t, reflect.DeepEqual(value(es), g.NewInt(42)))
+
+	es = catch("try { throw 'FooError: foo'; } catch e { return e; }")
+	tassert(t, es.Kind() == "FooError")
+	tassert(t, reflect.DeepEqual(value(es), g.MustStr("FooError: foo")))
+
+	// errors that were not thrown do not have a value
+	es = catch("try { 1/0; } catch e { return e; }")
+	_, err := es.GetField(nil, "value")
+	tassert(t, err != nil)
+}
+
+func TestOperatorHooks(t *testing.T) {
+
+	code := `
+let v = struct {
+    x: 10,
+    $add: |n| => this.x + n,
+    $sub: |n| => this.x - n,
+    $mul: |n| => this.x * n
+}
+assert(v + 2 == 12)
+assert(v - 2 == 8)
+assert(v * 2 == 20)
+
+// the hooks are only invoked when the struct is the left operand
+fn fail(f) {
+    try {
+        f()
+        assert(false)
+    } catch e {
+        assert(e.kind == 'TypeMismatch')
+        assert(e.msg == 'Expected Int or Float, not Struct')
+    }
+}
+fail(|| => 2 + v)
+fail(|| => 2 - v)
+fail(|| => 2 * v)
+
+// a Str on the left is concatenated with the struct
+assert(('v: ' + v).hasPrefix('v: struct {'))
+`
+	mod := compile(t, code)
+
+	_, es := NewInterpreter(builtins, nil).EvalModule(mod)
+	tassert(t, es == nil)
+}
+
 //--------------------------------------------------------------
 //--------------------------------------------------------------
 //--------------------------------------------------------------
 
 func failInterp(t *testing.T, code string, expect ErrorStruct) {
 
-	source := &scanner.Source{Name: "foo", Path: "foo.glm", Code: code}
-	mod, err := compiler.CompileSource(source, builtins)
-	tassert(t, err == nil)
+	mod := compile(t, code)
 
 	intp := NewInterpreter(builtins, nil)
 	_, es := intp.EvalModule(mod)
@@ -200,8 +385,8 @@ func TestStackTrace(t *testing.T) {
 	failInterp(t, code,
 		newErrorStruct(
 			g.DivideByZero(),
-			[]string{
-				"    at foo.glm:2"}))
+			[]TraceFrame{
+				{"foo.glm", 2, 4, "", 0}}))
 
 	code = `
 		let a = (|| => 1/0)
@@ -210,9 +395,9 @@ func TestStackTrace(t *testing.T) {
 	failInterp(t, code,
 		newErrorStruct(
 			g.DivideByZero(),
-			[]string{
-				"    at foo.glm:2",
-				"    at foo.glm:3"}))
+			[]TraceFrame{
+				{"foo.glm", 2, 19, "a", 0},
+				{"foo.glm", 3, 3, "", 0}}))
 
 	code = `
 		let s = struct {
@@ -223,9 +408,9 @@ func TestStackTrace(t *testing.T) {
 	failInterp(t, code,
 		newErrorStruct(
 			g.DivideByZero(),
-			[]string{
-				"    at foo.glm:3",
-				"    at foo.glm:5"}))
+			[]TraceFrame{
+				{"foo.glm", 3, 20, "q", 0},
+				{"foo.glm", 5, 13, "", 0}}))
 
 	code = `
 		[1, 2, 3].map(
@@ -234,9 +419,9 @@ func TestStackTrace(t *testing.T) {
 	failInterp(t, code,
 		newErrorStruct(
 			g.DivideByZero(),
-			[]string{
-				"    at foo.glm:3",
-				"    at foo.glm:2"}))
+			[]TraceFrame{
+				{"foo.glm", 3, 12, "", 0},
+				{"foo.glm", 2, 13, "", 0}}))
 
 	code = `
 		let s = struct {
@@ -251,10 +436,10 @@ func TestStackTrace(t *testing.T) {
 	failInterp(t, code,
 		newErrorStruct(
 			g.DivideByZero(),
-			[]string{
-				"    at foo.glm:5",
-				"    at foo.glm:4",
-				"    at foo.glm:9"}))
+			[]TraceFrame{
+				{"foo.glm", 5, 15, "q", 0},
+				{"foo.glm", 4, 16, "q", 0},
+				{"foo.glm", 9, 5, "", 0}}))
 
 	code = `
 		let s = struct {
@@ -267,10 +452,10 @@ func TestStackTrace(t *testing.T) {
 	failInterp(t, code,
 		newErrorStruct(
 			g.DivideByZero(),
-			[]string{
-				"    at foo.glm:3",
-				"    at foo.glm:7",
-				"    at foo.glm:6"}))
+			[]TraceFrame{
+				{"foo.glm", 3, 20, "q", 0},
+				{"foo.glm", 7, 13, "", 0},
+				{"foo.glm", 6, 21, "", 0}}))
 
 	code = `
 		fn b() {
@@ -295,13 +480,165 @@ func TestStackTrace(t *testing.T) {
 	failInterp(t, code,
 		newErrorStruct(
 			g.DivideByZero(),
-			[]string{
-				"    at foo.glm:8",
-				"    at foo.glm:12",
-				"    at foo.glm:11",
-				"    at foo.glm:3",
-				"    at foo.glm:16",
-				"    at foo.glm:19"}))
+			[]TraceFrame{
+				{"foo.glm", 8, 21, "q", 0},
+				{"foo.glm", 12, 14, "a", 0},
+				{"foo.glm", 11, 23, "a", 0},
+				{"foo.glm", 3, 4, "b", 0},
+				{"foo.glm", 16, 4, "c", 0},
+				{"foo.glm", 19, 3, "", 0}}))
+}
+
+func okInterp(t *testing.T, code string, expect g.Value) {
+
+	mod := compile(t, code)
+
+	val, es := NewInterpreter(builtins, nil).EvalModule(mod)
+	if es != nil {
+		t.Fatal(es)
+	}
+	ok(t, val, nil, expect)
+}
+
+func TestSwitchParenCase(t *testing.T) {
+
+	// a parenthesized case without a comma is compared for equality
+	okInterp(t, `
+fn f(x) {
+    let y = 7
+    switch x {
+    case (2 + 3):
+        return 'five'
+    case (y):
+        return 'seven'
+    case (a, 1):
+        return a
+    default:
+        return 'other'
+    }
+}
+return [f(5), f(7), f((9, 1)), f(8)]
+`, g.NewList([]g.Value{
+		g.MustStr("five"),
+		g.MustStr("seven"),
+		g.NewInt(9),
+		g.MustStr("other"),
+	}))
+}
+
+func TestGeneratorDefers(t *testing.T) {
+
+	mod := compile(t, `
+let log = []
+fn gen() {
+	defer log.add('defer')
+	try {
+		yield 1
+		yield 2
+	} finally {
+		log.add('finally')
+	}
+}
+
+// a generator that runs to completion exits its frame
+for x in gen() {}
+assert(log == ['finally', 'defer'])
+
+// an abandoned generator never does
+log.clear()
+for x in gen() {
+	break
+}
+let g = gen()
+g.next()
+assert(log == [])
+`)
+
+	_, es := NewInterpreter(builtins, nil).EvalModule(mod)
+	tassert(t, es == nil)
+}
+
+func TestTailCalls(t *testing.T) {
+
+	// these would all overflow the stack, if the frames were not reused
+	okInterp(t, `
+fn sum(n, acc) {
+    if n == 0 { return acc; }
+    return sum(n - 1, acc + n)
+}
+return sum(100000, 0)
+`, g.NewInt(5000050000))
+
+	okInterp(t, `
+fn isEven(n) {
+    if n == 0 { return true; }
+    return isOdd(n - 1)
+}
+fn isOdd(n) {
+    if n == 0 { return false; }
+    return isEven(n - 1)
+}
+return isEven(50001)
+`, g.False)
+
+	okInterp(t, `
+let s = struct {
+    count: fn(n, acc = 0) {
+        if n == 0 { return acc; }
+        return this.count(n - 1, acc + 1)
+    }
+}
+return s.count(50000)
+`, g.NewInt(50000))
+
+	// frames with a 'finally' clause, or a deferred invocation,
+	// still have work to do, so they are not reused
+	okInterp(t, `
+fn a(n) {
+    try {
+        return a(n + 1)
+    } catch e {
+        assert(e.kind == 'StackOverflow')
+        return n
+    }
+}
+return a(0)
+`, g.NewInt(DefaultMaxFrameDepth-1))
+
+	okInterp(t, `
+let log = []
+fn a(n) {
+    defer log.add(n)
+    if n == 0 { return 0; }
+    return a(n - 1)
+}
+a(3)
+return log
+`, g.NewList([]g.Value{g.Zero, g.One, g.NewInt(2), g.NewInt(3)}))
+
+	// the stack trace records the frames that were elided
+	code := `
+fn a(n) {
+    if n == 0 { return 1/0; }
+    return a(n - 1)
+}
+fn b() {
+    return a(3)
+}
+b()
+`
+	mod := compile(t, code)
+
+	_, es := NewInterpreter(builtins, nil).EvalModule(mod)
+	tassert(t, reflect.DeepEqual(es, newErrorStruct(
+		g.DivideByZero(),
+		[]TraceFrame{
+			{"foo.glm", 3, 25, "a", 4},
+			{"foo.glm", 9, 1, "", 0}})))
+	tassert(t, reflect.DeepEqual(es.StackTrace(), []string{
+		"    at a (foo.glm:3:25)",
+		"    ... 4 tail calls elided",
+		"    at foo.glm:9:1"}))
 }
 
 //func okInterp(t *testing.T, mods []*bc.Module) {
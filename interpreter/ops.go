@@ -6,6 +6,7 @@ package interpreter
 
 import (
 	"fmt"
+	"reflect"
 
 	g "github.com/mjarmy/golem-lang/core"
 	bc "github.com/mjarmy/golem-lang/core/bytecode"
@@ -70,8 +71,12 @@ func init() {
 		opFuncLocal,
 
 		opInvoke,
+		opTailInvoke,
 		opGo,
+		opDefer,
+		opSelect,
 		opReturn,
+		opYield,
 
 		opPushTry,
 		opPopTry,
@@ -82,10 +87,17 @@ func init() {
 		opNewList,
 		opNewSet,
 		opNewTuple,
+		opConcat,
 		opCheckTuple,
+		opCheckList,
+		opCheckStruct,
+		opMatchType,
+		opMatchLen,
+		opMatchField,
 
 		opGetField,
 		opInvokeField,
+		opTailInvokeField,
 		opInitField,
 		opInitProperty,
 		opInitReadonlyProperty,
@@ -127,9 +139,39 @@ func opInvoke(itp *Interpreter, f *frame) (g.Value, g.Error) {
 
 func invokeBytecode(itp *Interpreter, f *frame, fn bc.Func, n, p int) (g.Value, g.Error) {
 
-	params := f.stack[n-p+1:]
+	params, err := arityParams(fn, f.stack[n-p+1:])
+	if err != nil {
+		return nil, err
+	}
+
+	// Pop from stack.
+	f.stack = f.stack[:n-p]
+
+	// invoking a generator produces an Iterator, rather than a new frame
+	locals := newLocals(fn.Template().NumLocals, params)
+	if fn.Template().IsGenerator {
+		f.stack = append(f.stack, newGenerator(itp, fn, locals))
+		f.ip += 3
+		return nil, nil
+	}
+
+	// push a new frame
+	if es := itp.checkFrameDepth(); es != nil {
+		return nil, es
+	}
+	itp.frameStack.push(newFrame(fn, locals, false))
+
+	// NOTE: we do not actually advance the instruction pointer here.
+	// We aren't done with the invocation until the bc.Return of the
+	// new frame has been encountered.
+
+	return nil, nil
+}
+
+// check the arity of the params that are being passed to a bytecode.Func,
+// and modify the params if necessary
+func arityParams(fn bc.Func, params []g.Value) ([]g.Value, g.Error) {
 
-	// check arity, and modify params if necessary
 	arity := fn.Template().Arity
 	numParams := len(params)
 	numReq := int(arity.Required)
@@ -175,18 +217,7 @@ func invokeBytecode(itp *Interpreter, f *frame, fn bc.Func, n, p int) (g.Value,
 		panic("unreachable")
 	}
 
-	// Pop from stack.
-	f.stack = f.stack[:n-p]
-
-	// push a new frame
-	locals := newLocals(fn.Template().NumLocals, params)
-	itp.frameStack.push(newFrame(fn, locals, false))
-
-	// NOTE: we do not actually advance the instruction pointer here.
-	// We aren't done with the invocation until the bc.Return of the
-	// new frame has been encountered.
-
-	return nil, nil
+	return params, nil
 }
 
 func invokeNative(ev g.Eval, f *frame, fn g.NativeFunc, n, p int) (g.Value, g.Error) {
@@ -210,6 +241,54 @@ func invokeNative(ev g.Eval, f *frame, fn g.NativeFunc, n, p int) (g.Value, g.Er
 	return nil, nil
 }
 
+func opTailInvoke(itp *Interpreter, f *frame) (g.Value, g.Error) {
+
+	n := len(f.stack) - 1
+	p := bc.DecodeParam(f.btc, f.ip)
+
+	if fn, ok := f.stack[n-p].(bc.Func); ok && canReuseFrame(itp, f, fn) {
+		return reuseFrame(f, fn, f.stack[n-p+1:])
+	}
+
+	// Invoke normally.  The result will be returned by the bc.Return
+	// that follows this opcode.
+	return opInvoke(itp, f)
+}
+
+// canReuseFrame returns whether a frame can be reused for a tail call.
+// The frame cannot be reused if it still has work to do after the call
+// returns, such as running a deferred invocation or a 'finally' clause.
+func canReuseFrame(itp *Interpreter, f *frame, fn bc.Func) bool {
+	return itp.debugger == nil &&
+		f.numHandlers() == 0 &&
+		len(f.defers) == 0 &&
+		!f.isHandlingError &&
+		!f.fn.Template().IsGenerator &&
+		!fn.Template().IsGenerator
+}
+
+// reuseFrame turns a frame into a new frame for the given func, so that the
+// func's result is returned to the frame's caller.  The frame keeps a count
+// of the calls that have been elided, for use in stack traces.
+func reuseFrame(f *frame, fn bc.Func, params []g.Value) (g.Value, g.Error) {
+
+	params, err := arityParams(fn, params)
+	if err != nil {
+		return nil, err
+	}
+
+	tpl := fn.Template()
+	f.fn = fn
+	f.locals = newLocals(tpl.NumLocals, params)
+	f.btc = tpl.Bytecodes
+	f.pool = tpl.Module.Pool
+	f.stack = f.stack[:0]
+	f.ip = 0
+	f.tailCalls++
+
+	return nil, nil
+}
+
 func opReturn(itp *Interpreter, f *frame) (g.Value, g.Error) {
 
 	//// TODO once we've written a Control Flow Graph
@@ -220,6 +299,11 @@ func opReturn(itp *Interpreter, f *frame) (g.Value, g.Error) {
 	//	panic("invalid stack")
 	//}
 
+	// run any deferred invocations
+	if es := itp.runDefers(f); es != nil {
+		return nil, es
+	}
+
 	// get result from top of stack
 	n := len(f.stack) - 1
 	result := f.stack[n]
@@ -240,13 +324,30 @@ func opReturn(itp *Interpreter, f *frame) (g.Value, g.Error) {
 		f.stack = append(f.stack, result)
 
 		// advance past the bc.Invoke of the parent frame
-		g.Assert(f.btc[f.ip] == bc.Invoke)
+		g.Assert(f.btc[f.ip] == bc.Invoke || f.btc[f.ip] == bc.TailInvoke)
 		f.ip += 3
 
 		return nil, nil
 	}
 }
 
+func opYield(itp *Interpreter, f *frame) (g.Value, g.Error) {
+
+	// get value from top of stack
+	n := len(f.stack) - 1
+	val := f.stack[n]
+	f.stack = f.stack[:n]
+
+	// Suspend the frame.  A generator's frame is always the base frame
+	// of an Eval(), so the value is returned directly to the generator.
+	g.Assert(f.isBase)
+	f.isSuspended = true
+	f.ip++
+	itp.frameStack.pop()
+
+	return val, nil
+}
+
 func opPushTry(itp *Interpreter, f *frame) (g.Value, g.Error) {
 
 	p := bc.DecodeParam(f.btc, f.ip)
@@ -268,50 +369,179 @@ func opThrow(itp *Interpreter, f *frame) (g.Value, g.Error) {
 
 	n := len(f.stack) - 1
 
-	s, ok := f.stack[n].(g.Str)
-	if !ok {
-		return nil, g.TypeMismatch(g.StrType, f.stack[n].Type())
-	}
+	switch t := f.stack[n].(type) {
+
+	case ErrorStruct:
+		// re-throw a caught error, preserving its stack trace
+		return nil, t
 
-	return nil, g.Error(fmt.Errorf("%s", s.String()))
+	case g.Struct:
+		te, err := newThrownError(itp, t)
+		if err != nil {
+			return nil, err
+		}
+		return nil, te
+
+	default:
+		te, err := newThrownValue(itp, t)
+		if err != nil {
+			return nil, err
+		}
+		return nil, te
+	}
 }
 
 func opGo(itp *Interpreter, f *frame) (g.Value, g.Error) {
 
 	n := len(f.stack) - 1
 
-	p := bc.DecodeParam(f.btc, f.ip)
+	p, detached := bc.DecodeWideParams(f.btc, f.ip)
 	params := g.CopyValues(f.stack[n-p+1:])
-	fn := f.stack[n-p]
-	f.stack = f.stack[:n-p]
 
-	switch t := fn.(type) {
+	// each goroutine gets its own Interpreter
+	goItp := NewInterpreter(itp.builtins, itp.importer)
+	goItp.goErrorHandler = itp.goErrorHandler
+	goItp.budget = itp.budget
+
+	// only a detached goroutine reports its errors, since otherwise
+	// the error might yet be caught by whoever joins the handle
+	var onError func(ErrorStruct)
+	if detached != 0 {
+		onError = itp.goErrorHandler
+	}
+	gr := newGoroutine(onError)
+
+	switch fn := f.stack[n-p].(type) {
 	case bc.Func:
 
-		f.ip += 3
-		goItp := NewInterpreter(itp.builtins, itp.importer)
+		params, err := arityParams(fn, params)
+		if err != nil {
+			return nil, err
+		}
+
 		go (func() {
-			_, es := goItp.EvalBytecode(t, params)
-			if es != nil {
-				panic("TODO how to handle exceptions in goroutines")
-			}
+			val, es := goItp.EvalBytecode(fn, params)
+			gr.finish(val, es)
 		})()
-		return nil, nil
 
 	case g.NativeFunc:
 
-		f.ip += 3
+		// native functions do not have a stack trace of their own,
+		// so use the location of the 'go' expression
+		stackTrace := itp.frameStack.stackTrace()
+
 		go (func() {
-			_, err := t.Invoke(itp, params)
+			val, err := fn.Invoke(goItp, params)
 			if err != nil {
-				fmt.Printf("%v\n", err)
+				es, ok := err.(ErrorStruct)
+				if !ok {
+					es = newErrorStruct(err, stackTrace)
+				}
+				gr.finish(nil, es)
+				return
 			}
+			gr.finish(val, nil)
 		})()
-		return nil, nil
 
 	default:
 		return nil, g.TypeMismatch(g.FuncType, f.stack[n-p].Type())
 	}
+
+	// push a handle for the goroutine
+	f.stack = f.stack[:n-p]
+	f.stack = append(f.stack, gr.handle())
+	f.ip += 5
+
+	return nil, nil
+}
+
+func opDefer(itp *Interpreter, f *frame) (g.Value, g.Error) {
+
+	n := len(f.stack) - 1
+
+	p := bc.DecodeParam(f.btc, f.ip)
+	params := g.CopyValues(f.stack[n-p+1:])
+
+	switch fn := f.stack[n-p].(type) {
+	case bc.Func:
+
+		params, err := arityParams(fn, params)
+		if err != nil {
+			return nil, err
+		}
+		f.defers = append(f.defers, &deferred{fn, params})
+
+	case g.NativeFunc:
+
+		f.defers = append(f.defers, &deferred{fn, params})
+
+	default:
+		return nil, g.TypeMismatch(g.FuncType, f.stack[n-p].Type())
+	}
+
+	f.stack = f.stack[:n-p]
+	f.ip += 3
+	return nil, nil
+}
+
+func opSelect(itp *Interpreter, f *frame) (g.Value, g.Error) {
+
+	p, q := bc.DecodeWideParams(f.btc, f.ip)
+
+	// Each case is represented on the stack by a chan, a value,
+	// and a Bool that specifies whether the case is a send.
+	n := len(f.stack) - 1
+	ns := n - p*3 + 1
+
+	cases := make([]reflect.SelectCase, 0, p+3)
+	for i := ns; i <= n; i += 3 {
+
+		ch, ok := f.stack[i].(g.Chan)
+		if !ok {
+			return nil, g.TypeMismatch(g.ChanType, f.stack[i].Type())
+		}
+
+		isSend, ok := f.stack[i+2].(g.Bool)
+		g.Assert(ok)
+
+		if isSend.BoolVal() {
+			cases = append(cases, reflect.SelectCase{
+				Dir:  reflect.SelectSend,
+				Chan: reflect.ValueOf(ch.ToChan()),
+				Send: reflect.ValueOf(f.stack[i+1]),
+			})
+		} else {
+			cases = append(cases, reflect.SelectCase{
+				Dir:  reflect.SelectRecv,
+				Chan: reflect.ValueOf(ch.ToChan()),
+			})
+		}
+	}
+	if q == 1 {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectDefault})
+	}
+
+	// block until one of the cases can proceed
+	chosen, recv, recvOK, err := itp.Select(cases)
+	if err != nil {
+		return nil, err
+	}
+
+	// the default case is always last
+	if chosen == p {
+		chosen = -1
+	}
+
+	var val g.Value = g.Null
+	if recvOK {
+		val = recv.Interface().(g.Value)
+	}
+
+	f.stack = f.stack[:ns]
+	f.stack = append(f.stack, val, g.NewInt(int64(chosen)))
+	f.ip += 5
+
+	return nil, nil
 }
 
 func opNewFunc(itp *Interpreter, f *frame) (g.Value, g.Error) {
@@ -361,6 +591,9 @@ func opNewList(itp *Interpreter, f *frame) (g.Value, g.Error) {
 	n := len(f.stack) - 1
 
 	size := bc.DecodeParam(f.btc, f.ip)
+	if err := g.AllocateValues(itp, size); err != nil {
+		return nil, err
+	}
 	ns := n - size + 1
 	vals := g.CopyValues(f.stack[ns:])
 
@@ -396,6 +629,9 @@ func opNewTuple(itp *Interpreter, f *frame) (g.Value, g.Error) {
 	n := len(f.stack) - 1
 
 	size := bc.DecodeParam(f.btc, f.ip)
+	if err := g.AllocateValues(itp, size); err != nil {
+		return nil, err
+	}
 	ns := n - size + 1
 	vals := g.CopyValues(f.stack[ns:])
 
@@ -406,6 +642,34 @@ func opNewTuple(itp *Interpreter, f *frame) (g.Value, g.Error) {
 	return nil, nil
 }
 
+func opConcat(itp *Interpreter, f *frame) (g.Value, g.Error) {
+
+	n := len(f.stack) - 1
+
+	size := bc.DecodeParam(f.btc, f.ip)
+	ns := n - size + 1
+
+	// concatenate the string representations of the values
+	buf := g.NewStrBuilder(itp)
+	for _, v := range f.stack[ns:] {
+		s, err := v.ToStr(itp)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(s.String())
+	}
+	s, err := buf.ToStr()
+	if err != nil {
+		return nil, err
+	}
+
+	f.stack = f.stack[:ns]
+	f.stack = append(f.stack, s)
+	f.ip += 3
+
+	return nil, nil
+}
+
 func opCheckTuple(itp *Interpreter, f *frame) (g.Value, g.Error) {
 
 	n := len(f.stack) - 1
@@ -435,6 +699,115 @@ func opCheckTuple(itp *Interpreter, f *frame) (g.Value, g.Error) {
 	return nil, nil
 }
 
+func opCheckList(itp *Interpreter, f *frame) (g.Value, g.Error) {
+
+	n := len(f.stack) - 1
+
+	// make sure the top of the stack is really a list
+	ls, ok := f.stack[n].(g.List)
+	if !ok {
+		return nil, g.TypeMismatch(g.ListType, f.stack[n].Type())
+	}
+
+	// and make sure its of the expected length, or at least that
+	// long if the rest of the list is being destructured too
+	expectedLen, rest := bc.DecodeWideParams(f.btc, f.ip)
+	lsLen, err := ls.Len(itp)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case rest == 0 && expectedLen != int(lsLen.ToInt()):
+		return nil, g.InvalidArgument(
+			fmt.Sprintf(
+				"Expected List of length %d, not length %d",
+				expectedLen, int(lsLen.ToInt())))
+	case rest != 0 && expectedLen > int(lsLen.ToInt()):
+		return nil, g.InvalidArgument(
+			fmt.Sprintf(
+				"Expected List of at least length %d, not length %d",
+				expectedLen, int(lsLen.ToInt())))
+	}
+
+	// do not alter stack
+	f.ip += 5
+
+	return nil, nil
+}
+
+func opCheckStruct(itp *Interpreter, f *frame) (g.Value, g.Error) {
+
+	n := len(f.stack) - 1
+
+	// make sure the top of the stack is really a struct
+	if _, ok := f.stack[n].(g.Struct); !ok {
+		return nil, g.TypeMismatch(g.StructType, f.stack[n].Type())
+	}
+
+	// do not alter stack
+	f.ip++
+
+	return nil, nil
+}
+
+// The Match bytecodes are like the Check bytecodes, except that rather than
+// failing, they push a Bool that says whether the check succeeded.
+
+func opMatchType(itp *Interpreter, f *frame) (g.Value, g.Error) {
+
+	n := len(f.stack) - 1
+
+	t := g.Type(bc.DecodeParam(f.btc, f.ip))
+	f.stack = append(f.stack, g.NewBool(f.stack[n].Type() == t))
+	f.ip += 3
+
+	return nil, nil
+}
+
+func opMatchLen(itp *Interpreter, f *frame) (g.Value, g.Error) {
+
+	n := len(f.stack) - 1
+
+	ln, ok := f.stack[n].(g.Lenable)
+	g.Assert(ok)
+	length, err := ln.Len(itp)
+	if err != nil {
+		return nil, err
+	}
+
+	// if the rest of the value is being matched too,
+	// then it only has to be at least the expected length
+	expectedLen, rest := bc.DecodeWideParams(f.btc, f.ip)
+	var matched bool
+	if rest == 0 {
+		matched = int(length.ToInt()) == expectedLen
+	} else {
+		matched = int(length.ToInt()) >= expectedLen
+	}
+
+	f.stack = append(f.stack, g.NewBool(matched))
+	f.ip += 5
+
+	return nil, nil
+}
+
+func opMatchField(itp *Interpreter, f *frame) (g.Value, g.Error) {
+
+	n := len(f.stack) - 1
+
+	key, ok := f.pool.Constants[bc.DecodeParam(f.btc, f.ip)].(g.Str)
+	g.Assert(ok)
+	has, err := f.stack[n].HasField(key.String())
+	if err != nil {
+		return nil, err
+	}
+
+	f.stack = append(f.stack, g.NewBool(has))
+	f.ip += 3
+
+	return nil, nil
+}
+
 func opNewDict(itp *Interpreter, f *frame) (g.Value, g.Error) {
 
 	n := len(f.stack) - 1
@@ -463,6 +836,15 @@ func opNewDict(itp *Interpreter, f *frame) (g.Value, g.Error) {
 func opNewStruct(itp *Interpreter, f *frame) (g.Value, g.Error) {
 
 	p := bc.DecodeParam(f.btc, f.ip)
+
+	// use the definition's Shape, so that the struct's fields
+	// can be found via the inline caches
+	if shape := f.pool.Shape(p); shape != nil {
+		f.stack = append(f.stack, g.NewShapedStruct(shape))
+		f.ip += 3
+		return nil, nil
+	}
+
 	def := f.pool.StructDefs[p]
 	fields := make(map[string]g.Field)
 	for _, name := range def {
@@ -484,8 +866,10 @@ func opNewIter(itp *Interpreter, f *frame) (g.Value, g.Error) {
 
 	n := len(f.stack) - 1
 
-	ibl, ok := f.stack[n].(g.Iterable)
-	g.Assert(ok)
+	ibl, ok := g.AsIterable(f.stack[n])
+	if !ok {
+		return nil, g.IterableMismatch(f.stack[n].Type())
+	}
 
 	itr, err := ibl.NewIterator(itp)
 	if err != nil {
@@ -534,15 +918,48 @@ func opIterGet(itp *Interpreter, f *frame) (g.Value, g.Error) {
 	return nil, nil
 }
 
+// fieldKey returns the name of the field that is looked up via the
+// given inline cache slot.
+func fieldKey(f *frame, slot int) string {
+	key, ok := f.pool.Constants[f.fn.Template().FieldKeys[slot]].(g.Str)
+	g.Assert(ok)
+	return key.String()
+}
+
+// cachedField looks up a field via the inline cache in the given slot.
+// If the value is not a Struct that has a Shape, cachedField returns nil.
+func cachedField(f *frame, val g.Value, slot int) g.Field {
+
+	tpl := f.fn.Template()
+	for _, fc := range tpl.FieldCaches(slot) {
+		if fld, ok := g.ShapedField(val, fc.Shape, fc.Index); ok {
+			return fld
+		}
+	}
+
+	shape, idx, ok := g.FieldIndex(val, fieldKey(f, slot))
+	if !ok {
+		return nil
+	}
+	tpl.AddFieldCache(slot, bc.FieldCache{Shape: shape, Index: idx})
+
+	fld, _ := g.ShapedField(val, shape, idx)
+	return fld
+}
+
 func opGetField(itp *Interpreter, f *frame) (g.Value, g.Error) {
 
 	n := len(f.stack) - 1
 
 	p := bc.DecodeParam(f.btc, f.ip)
-	key, ok := f.pool.Constants[p].(g.Str)
-	g.Assert(ok)
 
-	result, err := f.stack[n].GetField(itp, key.String())
+	var result g.Value
+	var err g.Error
+	if fld := cachedField(f, f.stack[n], p); fld != nil {
+		result, err = fld.Get(itp)
+	} else {
+		result, err = f.stack[n].GetField(itp, fieldKey(f, p))
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -559,13 +976,57 @@ func opInvokeField(itp *Interpreter, f *frame) (g.Value, g.Error) {
 
 	p, q := bc.DecodeWideParams(f.btc, f.ip)
 
-	key, ok := f.pool.Constants[p].(g.Str)
-	g.Assert(ok)
+	self := f.stack[n-q]
+	params := f.stack[n-q+1:]
+
+	var result g.Value
+	var err g.Error
+	if fld := cachedField(f, self, p); fld != nil {
+		result, err = fld.Invoke(itp, params)
+	} else {
+		result, err = self.InvokeField(itp, fieldKey(f, p), params)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	f.stack[n-q] = result
+	f.stack = f.stack[:n-q+1]
+	f.ip += 5
+
+	return nil, nil
+}
+
+func opTailInvokeField(itp *Interpreter, f *frame) (g.Value, g.Error) {
+
+	n := len(f.stack) - 1
+
+	p, q := bc.DecodeWideParams(f.btc, f.ip)
 
 	self := f.stack[n-q]
 	params := f.stack[n-q+1:]
 
-	result, err := self.InvokeField(itp, key.String(), params)
+	// Only the fields of a Struct that has a Shape can be reused for a
+	// tail call.  Anything else is invoked normally.
+	fld := cachedField(f, self, p)
+	if fld == nil {
+		return opInvokeField(itp, f)
+	}
+
+	val, err := fld.Get(itp)
+	if err != nil {
+		return nil, err
+	}
+
+	if fn, ok := val.(bc.Func); ok && canReuseFrame(itp, f, fn) {
+		return reuseFrame(f, fn, params)
+	}
+
+	callable, ok := val.(g.Func)
+	if !ok {
+		return nil, g.TypeMismatch(g.FuncType, val.Type())
+	}
+	result, err := callable.Invoke(itp, params)
 	if err != nil {
 		return nil, err
 	}
@@ -1236,6 +1697,16 @@ func opSub(itp *Interpreter, f *frame) (g.Value, g.Error) {
 
 	n := len(f.stack) - 1
 
+	if val, ok, err := g.InvokeMagicField(itp, f.stack[n-1], "$sub", []g.Value{f.stack[n]}); ok {
+		if err != nil {
+			return nil, err
+		}
+		f.stack = f.stack[:n]
+		f.stack[n-1] = val
+		f.ip++
+		return nil, nil
+	}
+
 	lhs, lhsOk := f.stack[n-1].(g.Number)
 	rhs, rhsOk := f.stack[n].(g.Number)
 	if !lhsOk {
@@ -1258,6 +1729,16 @@ func opMul(itp *Interpreter, f *frame) (g.Value, g.Error) {
 
 	n := len(f.stack) - 1
 
+	if val, ok, err := g.InvokeMagicField(itp, f.stack[n-1], "$mul", []g.Value{f.stack[n]}); ok {
+		if err != nil {
+			return nil, err
+		}
+		f.stack = f.stack[:n]
+		f.stack[n-1] = val
+		f.ip++
+		return nil, nil
+	}
+
 	lhs, lhsOk := f.stack[n-1].(g.Number)
 	rhs, rhsOk := f.stack[n].(g.Number)
 	if !lhsOk {
@@ -1495,6 +1976,12 @@ func opPop(itp *Interpreter, f *frame) (g.Value, g.Error) {
 
 func plus(ev g.Eval, a g.Value, b g.Value) (g.Value, g.Error) {
 
+	// if a is a Struct that overrides '+', invoke it.  The hooks of b are
+	// never invoked, since the operators are not all commutative.
+	if val, ok, err := g.InvokeMagicField(ev, a, "$add", []g.Value{b}); ok {
+		return val, err
+	}
+
 	// if either is a Str, return concatenated strings
 	_, ia := a.(g.Str)
 	_, ib := b.(g.Str)
@@ -1510,7 +1997,7 @@ func plus(ev g.Eval, a g.Value, b g.Value) (g.Value, g.Error) {
 			return nil, err
 		}
 
-		return as.Concat(bs), nil
+		return as.Concat(ev, bs)
 	}
 
 	// if both are Numbers, add them together
@@ -109,8 +109,8 @@ func fromDict(ev g.Eval, dict g.Dict) (interface{}, g.Error) {
 
 		s, ok := entry.Key.(g.Str)
 		if !ok {
-			return nil, g.Error(fmt.Errorf(
-				"JsonError: %s is not a valid object key", entry.Key.Type()))
+			return nil, g.NewError(
+				"JsonError", fmt.Sprintf("%s is not a valid object key", entry.Key.Type()))
 		}
 
 		fv, err := fromValue(ev, entry.Value)
@@ -179,8 +179,8 @@ func fromValue(ev g.Eval, val g.Value) (interface{}, g.Error) {
 		return fromStruct(ev, val.(g.Struct))
 
 	default:
-		return nil, g.Error(fmt.Errorf(
-			"JsonError: %s cannot be marshalled", val.Type()))
+		return nil, g.NewError(
+			"JsonError", fmt.Sprintf("%s cannot be marshalled", val.Type()))
 	}
 }
 
@@ -193,7 +193,7 @@ func marshal(ev g.Eval, val g.Value) (g.Str, g.Error) {
 
 	b, err := json.Marshal(ifc)
 	if err != nil {
-		return nil, g.Error(fmt.Errorf("JsonError: %s", err.Error()))
+		return nil, g.NewError("JsonError", err.Error())
 	}
 	return g.NewStr(string(b))
 }
@@ -207,7 +207,7 @@ func marshalIndent(ev g.Eval, val g.Value, prefix, indent g.Str) (g.Str, g.Error
 
 	b, err := json.MarshalIndent(ifc, prefix.String(), indent.String())
 	if err != nil {
-		return nil, g.Error(fmt.Errorf("JsonError: %s", err.Error()))
+		return nil, g.NewError("JsonError", err.Error())
 	}
 	return g.NewStr(string(b))
 }
@@ -348,7 +348,7 @@ func unmarshal(ev g.Eval, s g.Str, useStructs bool) (g.Value, g.Error) {
 
 	err := json.Unmarshal([]byte(s.String()), &ifc)
 	if err != nil {
-		return nil, g.Error(fmt.Errorf("JsonError: %s", err.Error()))
+		return nil, g.NewError("JsonError", err.Error())
 	}
 
 	return toValue(ev, ifc, useStructs)
@@ -5,7 +5,6 @@
 package ioutil
 
 import (
-	"fmt"
 	"io/ioutil"
 	"os"
 
@@ -58,7 +57,7 @@ var ReadDir g.Value = g.NewFixedNativeFunc(
 
 		infos, err := ioutil.ReadDir(filename.String())
 		if err != nil {
-			return nil, g.Error(fmt.Errorf("IoError: %s", err.Error()))
+			return nil, g.NewError("IoError", err.Error())
 		}
 		values := make([]g.Value, len(infos))
 		for i, inf := range infos {
@@ -90,7 +89,7 @@ var ReadFileString g.Value = g.NewFixedNativeFunc(
 
 		content, err := ioutil.ReadFile(filename.String())
 		if err != nil {
-			return nil, g.Error(fmt.Errorf("IoError: %s", err.Error()))
+			return nil, g.NewError("IoError", err.Error())
 		}
 		return g.NewStr(string(content))
 	})
@@ -121,7 +120,7 @@ var WriteFileString g.Value = g.NewFixedNativeFunc(
 		fileMode := os.FileMode(0666)
 		err := ioutil.WriteFile(filename.String(), []byte(data.String()), fileMode)
 		if err != nil {
-			return nil, g.Error(fmt.Errorf("IoError: %s", err.Error()))
+			return nil, g.NewError("IoError", err.Error())
 		}
 		return g.Null, nil
 	})
@@ -6,7 +6,6 @@ package os
 
 import (
 	"bufio"
-	"fmt"
 	"io"
 	"os"
 
@@ -100,7 +99,7 @@ var create g.Value = g.NewFixedNativeFunc(
 
 		f, err := os.Create(s.String())
 		if err != nil {
-			return nil, g.Error(fmt.Errorf("OsError: %s", err.Error()))
+			return nil, g.NewError("OsError", err.Error())
 		}
 		return newFile(f), nil
 	})
@@ -164,7 +163,7 @@ var open g.Value = g.NewFixedNativeFunc(
 
 		f, err := os.Open(s.String())
 		if err != nil {
-			return nil, g.Error(fmt.Errorf("OsError: %s", err.Error()))
+			return nil, g.NewError("OsError", err.Error())
 		}
 		return newFile(f), nil
 	})
@@ -215,7 +214,7 @@ var stat g.Value = g.NewFixedNativeFunc(
 
 		info, err := os.Stat(s.String())
 		if err != nil {
-			return nil, g.Error(fmt.Errorf("OsError: %s", err.Error()))
+			return nil, g.NewError("OsError", err.Error())
 		}
 		return NewFileInfo(info), nil
 	})
@@ -409,7 +408,7 @@ func readLines(f io.Reader) (g.List, g.Error) {
 	}
 
 	if e := scanner.Err(); e != nil {
-		return nil, g.Error(fmt.Errorf("OsError: %s", e.Error()))
+		return nil, g.NewError("OsError", e.Error())
 	}
 
 	return g.NewList(lines), nil
@@ -424,11 +423,11 @@ func writeLines(ev g.Eval, f *os.File, lines g.List) g.Error {
 		}
 		_, e := f.WriteString(s.String())
 		if e != nil {
-			return g.Error(fmt.Errorf("OsError: %s", e.Error()))
+			return g.NewError("OsError", e.Error())
 		}
 		_, e = f.WriteString("\n")
 		if e != nil {
-			return g.Error(fmt.Errorf("OsError: %s", e.Error()))
+			return g.NewError("OsError", e.Error())
 		}
 	}
 
@@ -438,7 +437,7 @@ func writeLines(ev g.Eval, f *os.File, lines g.List) g.Error {
 func closeFile(f io.Closer) g.Error {
 	e := f.Close()
 	if e != nil {
-		return g.Error(fmt.Errorf("OsError: %s", e.Error()))
+		return g.NewError("OsError", e.Error())
 	}
 	return nil
 }
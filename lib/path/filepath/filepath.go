@@ -5,7 +5,6 @@
 package path
 
 import (
-	"fmt"
 	"os"
 	"path/filepath"
 
@@ -113,7 +112,7 @@ var Walk g.Value = g.NewFixedNativeFunc(
 			if gerr, ok := err.(g.Error); ok {
 				return nil, gerr
 			}
-			return nil, g.Error(fmt.Errorf("PathError: %s", err.Error()))
+			return nil, g.NewError("PathError", err.Error())
 		}
 		return g.Null, nil
 	})
@@ -5,7 +5,6 @@
 package regexp
 
 import (
-	"fmt"
 	"regexp"
 
 	g "github.com/mjarmy/golem-lang/core"
@@ -71,7 +70,7 @@ var compile g.Value = g.NewFixedNativeFunc(
 
 		r, err := regexp.Compile(s.String())
 		if err != nil {
-			return nil, g.Error(fmt.Errorf("RegexpError: %s", err.Error()))
+			return nil, g.NewError("RegexpError", err.Error())
 		}
 
 		return makeRegexp(r), nil
@@ -0,0 +1,388 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/mjarmy/golem-lang/analyzer"
+	"github.com/mjarmy/golem-lang/ast"
+	"github.com/mjarmy/golem-lang/parser"
+	"github.com/mjarmy/golem-lang/scanner"
+)
+
+//---------------------------------------------------------------
+// Documents
+//---------------------------------------------------------------
+
+// A document is a source file that is open in the editor.
+type document struct {
+	uri   string
+	path  string
+	text  string
+	lines []string
+
+	diagnostics []diagnostic
+
+	// index is the index of the most recent version of the
+	// document that could be parsed.
+	index *index
+}
+
+// update replaces the text of a document, and then parses and analyzes it.
+func (doc *document) update(text string, isBuiltin func(string) bool) {
+
+	doc.text = text
+	doc.lines = strings.Split(text, "\n")
+	doc.diagnostics = []diagnostic{}
+
+	source := &scanner.Source{
+		Name: moduleName(doc.path),
+		Path: doc.path,
+		Code: text,
+	}
+
+	scn, err := scanner.NewScanner(source)
+	if err != nil {
+		doc.addDiagnostic(err.(*ast.Diagnostic))
+		return
+	}
+
+	mod, err := parser.NewParser(scn, isBuiltin).ParseModule()
+	if err != nil {
+		for _, d := range err.(ast.Diagnostics) {
+			doc.addDiagnostic(d)
+		}
+		return
+	}
+
+	for _, d := range analyzer.NewAnalyzer(mod).Analyze() {
+		doc.addDiagnostic(d)
+	}
+	doc.index = newIndex(mod)
+}
+
+func (doc *document) addDiagnostic(d *ast.Diagnostic) {
+
+	// make sure the range is not empty, so that the editor can show it
+	end := d.End
+	if end == d.Start {
+		end = end.Advance(1)
+	}
+
+	severity := severityError
+	if d.Severity == ast.SeverityWarning {
+		severity = severityWarning
+	}
+
+	doc.diagnostics = append(doc.diagnostics, diagnostic{
+		Range:    rng{doc.toPosition(d.Start), doc.toPosition(end)},
+		Severity: severity,
+		Code:     d.Code,
+		Source:   "golem",
+		Message:  d.Msg,
+	})
+}
+
+func moduleName(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), ".glm")
+}
+
+// The editor measures the character offset of a position in UTF-16 code
+// units, which is the default position encoding of the protocol, whereas
+// the column of an ast.Pos is a byte offset.
+
+// line returns the text of a 0-based line
+func (doc *document) line(n int) string {
+	if n < 0 || n >= len(doc.lines) {
+		return ""
+	}
+	return doc.lines[n]
+}
+
+// toPosition converts a 1-based ast.Pos into a 0-based position
+func (doc *document) toPosition(pos ast.Pos) position {
+
+	line := doc.line(pos.Line - 1)
+	col := pos.Col - 1
+
+	// a column past the end of the line counts one unit per byte
+	units := 0
+	if col > len(line) {
+		units = col - len(line)
+		col = len(line)
+	}
+	for _, r := range line[:col] {
+		units += utf16Len(r)
+	}
+	return position{pos.Line - 1, units}
+}
+
+// fromPosition converts a 0-based position into a 1-based ast.Pos
+func (doc *document) fromPosition(pos position) ast.Pos {
+
+	line := doc.line(pos.Line)
+
+	units := 0
+	for i, r := range line {
+		if units >= pos.Character {
+			return ast.Pos{Line: pos.Line + 1, Col: i + 1}
+		}
+		units += utf16Len(r)
+	}
+	return ast.Pos{Line: pos.Line + 1, Col: len(line) + 1 + pos.Character - units}
+}
+
+// utf16Len returns the number of UTF-16 code units that encode a rune
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+func (doc *document) tokenRange(tok *ast.Token) rng {
+	return rng{
+		doc.toPosition(tok.Position),
+		doc.toPosition(tok.Position.Advance(len(tok.Text))),
+	}
+}
+
+// containsPos returns whether a position is within a token, or just after it.
+func containsPos(tok *ast.Token, pos ast.Pos) bool {
+	return tok.Position.Line == pos.Line &&
+		tok.Position.Col <= pos.Col &&
+		pos.Col <= tok.Position.Col+len(tok.Text)
+}
+
+//---------------------------------------------------------------
+// Indexing
+//---------------------------------------------------------------
+
+// An index records where the identifiers in a module are defined and used.
+type index struct {
+	mod *ast.Module
+
+	idents   []*ast.IdentExpr
+	builtins []*ast.Token
+	fields   []*ast.FieldExpr
+	structs  []*ast.StructExpr
+
+	// the declaration of each Variable that is not a capture
+	decls map[ast.Variable]*declaration
+
+	// the Variable that each captured Variable refers to
+	parents map[ast.Variable]ast.Variable
+}
+
+// A declaration is an identifier that defines a Variable.
+type declaration struct {
+	ident    *ast.IdentExpr
+	value    ast.Expression // the initial value of the Variable, if any
+	isImport bool
+}
+
+func newIndex(mod *ast.Module) *index {
+
+	x := &index{
+		mod:      mod,
+		idents:   []*ast.IdentExpr{},
+		builtins: []*ast.Token{},
+		fields:   []*ast.FieldExpr{},
+		structs:  []*ast.StructExpr{},
+		decls:    map[ast.Variable]*declaration{},
+		parents:  map[ast.Variable]ast.Variable{},
+	}
+	x.Visit(mod.InitFunc)
+	return x
+}
+
+func (x *index) Visit(node ast.Node) {
+
+	switch t := node.(type) {
+
+	case *ast.IdentExpr:
+		// skip identifiers that are not defined, or were made by the parser
+		if t.Variable != nil && !strings.HasPrefix(t.Symbol.Text, "#") {
+			x.idents = append(x.idents, t)
+		}
+
+	case *ast.BuiltinExpr:
+		x.builtins = append(x.builtins, t.Fn)
+
+	case *ast.FieldExpr:
+		x.fields = append(x.fields, t)
+
+	case *ast.StructExpr:
+		x.structs = append(x.structs, t)
+
+	case *ast.ImportStmt:
+		for _, ident := range t.Idents {
+			x.declare(ident, nil, true)
+		}
+
+	case *ast.LetStmt:
+		for _, d := range t.Decls {
+			x.declareAll(d)
+		}
+
+	case *ast.ConstStmt:
+		for _, d := range t.Decls {
+			x.declareAll(d)
+		}
+
+	case *ast.NamedFnStmt:
+		x.declare(t.Ident, t.Func, false)
+
+	case *ast.ForStmt:
+		for _, ident := range t.Idents {
+			x.declare(ident, nil, false)
+		}
+
+	case *ast.CaseNode:
+		for _, pt := range t.Patterns {
+			for _, ident := range ast.PatternIdents(pt) {
+				x.declare(ident, nil, false)
+			}
+		}
+
+	case *ast.TryStmt:
+		if t.CatchIdent != nil {
+			x.declare(t.CatchIdent, nil, false)
+		}
+
+	case *ast.FnExpr:
+		for _, p := range t.Required {
+			x.declare(p.Ident, nil, false)
+		}
+		for _, p := range t.Optional {
+			x.declare(p.Ident, nil, false)
+		}
+		if t.Variadic != nil {
+			x.declare(t.Variadic.Ident, nil, false)
+		}
+		for _, c := range t.Scope.GetCaptures() {
+			x.parents[c.Child()] = c.Parent()
+		}
+	}
+
+	node.Traverse(x)
+}
+
+func (x *index) declare(ident *ast.IdentExpr, value ast.Expression, isImport bool) {
+	// the Variable is nil if the identifier was already defined
+	if ident.Variable != nil {
+		x.decls[ident.Variable] = &declaration{ident, value, isImport}
+	}
+}
+
+// declareAll declares the identifiers of a declaration.  The identifiers in
+// a pattern do not have a value, since they are each only part of the value.
+func (x *index) declareAll(d *ast.DeclNode) {
+	if d.Pattern == nil {
+		x.declare(d.Ident, d.Val, false)
+		return
+	}
+	for _, ident := range d.Idents() {
+		x.declare(ident, nil, false)
+	}
+}
+
+// root follows a captured Variable back to the Variable that it captures.
+func (x *index) root(v ast.Variable) ast.Variable {
+	for {
+		p, ok := x.parents[v]
+		if !ok {
+			return v
+		}
+		v = p
+	}
+}
+
+// declaration returns the declaration of the Variable that an identifier refers to.
+func (x *index) declaration(ident *ast.IdentExpr) (*declaration, bool) {
+	d, ok := x.decls[x.root(ident.Variable)]
+	return d, ok
+}
+
+// references returns all the identifiers that refer to the same Variable.
+func (x *index) references(ident *ast.IdentExpr) []*ast.IdentExpr {
+
+	v := x.root(ident.Variable)
+
+	refs := []*ast.IdentExpr{}
+	for _, id := range x.idents {
+		if x.root(id.Variable) == v {
+			refs = append(refs, id)
+		}
+	}
+	return refs
+}
+
+// declarationNamed finds the declaration of a symbol that is nearest to,
+// and before, the given line.
+func (x *index) declarationNamed(sym string, line int) (*declaration, bool) {
+
+	var result *declaration
+	for _, d := range x.decls {
+		if d.ident.Symbol.Text != sym {
+			continue
+		}
+		dl := d.ident.Symbol.Position.Line
+		switch {
+		case result == nil:
+			result = d
+		case dl <= line && (result.ident.Symbol.Position.Line > line || dl > result.ident.Symbol.Position.Line):
+			result = d
+		}
+	}
+	return result, result != nil
+}
+
+func (x *index) identAt(pos ast.Pos) (*ast.IdentExpr, bool) {
+	for _, id := range x.idents {
+		if containsPos(id.Symbol, pos) {
+			return id, true
+		}
+	}
+	return nil, false
+}
+
+func (x *index) builtinAt(pos ast.Pos) (*ast.Token, bool) {
+	for _, tok := range x.builtins {
+		if containsPos(tok, pos) {
+			return tok, true
+		}
+	}
+	return nil, false
+}
+
+func (x *index) fieldAt(pos ast.Pos) (*ast.FieldExpr, bool) {
+	for _, f := range x.fields {
+		if containsPos(f.Key, pos) {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// structAt returns the innermost struct literal that contains a position.
+func (x *index) structAt(pos ast.Pos) (*ast.StructExpr, bool) {
+
+	var result *ast.StructExpr
+	for _, stc := range x.structs {
+		if before(stc.LBrace.Position, pos) && before(pos, stc.RBrace.Position) {
+			if result == nil || before(result.LBrace.Position, stc.LBrace.Position) {
+				result = stc
+			}
+		}
+	}
+	return result, result != nil
+}
+
+func before(a ast.Pos, b ast.Pos) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Col < b.Col)
+}
@@ -0,0 +1,624 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package lsp implements a Language Server Protocol server for Golem,
+// so that editors can show errors, navigate between identifiers,
+// and complete code as it is typed.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mjarmy/golem-lang/ast"
+	g "github.com/mjarmy/golem-lang/core"
+	"github.com/mjarmy/golem-lang/internal/jsonrpc"
+	"github.com/mjarmy/golem-lang/parser"
+	"github.com/mjarmy/golem-lang/scanner"
+)
+
+// Server is a Language Server Protocol server for Golem source files.
+type Server struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	builtins map[string]g.Value
+	modules  map[string]g.Module
+
+	docs map[string]*document
+}
+
+// NewServer creates a new Server.  The builtins and modules are the ones
+// that are available to the programs that are being edited.
+func NewServer(
+	in io.Reader,
+	out io.Writer,
+	builtins []*g.Builtin,
+	modules []g.Module) *Server {
+
+	s := &Server{
+		in:       bufio.NewReader(in),
+		out:      out,
+		builtins: map[string]g.Value{},
+		modules:  map[string]g.Module{},
+		docs:     map[string]*document{},
+	}
+	for _, b := range builtins {
+		s.builtins[b.Name] = b.Value
+	}
+	for _, m := range modules {
+		s.modules[m.Name()] = m
+	}
+	return s
+}
+
+// Serve reads messages until the client sends 'exit', or the input is closed.
+func (s *Server) Serve() error {
+
+	for {
+		buf, err := jsonrpc.ReadMessage(s.in)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		var msg message
+		if err := json.Unmarshal(buf, &msg); err != nil {
+			return err
+		}
+
+		if msg.Method == "exit" {
+			return nil
+		}
+		s.handle(&msg)
+	}
+}
+
+func (s *Server) isBuiltin(name string) bool {
+	_, ok := s.builtins[name]
+	return ok
+}
+
+//---------------------------------------------------------------
+// Messages
+//---------------------------------------------------------------
+
+func (s *Server) handle(msg *message) {
+
+	switch msg.Method {
+
+	case "initialize":
+		s.respond(msg, map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"positionEncoding":   "utf-16",
+				"textDocumentSync":   1, // the full text is sent whenever it changes
+				"definitionProvider": true,
+				"referencesProvider": true,
+				"hoverProvider":      true,
+				"completionProvider": map[string]interface{}{
+					"triggerCharacters": []string{"."},
+				},
+			},
+			"serverInfo": map[string]interface{}{
+				"name": "golem",
+			},
+		})
+
+	case "shutdown":
+		s.respond(msg, nil)
+
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if s.decode(msg, &params) {
+			s.open(params.TextDocument.URI, params.TextDocument.Text)
+		}
+
+	case "textDocument/didChange":
+		var params didChangeParams
+		if s.decode(msg, &params) && len(params.ContentChanges) > 0 {
+			changes := params.ContentChanges
+			s.open(params.TextDocument.URI, changes[len(changes)-1].Text)
+		}
+
+	case "textDocument/didClose":
+		var params didCloseParams
+		if s.decode(msg, &params) {
+			delete(s.docs, params.TextDocument.URI)
+			s.notify("textDocument/publishDiagnostics", &publishDiagnosticsParams{
+				URI:         params.TextDocument.URI,
+				Diagnostics: []diagnostic{},
+			})
+		}
+
+	case "textDocument/definition":
+		var params textDocumentPositionParams
+		if s.decode(msg, &params) {
+			s.respond(msg, s.definition(params.TextDocument.URI, params.Position))
+		}
+
+	case "textDocument/references":
+		var params referenceParams
+		if s.decode(msg, &params) {
+			s.respond(msg, s.references(
+				params.TextDocument.URI, params.Position, params.Context.IncludeDeclaration))
+		}
+
+	case "textDocument/hover":
+		var params textDocumentPositionParams
+		if s.decode(msg, &params) {
+			s.respond(msg, s.hover(params.TextDocument.URI, params.Position))
+		}
+
+	case "textDocument/completion":
+		var params textDocumentPositionParams
+		if s.decode(msg, &params) {
+			s.respond(msg, s.completion(params.TextDocument.URI, params.Position))
+		}
+
+	default:
+		// notifications that we don't understand are ignored
+		if msg.ID != nil {
+			s.fail(msg, methodNotFound, fmt.Sprintf("Unsupported method '%s'", msg.Method))
+		}
+	}
+}
+
+func (s *Server) decode(msg *message, params interface{}) bool {
+	if err := json.Unmarshal(msg.Params, params); err != nil {
+		if msg.ID != nil {
+			s.fail(msg, invalidParams, err.Error())
+		}
+		return false
+	}
+	return true
+}
+
+func (s *Server) respond(msg *message, result interface{}) {
+	jsonrpc.WriteMessage(s.out, &response{
+		JSONRPC: "2.0",
+		ID:      msg.ID,
+		Result:  result,
+	})
+}
+
+func (s *Server) fail(msg *message, code int, text string) {
+	jsonrpc.WriteMessage(s.out, &response{
+		JSONRPC: "2.0",
+		ID:      msg.ID,
+		Result:  nil,
+		Error:   &responseError{code, text},
+	})
+}
+
+func (s *Server) notify(method string, params interface{}) {
+	jsonrpc.WriteMessage(s.out, &notification{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+}
+
+//---------------------------------------------------------------
+// Documents
+//---------------------------------------------------------------
+
+// open creates or updates a document, and publishes its diagnostics.
+func (s *Server) open(uri string, text string) {
+
+	doc, ok := s.docs[uri]
+	if !ok {
+		doc = &document{uri: uri, path: uriPath(uri)}
+		s.docs[uri] = doc
+	}
+	doc.update(text, s.isBuiltin)
+
+	s.notify("textDocument/publishDiagnostics", &publishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: doc.diagnostics,
+	})
+}
+
+func uriPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return u.Path
+}
+
+// indexed returns a document that has been indexed
+func (s *Server) indexed(uri string) (*document, bool) {
+	doc, ok := s.docs[uri]
+	if !ok || doc.index == nil {
+		return nil, false
+	}
+	return doc, true
+}
+
+//---------------------------------------------------------------
+// Navigation
+//---------------------------------------------------------------
+
+func (s *Server) definition(uri string, pos position) interface{} {
+
+	doc, ok := s.indexed(uri)
+	if !ok {
+		return nil
+	}
+	x := doc.index
+
+	ident, ok := x.identAt(doc.fromPosition(pos))
+	if !ok {
+		return nil
+	}
+
+	decl, ok := x.declaration(ident)
+	if !ok {
+		return nil
+	}
+	return &location{uri, doc.tokenRange(decl.ident.Symbol)}
+}
+
+func (s *Server) references(uri string, pos position, includeDecl bool) interface{} {
+
+	locs := []location{}
+
+	doc, ok := s.indexed(uri)
+	if !ok {
+		return locs
+	}
+	x := doc.index
+	ident, ok := x.identAt(doc.fromPosition(pos))
+	if !ok {
+		return locs
+	}
+	decl, hasDecl := x.declaration(ident)
+
+	for _, ref := range x.references(ident) {
+		if !includeDecl && hasDecl && ref == decl.ident {
+			continue
+		}
+		locs = append(locs, location{uri, doc.tokenRange(ref.Symbol)})
+	}
+	return locs
+}
+
+//---------------------------------------------------------------
+// Hover
+//---------------------------------------------------------------
+
+func (s *Server) hover(uri string, pos position) interface{} {
+
+	doc, ok := s.indexed(uri)
+	if !ok {
+		return nil
+	}
+	x := doc.index
+	p := doc.fromPosition(pos)
+
+	// a variable
+	if ident, ok := x.identAt(p); ok {
+		if decl, ok := x.declaration(ident); ok {
+			return doc.markdown(describeDecl(decl), ident.Symbol)
+		}
+		return nil
+	}
+
+	// a builtin function
+	if tok, ok := x.builtinAt(p); ok {
+		if fn, ok := s.builtins[tok.Text].(g.Func); ok {
+			return doc.markdown(describeFunc(tok.Text, fn), tok)
+		}
+		return nil
+	}
+
+	// a field of an imported module
+	if fe, ok := x.fieldAt(p); ok {
+		if ident, ok := fe.Operand.(*ast.IdentExpr); ok {
+			decl, ok := x.declaration(ident)
+			if !ok || !decl.isImport {
+				return nil
+			}
+			mod, ok := s.modules[ident.Symbol.Text]
+			if !ok {
+				return nil
+			}
+			val, err := mod.Contents().GetField(nil, fe.Key.Text)
+			if err != nil {
+				return nil
+			}
+			name := ident.Symbol.Text + "." + fe.Key.Text
+			if fn, ok := val.(g.Func); ok {
+				return doc.markdown(describeFunc(name, fn), fe.Key)
+			}
+			return doc.markdown(codeBlock(name), fe.Key)
+		}
+	}
+
+	return nil
+}
+
+func (doc *document) markdown(text string, tok *ast.Token) *hover {
+	return &hover{
+		Contents: markupContent{"markdown", text},
+		Range:    doc.tokenRange(tok),
+	}
+}
+
+func codeBlock(code string) string {
+	return "```golem\n" + code + "\n```"
+}
+
+func describeDecl(decl *declaration) string {
+
+	sym := decl.ident.Symbol.Text
+
+	if decl.isImport {
+		return codeBlock("import " + sym)
+	}
+
+	if fn, ok := decl.value.(*ast.FnExpr); ok {
+		return codeBlock("fn "+sym+signature(fn)) + "\n\n" + describeArity(fnArity(fn))
+	}
+
+	if decl.ident.Variable.IsConst() {
+		return codeBlock("const " + sym)
+	}
+	return codeBlock("let " + sym)
+}
+
+func describeFunc(name string, fn g.Func) string {
+	return codeBlock(name) + "\n\n" + describeArity(fn.Arity())
+}
+
+func describeArity(a g.Arity) string {
+
+	plural := func(n int) string {
+		if n == 1 {
+			return "1 parameter"
+		}
+		return fmt.Sprintf("%d parameters", n)
+	}
+
+	switch a.Kind {
+	case g.FixedArity:
+		return fmt.Sprintf("Takes %s.", plural(int(a.Required)))
+	case g.VariadicArity:
+		return fmt.Sprintf("Takes %s, or more.", plural(int(a.Required)))
+	case g.MultipleArity:
+		return fmt.Sprintf("Takes %d to %s.", a.Required, plural(int(a.Required+a.Optional)))
+	default:
+		panic("unreachable")
+	}
+}
+
+// signature returns the formal parameters of a function, e.g. '(a, b = 1, c...)'
+func signature(fn *ast.FnExpr) string {
+
+	params := []string{}
+	for _, p := range fn.Required {
+		params = append(params, p.Ident.Symbol.Text)
+	}
+	for _, p := range fn.Optional {
+		params = append(params, p.Ident.Symbol.Text+" = "+p.Value.Token.Text)
+	}
+	if fn.Variadic != nil {
+		params = append(params, fn.Variadic.Ident.Symbol.Text+"...")
+	}
+	return "(" + strings.Join(params, ", ") + ")"
+}
+
+func fnArity(fn *ast.FnExpr) g.Arity {
+
+	req := uint16(len(fn.Required))
+	opt := uint16(len(fn.Optional))
+
+	switch {
+	case fn.Variadic != nil:
+		return g.Arity{Kind: g.VariadicArity, Required: req, Optional: 0}
+	case opt > 0:
+		return g.Arity{Kind: g.MultipleArity, Required: req, Optional: opt}
+	default:
+		return g.Arity{Kind: g.FixedArity, Required: req, Optional: 0}
+	}
+}
+
+//---------------------------------------------------------------
+// Completion
+//---------------------------------------------------------------
+
+func (s *Server) completion(uri string, pos position) interface{} {
+
+	items := []completionItem{}
+
+	doc, ok := s.docs[uri]
+	if !ok {
+		return items
+	}
+
+	// Find the identifier, if any, that precedes a '.' before the cursor.
+	// The text is used rather than the AST, since the AST is usually out of
+	// date while the user is in the middle of typing a field.
+	if pos.Line >= len(doc.lines) {
+		return items
+	}
+	p := doc.fromPosition(pos)
+	text := doc.lines[pos.Line]
+	if p.Col-1 < len(text) {
+		text = text[:p.Col-1]
+	}
+	line := trimIdent([]rune(text))
+
+	// a field
+	if n := len(line); n > 0 && line[n-1] == '.' {
+		operand := string(trimIdentPrefix(line[:n-1]))
+		return s.fieldCompletion(doc, operand, p)
+	}
+
+	// builtins
+	for name, val := range s.builtins {
+		kind := kindVariable
+		if _, ok := val.(g.Func); ok {
+			kind = kindFunction
+		}
+		items = append(items, completionItem{name, kind, "builtin"})
+	}
+
+	// everything that has been declared in the document
+	if doc.index != nil {
+		seen := map[string]bool{}
+		for _, d := range doc.index.decls {
+			sym := d.ident.Symbol.Text
+			if seen[sym] {
+				continue
+			}
+			seen[sym] = true
+
+			switch {
+			case d.isImport:
+				items = append(items, completionItem{sym, kindModule, "module"})
+			default:
+				if fn, ok := d.value.(*ast.FnExpr); ok {
+					items = append(items, completionItem{sym, kindFunction, "fn" + signature(fn)})
+				} else {
+					items = append(items, completionItem{sym, kindVariable, ""})
+				}
+			}
+		}
+	}
+
+	return sortItems(items)
+}
+
+func (s *Server) fieldCompletion(doc *document, operand string, pos ast.Pos) []completionItem {
+
+	items := []completionItem{}
+	if doc.index == nil || operand == "" {
+		return items
+	}
+
+	// the fields of the enclosing struct
+	if operand == "this" {
+		if stc, ok := doc.index.structAt(pos); ok {
+			items = structFields(stc)
+		}
+		return sortItems(items)
+	}
+
+	decl, ok := doc.index.declarationNamed(operand, pos.Line)
+	if !ok {
+		return items
+	}
+
+	switch {
+	case decl.isImport:
+		items = s.moduleFields(doc, operand)
+	default:
+		if stc, ok := decl.value.(*ast.StructExpr); ok {
+			items = structFields(stc)
+		}
+	}
+	return sortItems(items)
+}
+
+func structFields(stc *ast.StructExpr) []completionItem {
+	items := []completionItem{}
+	for _, e := range stc.Entries {
+		detail := "field"
+		if fn, ok := e.Value.(*ast.FnExpr); ok {
+			detail = "fn" + signature(fn)
+		}
+		items = append(items, completionItem{e.Key.Text, kindField, detail})
+	}
+	return items
+}
+
+// moduleFields returns the fields of a module from the standard library, or
+// the top-level declarations of a module in the same directory as the document.
+func (s *Server) moduleFields(doc *document, name string) []completionItem {
+
+	items := []completionItem{}
+
+	if mod, ok := s.modules[name]; ok {
+		names, err := mod.Contents().FieldNames()
+		if err != nil {
+			return items
+		}
+		for _, n := range names {
+			kind := kindField
+			val, err := mod.Contents().GetField(nil, n)
+			if err == nil {
+				if _, ok := val.(g.Func); ok {
+					kind = kindFunction
+				}
+			}
+			items = append(items, completionItem{n, kind, name + "." + n})
+		}
+		return items
+	}
+
+	path := filepath.Join(filepath.Dir(doc.path), name+".glm")
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return items
+	}
+	scn, err := scanner.NewScanner(&scanner.Source{Name: name, Path: path, Code: string(buf)})
+	if err != nil {
+		return items
+	}
+	mod, err := parser.NewParser(scn, s.isBuiltin).ParseModule()
+	if err != nil {
+		return items
+	}
+
+	for _, st := range mod.InitFunc.Body.Statements {
+		switch t := st.(type) {
+		case *ast.NamedFnStmt:
+			items = append(items, completionItem{t.Ident.Symbol.Text, kindFunction, "fn" + signature(t.Func)})
+		case *ast.LetStmt:
+			for _, d := range t.Decls {
+				for _, ident := range d.Idents() {
+					items = append(items, completionItem{ident.Symbol.Text, kindField, "let"})
+				}
+			}
+		case *ast.ConstStmt:
+			for _, d := range t.Decls {
+				for _, ident := range d.Idents() {
+					items = append(items, completionItem{ident.Symbol.Text, kindField, "const"})
+				}
+			}
+		}
+	}
+	return items
+}
+
+func sortItems(items []completionItem) []completionItem {
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Label < items[j].Label
+	})
+	return items
+}
+
+// trimIdent removes a partially typed identifier from the end of a line.
+func trimIdent(line []rune) []rune {
+	return line[:len(line)-len(trimIdentPrefix(line))]
+}
+
+// trimIdentPrefix returns the identifier at the end of a line.
+func trimIdentPrefix(line []rune) []rune {
+	i := len(line)
+	for i > 0 && scanner.IsIdentContinue(line[i-1]) {
+		i--
+	}
+	return line[i:]
+}
@@ -0,0 +1,157 @@
+// Copyright 2018 The Golem Language Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mjarmy/golem-lang/ast"
+	g "github.com/mjarmy/golem-lang/core"
+	"github.com/mjarmy/golem-lang/internal/jsonrpc/jsonrpctest"
+	"github.com/mjarmy/golem-lang/lib"
+)
+
+const uri = "file:///tmp/golem/foo.glm"
+
+const text = `import regexp
+let a = 1
+fn add(x, y = 2) {
+    return x + y + a
+}
+let s = struct { name: 'bob', greet: fn() { return this.name; } }
+add(a)
+let z = q
+assert(len(s.name) == 3)
+let r = regexp.compile('a')
+`
+
+// A recorded session between an editor and the server.  Lines that start
+// with '->' are sent to the server, and lines that start with '<-' are the
+// messages we expect to receive.  The expected messages only have to match
+// the parts of the received messages that they mention.
+const session = `
+-> {"jsonrpc":"2.0","id":1,"method":"initialize","params":{"capabilities":{}}}
+<- {"id":1,"result":{"capabilities":{"definitionProvider":true,"referencesProvider":true,"hoverProvider":true}}}
+-> {"jsonrpc":"2.0","method":"initialized","params":{}}
+
+-> {"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"${uri}","languageId":"golem","version":1,"text":${text}}}}
+<- {"method":"textDocument/publishDiagnostics","params":{"uri":"${uri}","diagnostics":[{"range":{"start":{"line":7,"character":8},"end":{"line":7,"character":9}},"severity":1,"code":"undefined-symbol","message":"Symbol 'q' is not defined"}]}}
+
+-> {"jsonrpc":"2.0","id":2,"method":"textDocument/definition","params":{"textDocument":{"uri":"${uri}"},"position":{"line":3,"character":19}}}
+<- {"id":2,"result":{"uri":"${uri}","range":{"start":{"line":1,"character":4},"end":{"line":1,"character":5}}}}
+
+-> {"jsonrpc":"2.0","id":3,"method":"textDocument/references","params":{"textDocument":{"uri":"${uri}"},"position":{"line":1,"character":4},"context":{"includeDeclaration":true}}}
+<- {"id":3,"result":[{"range":{"start":{"line":1,"character":4}}},{"range":{"start":{"line":3,"character":19}}},{"range":{"start":{"line":6,"character":4}}}]}
+
+-> {"jsonrpc":"2.0","id":4,"method":"textDocument/references","params":{"textDocument":{"uri":"${uri}"},"position":{"line":6,"character":5},"context":{"includeDeclaration":false}}}
+<- {"id":4,"result":[{"range":{"start":{"line":3,"character":19}}},{"range":{"start":{"line":6,"character":4}}}]}
+
+-> {"jsonrpc":"2.0","id":5,"method":"textDocument/hover","params":{"textDocument":{"uri":"${uri}"},"position":{"line":6,"character":1}}}
+<- {"id":5,"result":{"contents":{"kind":"markdown","value":"` + "```golem\\nfn add(x, y = 2)\\n```\\n\\nTakes 1 to 2 parameters." + `"}}}
+
+-> {"jsonrpc":"2.0","id":6,"method":"textDocument/hover","params":{"textDocument":{"uri":"${uri}"},"position":{"line":8,"character":8}}}
+<- {"id":6,"result":{"contents":{"value":"` + "```golem\\nlen\\n```\\n\\nTakes 1 parameter." + `"}}}
+
+-> {"jsonrpc":"2.0","id":7,"method":"textDocument/hover","params":{"textDocument":{"uri":"${uri}"},"position":{"line":9,"character":16}}}
+<- {"id":7,"result":{"contents":{"value":"` + "```golem\\nregexp.compile\\n```\\n\\nTakes 1 parameter." + `"}}}
+
+-> {"jsonrpc":"2.0","id":8,"method":"textDocument/hover","params":{"textDocument":{"uri":"${uri}"},"position":{"line":4,"character":0}}}
+<- {"id":8,"result":null}
+
+-> {"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":"${uri}","version":2},"contentChanges":[{"text":${edited}}]}}
+<- {"method":"textDocument/publishDiagnostics","params":{"uri":"${uri}","diagnostics":[{"range":{"start":{"line":12,"character":0}},"message":"Unexpected EOF, expected identifier"}]}}
+
+-> {"jsonrpc":"2.0","id":9,"method":"textDocument/completion","params":{"textDocument":{"uri":"${uri}"},"position":{"line":10,"character":9}}}
+<- {"id":9,"result":[{"label":"compile","kind":3}]}
+
+-> {"jsonrpc":"2.0","id":10,"method":"textDocument/completion","params":{"textDocument":{"uri":"${uri}"},"position":{"line":11,"character":2}}}
+<- {"id":10,"result":[{"label":"greet","kind":5,"detail":"fn()"},{"label":"name","kind":5}]}
+
+-> {"jsonrpc":"2.0","id":11,"method":"textDocument/didClose","params":{"textDocument":{"uri":"${uri}"}}}
+<- {"method":"textDocument/publishDiagnostics","params":{"uri":"${uri}","diagnostics":[]}}
+
+-> {"jsonrpc":"2.0","id":12,"method":"shutdown"}
+<- {"id":12,"result":null}
+-> {"jsonrpc":"2.0","method":"exit"}
+`
+
+func TestSession(t *testing.T) {
+
+	builtins := g.SandboxBuiltins
+	edited := text + "regexp.co\ns.\n"
+
+	r := strings.NewReplacer(
+		"${uri}", uri,
+		"${text}", quote(text),
+		"${edited}", quote(edited))
+	jsonrpctest.Replay(t, r.Replace(session), func(in io.Reader, out io.Writer) error {
+		return NewServer(in, out, builtins, lib.SandboxLibrary).Serve()
+	})
+}
+
+func TestCompletion(t *testing.T) {
+
+	s := NewServer(nil, nil, g.SandboxBuiltins, nil)
+	s.docs[uri] = &document{uri: uri, path: uriPath(uri)}
+	s.docs[uri].update(text, s.isBuiltin)
+
+	labels := func(result interface{}) []string {
+		items := result.([]completionItem)
+		labels := []string{}
+		for _, item := range items {
+			labels = append(labels, item.Label)
+		}
+		return labels
+	}
+
+	// builtins and declarations
+	all := strings.Join(labels(s.completion(uri, position{6, 1})), " ")
+	for _, name := range []string{"add", "len", "regexp", "s", "x"} {
+		tassert(t, strings.Contains(" "+all+" ", " "+name+" "))
+	}
+
+	// fields of the enclosing struct
+	tassert(t, strings.Join(labels(s.completion(uri, position{5, 58})), " ") == "greet name")
+}
+
+func TestPositionEncoding(t *testing.T) {
+
+	// 'é' is two bytes and one UTF-16 code unit, and '😀' is four bytes
+	// and two UTF-16 code units
+	s := NewServer(nil, nil, g.SandboxBuiltins, nil)
+	doc := &document{uri: uri, path: uriPath(uri)}
+	s.docs[uri] = doc
+	doc.update("let é = '😀'; let x = q\nlet y = é + x\n", s.isBuiltin)
+
+	tassert(t, len(doc.diagnostics) == 1)
+	tassert(t, doc.diagnostics[0].Range == rng{position{0, 22}, position{0, 23}})
+
+	tassert(t, doc.fromPosition(position{0, 22}) == ast.Pos{Line: 1, Col: 26})
+	tassert(t, doc.toPosition(ast.Pos{Line: 1, Col: 26}) == position{0, 22})
+
+	loc := s.definition(uri, position{1, 12}).(*location)
+	tassert(t, loc.Range == rng{position{0, 18}, position{0, 19}})
+
+	loc = s.definition(uri, position{1, 8}).(*location)
+	tassert(t, loc.Range == rng{position{0, 4}, position{0, 5}})
+}
+
+func tassert(t *testing.T, flag bool) {
+	if !flag {
+		t.Error("assertion failure")
+		panic("tassert")
+	}
+}
+
+func quote(s string) string {
+	buf, err := json.Marshal(s)
+	if err != nil {
+		panic(err)
+	}
+	return string(buf)
+}
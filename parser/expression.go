@@ -6,12 +6,26 @@ package parser
 
 import (
 	"github.com/mjarmy/golem-lang/ast"
+	"github.com/mjarmy/golem-lang/scanner"
 )
 
 func (p *Parser) expression() ast.Expression {
 
 	exp := p.ternaryExpr()
 
+	switch exp.(type) {
+	case *ast.TupleExpr, *ast.ListExpr, *ast.BlankExpr:
+		if p.cur.token.Kind == ast.Eq {
+
+			// destructuring assignment
+			return &ast.DestructureExpr{
+				Pattern: p.toPattern(exp),
+				Eq:      p.expect(ast.Eq),
+				Val:     p.expression(),
+			}
+		}
+	}
+
 	if asn, ok := exp.(ast.Assignable); ok {
 
 		if p.cur.token.Kind == ast.Eq {
@@ -177,6 +191,14 @@ func (p *Parser) primaryExpr() ast.Expression {
 	prm := p.primary()
 
 	for {
+		// An invocation or an index must begin on the same line as its
+		// operand.  Otherwise a line that begins with a tuple or a list, like
+		// the destructuring assignment '(a, b) = (b, a)', would be parsed as
+		// a suffix of the previous line.
+		if p.cur.skipLF && (p.cur.token.Kind == ast.Lparen || p.cur.token.Kind == ast.Lbracket) {
+			return prm
+		}
+
 		// look for suffixes: Invoke, Select, Index, Slice
 		switch p.cur.token.Kind {
 
@@ -236,7 +258,7 @@ func (p *Parser) primaryExpr() ast.Expression {
 					}
 
 				default:
-					panic(p.unexpected())
+					panic(p.unexpected(describeAll(ast.Colon, ast.Rbracket)))
 				}
 			}
 
@@ -244,7 +266,7 @@ func (p *Parser) primaryExpr() ast.Expression {
 			p.expect(ast.Dot)
 			prm = &ast.FieldExpr{
 				Operand: prm,
-				Key:     p.expect(ast.Ident),
+				Key:     p.fieldKey(),
 			}
 
 		default:
@@ -271,7 +293,7 @@ func (p *Parser) primary() ast.Expression {
 			return p.tupleExpr(lparen, expr)
 
 		default:
-			panic(p.unexpected())
+			panic(p.unexpected(describeAll(ast.Comma, ast.Rparen)))
 		}
 
 	case p.cur.token.Kind == ast.Ident:
@@ -286,6 +308,11 @@ func (p *Parser) primary() ast.Expression {
 			return p.identExpr()
 		}
 
+	case p.cur.token.Kind == ast.BlankIdent:
+		return &ast.BlankExpr{
+			Token: p.consume().token,
+		}
+
 	case p.cur.token.Kind == ast.This:
 		return &ast.ThisExpr{
 			Token:    p.consume().token,
@@ -301,6 +328,9 @@ func (p *Parser) primary() ast.Expression {
 	case p.cur.token.Kind == ast.DoublePipe:
 		return p.lambdaZero()
 
+	case p.cur.token.Kind == ast.Go:
+		return p.goExpr()
+
 	case p.cur.token.Kind == ast.Struct:
 		return p.structExpr()
 
@@ -313,8 +343,60 @@ func (p *Parser) primary() ast.Expression {
 	case p.cur.token.Kind == ast.Lbracket:
 		return p.listExpr()
 
-	default:
+	case p.cur.token.Kind == ast.InterpBegin:
+		return p.interpStrExpr()
+
+	case p.cur.token.IsBasic():
 		return p.basicExpr()
+
+	default:
+		panic(p.unexpected("expression"))
+	}
+}
+
+func (p *Parser) interpStrExpr() *ast.InterpStrExpr {
+
+	tokens := []*ast.Token{p.expect(ast.InterpBegin)}
+	exprs := []ast.Expression{}
+
+	for {
+		exprs = append(exprs, p.expression())
+
+		switch p.cur.token.Kind {
+		case ast.InterpMiddle:
+			tokens = append(tokens, p.consume().token)
+
+		case ast.InterpEnd:
+			tokens = append(tokens, p.consume().token)
+			return &ast.InterpStrExpr{
+				Tokens: tokens,
+				Exprs:  exprs,
+			}
+
+		default:
+			panic(p.unexpected(describe(ast.Rbrace)))
+		}
+	}
+}
+
+func (p *Parser) goExpr() *ast.GoExpr {
+
+	token := p.expect(ast.Go)
+
+	prm := p.primary()
+	if p.cur.token.Kind != ast.Lparen {
+		panic(p.unexpected(describe(ast.Lparen)))
+	}
+	lparen, actual, rparen := p.actualParams()
+
+	return &ast.GoExpr{
+		Token: token,
+		Invocation: &ast.InvokeExpr{
+			Operand: prm,
+			LParen:  lparen,
+			Params:  actual,
+			RParen:  rparen,
+		},
 	}
 }
 
@@ -358,7 +440,7 @@ func (p *Parser) fnExpr(token *ast.Token) *ast.FnExpr {
 				})
 			} else {
 				if len(optional) > 0 {
-					panic(p.unexpected())
+					panic(p.unexpected(describe(ast.Eq)))
 				}
 
 				params = append(params, &ast.Param{
@@ -378,7 +460,7 @@ func (p *Parser) fnExpr(token *ast.Token) *ast.FnExpr {
 				})
 			} else {
 				if len(optional) > 0 {
-					panic(p.unexpected())
+					panic(p.unexpected(describe(ast.Eq)))
 				}
 
 				params = append(params, &ast.Param{
@@ -388,7 +470,7 @@ func (p *Parser) fnExpr(token *ast.Token) *ast.FnExpr {
 			}
 
 		default:
-			panic(p.unexpected())
+			panic(p.unexpected(describeAll(ast.Ident, ast.Const)))
 		}
 
 		switch p.cur.token.Kind {
@@ -400,7 +482,7 @@ func (p *Parser) fnExpr(token *ast.Token) *ast.FnExpr {
 		case ast.TripleDot:
 
 			if len(optional) > 0 {
-				panic(p.unexpected())
+				panic(p.unexpected(describeAll(ast.Comma, ast.Rparen)))
 			}
 
 			p.consume()
@@ -438,7 +520,7 @@ func (p *Parser) fnExpr(token *ast.Token) *ast.FnExpr {
 			}
 
 		default:
-			panic(p.unexpected())
+			panic(p.unexpected(describeAll(ast.Comma, ast.TripleDot, ast.Rparen)))
 		}
 	}
 
@@ -493,7 +575,7 @@ func (p *Parser) lambda() *ast.FnExpr {
 				break loop
 
 			default:
-				panic(p.unexpected())
+				panic(p.unexpected(describeAll(ast.Comma, ast.Pipe)))
 			}
 		}
 
@@ -501,7 +583,7 @@ func (p *Parser) lambda() *ast.FnExpr {
 		p.consume()
 
 	default:
-		panic(p.unexpected())
+		panic(p.unexpected(describeAll(ast.Ident, ast.Pipe)))
 	}
 
 	p.expect(ast.EqGt)
@@ -565,14 +647,14 @@ func (p *Parser) structExpr() ast.Expression {
 
 			entries = append(entries, entry)
 		default:
-			panic(p.unexpected())
+			panic(p.unexpected(describeAll(ast.Comma, ast.Rbrace)))
 		}
 	}
 }
 
 func (p *Parser) structEntry() *ast.StructEntry {
 
-	key := p.expect(ast.Ident)
+	key := p.fieldKey()
 
 	p.expect(ast.Colon)
 
@@ -589,6 +671,19 @@ func (p *Parser) structEntry() *ast.StructEntry {
 
 }
 
+// fieldKey parses the name of a field, which is either an identifier,
+// or the name of a magic field.
+func (p *Parser) fieldKey() *ast.Token {
+
+	if p.cur.token.Kind == ast.MagicField {
+		if !scanner.IsMagicField(p.cur.token.Text) {
+			panic(newParserError(p.scn.Source.Path, invalidMagicField, p.cur.token))
+		}
+		return p.consume().token
+	}
+	return p.expect(ast.Ident)
+}
+
 func (p *Parser) property() *ast.PropNode {
 
 	token := p.expect(ast.Prop)
@@ -630,7 +725,7 @@ func (p *Parser) propertyFunc() *ast.FnExpr {
 		return p.lambda()
 
 	default:
-		panic(p.unexpected())
+		panic(p.unexpected(describeAll(ast.Fn, ast.Pipe, ast.DoublePipe)))
 	}
 }
 
@@ -662,7 +757,7 @@ func (p *Parser) dictExpr() ast.Expression {
 			p.consume()
 			entries = append(entries, p.dictEntry())
 		default:
-			panic(p.unexpected())
+			panic(p.unexpected(describeAll(ast.Comma, ast.Rbrace)))
 		}
 	}
 }
@@ -705,7 +800,7 @@ func (p *Parser) setExpr() ast.Expression {
 			p.consume()
 			elems = append(elems, p.expression())
 		default:
-			panic(p.unexpected())
+			panic(p.unexpected(describeAll(ast.Comma, ast.Rbrace)))
 		}
 	}
 }
@@ -722,7 +817,7 @@ func (p *Parser) listExpr() ast.Expression {
 		}
 	}
 
-	elems := []ast.Expression{p.expression()}
+	elems := []ast.Expression{p.listElem()}
 	for {
 		switch p.cur.token.Kind {
 		case ast.Rbracket:
@@ -733,13 +828,32 @@ func (p *Parser) listExpr() ast.Expression {
 			}
 		case ast.Comma:
 			p.consume()
-			elems = append(elems, p.expression())
+			elems = append(elems, p.listElem())
 		default:
-			panic(p.unexpected())
+			panic(p.unexpected(describeAll(ast.Comma, ast.Rbracket)))
 		}
 	}
 }
 
+// listElem parses an element of a list literal.  The element can be a
+// RestExpr, in case the list turns out to be a destructuring assignment.
+func (p *Parser) listElem() ast.Expression {
+
+	if p.cur.token.Kind != ast.TripleDot {
+		return p.expression()
+	}
+
+	tripleDot := p.consume().token
+	switch p.cur.token.Kind {
+	case ast.Ident:
+		return &ast.RestExpr{TripleDot: tripleDot, Rest: p.identExpr()}
+	case ast.BlankIdent:
+		return &ast.RestExpr{TripleDot: tripleDot, Rest: &ast.BlankExpr{Token: p.consume().token}}
+	default:
+		panic(p.unexpected(describeAll(ast.Ident, ast.BlankIdent)))
+	}
+}
+
 func (p *Parser) tupleExpr(lparen *ast.Token, expr ast.Expression) ast.Expression {
 
 	elems := []ast.Expression{expr, p.expression()}
@@ -762,7 +876,7 @@ func (p *Parser) tupleExpr(lparen *ast.Token, expr ast.Expression) ast.Expressio
 			p.consume()
 			elems = append(elems, p.expression())
 		default:
-			panic(p.unexpected())
+			panic(p.unexpected(describeAll(ast.Comma, ast.Rparen)))
 		}
 	}
 }
@@ -780,7 +894,7 @@ func (p *Parser) basicExpr() *ast.BasicExpr {
 		}
 
 	default:
-		panic(p.unexpected())
+		panic(p.unexpected("literal value"))
 	}
 }
 
@@ -807,7 +921,7 @@ func (p *Parser) actualParams() (*ast.Token, []ast.Expression, *ast.Token) {
 				return lparen, params, p.consume().token
 
 			default:
-				panic(p.unexpected())
+				panic(p.unexpected(describeAll(ast.Comma, ast.Rparen)))
 			}
 
 		}
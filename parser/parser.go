@@ -5,8 +5,6 @@
 package parser
 
 import (
-	"runtime"
-
 	"github.com/mjarmy/golem-lang/ast"
 	"github.com/mjarmy/golem-lang/scanner"
 )
@@ -21,7 +19,9 @@ type Parser struct {
 	isBuiltIn     func(string) bool
 	cur           tokenInfo
 	next          tokenInfo
+	ahead         []tokenInfo // tokens past 'next' that lookahead() has scanned
 	iterIDCounter int
+	errors        ast.Diagnostics
 }
 
 type tokenInfo struct {
@@ -31,21 +31,33 @@ type tokenInfo struct {
 
 // NewParser creates a new Parser
 func NewParser(scn *scanner.Scanner, isBuiltIn func(string) bool) *Parser {
-	return &Parser{scn, isBuiltIn, tokenInfo{}, tokenInfo{}, 0}
+	return &Parser{scn, isBuiltIn, tokenInfo{}, tokenInfo{}, nil, 0, nil}
 }
 
-// ParseModule parses a Golem module
+// The maximum number of errors that the parser will report for a module.
+const maxErrors = 10
+
+// bailout is used to stop parsing altogether.
+type bailout struct{}
+
+// ParseModule parses a Golem module.
+//
+// When the parser finds a syntax error, it skips ahead to the beginning of the
+// next statement and keeps going, so that it can report as many errors as possible.
+// If there were any errors, they are returned as ast.Diagnostics.
 func (p *Parser) ParseModule() (mod *ast.Module, err error) {
 
 	// In a recursive descent parser, errors can be generated deep
 	// in the call stack.  We are going to use panic-recover to handle them.
+	// Each statement recovers from its own errors -- see parseStatement().
 	defer func() {
 		if r := recover(); r != nil {
-			if _, ok := r.(runtime.Error); ok {
+			if _, ok := r.(bailout); !ok {
 				panic(r)
 			}
-			mod = nil
-			err = r.(error)
+		}
+		if len(p.errors) > 0 {
+			mod, err = nil, p.errors
 		}
 	}()
 
@@ -98,7 +110,7 @@ func (p *Parser) expect(kind ast.TokenKind) *ast.Token {
 		p.consume()
 		return result
 	}
-	panic(p.unexpected())
+	panic(p.unexpected(describe(kind)))
 }
 
 func (p *Parser) expectStatementDelimiter() {
@@ -111,7 +123,7 @@ func (p *Parser) expectStatementDelimiter() {
 		// nothing to do
 		return
 	default:
-		panic(p.unexpected())
+		panic(p.unexpected("end of statement"))
 	}
 }
 
@@ -138,6 +150,34 @@ func (p *Parser) consume() tokenInfo {
 
 func (p *Parser) advance() tokenInfo {
 
+	if len(p.ahead) > 0 {
+		result := p.ahead[0]
+		p.ahead = p.ahead[1:]
+		return result
+	}
+	return p.scan()
+}
+
+// lookahead returns the token that is n tokens past the current one,
+// without consuming anything.
+func (p *Parser) lookahead(n int) *ast.Token {
+
+	switch n {
+	case 0:
+		return p.cur.token
+	case 1:
+		return p.next.token
+	}
+
+	for len(p.ahead) < n-1 {
+		p.ahead = append(p.ahead, p.scan())
+	}
+	return p.ahead[n-2].token
+}
+
+// scan reads the next token from the scanner
+func (p *Parser) scan() tokenInfo {
+
 	token := p.scn.Next()
 	skipLF := false
 
@@ -151,11 +191,14 @@ func (p *Parser) advance() tokenInfo {
 	if token.IsBad() {
 		switch token.Kind {
 
+		// the scanner cannot continue past a bad token
 		case ast.UnexpectedChar:
-			panic(newParserError(p.scn.Source.Path, unexpectedChar, token))
+			p.addError(newParserError(p.scn.Source.Path, unexpectedChar, token))
+			panic(bailout{})
 
 		case ast.UnexpectedEOF:
-			panic(newParserError(p.scn.Source.Path, unexpectedEOF, token))
+			p.addError(newParserError(p.scn.Source.Path, unexpectedEOF, token))
+			panic(bailout{})
 
 		default:
 			panic("unreachable")
@@ -166,16 +209,160 @@ func (p *Parser) advance() tokenInfo {
 	return tokenInfo{token, skipLF}
 }
 
-// create a error that we will panic with
-func (p *Parser) unexpected() error {
+// create a error that we will panic with, for an unexpected current token.
+// The error describes what was expected instead.
+func (p *Parser) unexpected(expected string) *parserError {
+
+	var err *parserError
 	switch p.cur.token.Kind {
 	case ast.EOF:
-		return newParserError(p.scn.Source.Path, unexpectedEOF, p.cur.token)
+		err = newParserError(p.scn.Source.Path, unexpectedEOF, p.cur.token)
 
 	case ast.Reserved:
-		return newParserError(p.scn.Source.Path, unexpectedReservedWord, p.cur.token)
+		err = newParserError(p.scn.Source.Path, unexpectedReservedWord, p.cur.token)
+
+	case ast.InterpMiddle, ast.InterpEnd:
+		// report the closing brace of the interpolation,
+		// rather than the text that follows it
+		err = newParserError(p.scn.Source.Path, unexpectedToken, &ast.Token{
+			Kind:     ast.Rbrace,
+			Text:     "}",
+			Position: p.cur.token.Position,
+		})
 
 	default:
-		return newParserError(p.scn.Source.Path, unexpectedToken, p.cur.token)
+		err = newParserError(p.scn.Source.Path, unexpectedToken, p.cur.token)
+	}
+
+	err.expected = expected
+	return err
+}
+
+//---------------------------------------------------------------
+// Error recovery
+//---------------------------------------------------------------
+
+// parseStatement parses a statement.  If the statement has a syntax error,
+// the error is recorded, and the parser skips ahead to the beginning of
+// the next statement.  In that case, nil is returned.
+func (p *Parser) parseStatement(parse func() ast.Statement) (stmt ast.Statement) {
+
+	start := p.cur.token
+
+	defer func() {
+		if r := recover(); r != nil {
+			err, ok := r.(*parserError)
+			if !ok {
+				panic(r)
+			}
+			p.addError(err)
+			p.synchronize(start)
+			stmt = nil
+		}
+	}()
+
+	return parse()
+}
+
+// addError records an error.  Only the first error on any given line
+// is recorded, since the others are usually caused by that one.
+func (p *Parser) addError(err *parserError) {
+
+	n := len(p.errors)
+	if n == 0 || p.errors[n-1].Start.Line != err.token.Position.Line {
+		p.errors = append(p.errors, err.toDiagnostic())
+	}
+
+	// there is nothing left to recover at the end of the file
+	if err.token.Kind == ast.EOF || len(p.errors) >= maxErrors {
+		panic(bailout{})
+	}
+}
+
+// synchronize skips ahead to the beginning of the next statement, which
+// is after the next statement delimiter, or at the closing brace of the
+// enclosing block.  Any nested blocks are skipped entirely.
+func (p *Parser) synchronize(start *ast.Token) {
+
+	// make sure we always make progress
+	if p.cur.token == start {
+		p.consume()
+	}
+
+	depth := 0
+	for {
+		switch {
+		case p.cur.token.Kind == ast.EOF:
+			return
+
+		case depth == 0 && p.cur.skipLF:
+			return
+
+		case depth == 0 && p.cur.token.Kind == ast.Semicolon:
+			p.consume()
+			return
+
+		case p.cur.token.Kind == ast.Lbrace:
+			depth++
+
+		case p.cur.token.Kind == ast.Rbrace:
+			if depth == 0 {
+				return
+			}
+			depth--
+		}
+		p.consume()
+	}
+}
+
+// parseClause parses a 'case' or 'default' clause of a 'switch' or 'select'
+// statement.  If the clause has a syntax error, the error is recorded, and
+// the parser skips ahead to the next clause, or to the closing brace of
+// the statement.
+func (p *Parser) parseClause(parse func()) {
+
+	start := p.cur.token
+
+	defer func() {
+		if r := recover(); r != nil {
+			err, ok := r.(*parserError)
+			if !ok {
+				panic(r)
+			}
+			p.addError(err)
+			p.synchronizeClause(start)
+		}
+	}()
+
+	parse()
+}
+
+// synchronizeClause skips ahead to the next 'case' or 'default' clause, or to
+// the closing brace of the enclosing statement.  Any nested blocks are
+// skipped entirely.
+func (p *Parser) synchronizeClause(start *ast.Token) {
+
+	depth := 0
+	for {
+		switch p.cur.token.Kind {
+		case ast.EOF:
+			return
+
+		case ast.Case, ast.Default:
+			// make sure we always make progress
+			if depth == 0 && p.cur.token != start {
+				return
+			}
+
+		case ast.Lbrace:
+			depth++
+
+		case ast.Rbrace:
+			if depth == 0 {
+				return
+			}
+			depth--
+		}
+		p.consume()
 	}
 }
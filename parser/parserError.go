@@ -6,6 +6,7 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/mjarmy/golem-lang/ast"
 )
@@ -20,16 +21,23 @@ const (
 	invalidPostfix
 	invalidFor
 	invalidSwitch
+	invalidSelect
 	invalidTry
+	invalidDefer
 	invalidPropertyGetter
 	invalidPropertySetter
 	duplicateKey
+	invalidMagicField
+	invalidPattern
 )
 
 type parserError struct {
 	path  string
 	kind  parserErrorKind
 	token *ast.Token
+
+	// a description of what was expected instead of the token, if known
+	expected string
 }
 
 func newParserError(path string, kind parserErrorKind, token *ast.Token) *parserError {
@@ -37,43 +45,158 @@ func newParserError(path string, kind parserErrorKind, token *ast.Token) *parser
 }
 
 func (e *parserError) Error() string {
+	return e.toDiagnostic().Error()
+}
+
+func (e *parserError) toDiagnostic() *ast.Diagnostic {
+	return ast.NewDiagnostic(e.path, e.token, errorCodes[e.kind], e.message())
+}
+
+var errorCodes = map[parserErrorKind]string{
+	unexpectedChar:         "unexpected-char",
+	unexpectedToken:        "unexpected-token",
+	unexpectedReservedWord: "unexpected-reserved-word",
+	unexpectedEOF:          "unexpected-eof",
+	invalidPostfix:         "invalid-postfix",
+	invalidFor:             "invalid-for",
+	invalidSwitch:          "invalid-switch",
+	invalidSelect:          "invalid-select",
+	invalidTry:             "invalid-try",
+	invalidDefer:           "invalid-defer",
+	invalidPropertyGetter:  "invalid-property-getter",
+	invalidPropertySetter:  "invalid-property-setter",
+	duplicateKey:           "duplicate-key",
+	invalidMagicField:      "invalid-magic-field",
+	invalidPattern:         "invalid-pattern",
+}
+
+func (e *parserError) message() string {
 
 	switch e.kind {
 
 	case unexpectedChar:
-		return fmt.Sprintf("Unexpected Character '%v' at %s:%v", e.token.Text, e.path, e.token.Position)
+		return fmt.Sprintf("Unexpected Character '%v'", e.token.Text)
 
 	case unexpectedToken:
-		return fmt.Sprintf("Unexpected Token '%v' at %s:%v", e.token.Text, e.path, e.token.Position)
+		return fmt.Sprintf("Unexpected Token '%v'", e.token.Text) + e.expecting()
 
 	case unexpectedReservedWord:
-		return fmt.Sprintf("Unexpected Reserved Word '%v' at %s:%v", e.token.Text, e.path, e.token.Position)
+		return fmt.Sprintf("Unexpected Reserved Word '%v'", e.token.Text) + e.expecting()
 
 	case unexpectedEOF:
-		return fmt.Sprintf("Unexpected EOF at %s:%v", e.path, e.token.Position)
+		return "Unexpected EOF" + e.expecting()
 
 	case invalidPostfix:
-		return fmt.Sprintf("Invalid Postfix Expression at %s:%v", e.path, e.token.Position)
+		return "Invalid Postfix Expression"
 
 	case invalidFor:
-		return fmt.Sprintf("Invalid ForStmt Expression at %s:%v", e.path, e.token.Position)
+		return "Invalid ForStmt Expression"
 
 	case invalidSwitch:
-		return fmt.Sprintf("Invalid SwitchStmt Expression at %s:%v", e.path, e.token.Position)
+		return "Invalid SwitchStmt Expression"
+
+	case invalidSelect:
+		return "Invalid SelectStmt Expression"
 
 	case invalidTry:
-		return fmt.Sprintf("Invalid Try Expression at %s:%v", e.path, e.token.Position)
+		return "Invalid Try Expression"
+
+	case invalidDefer:
+		return "Invalid Defer Expression"
 
 	case invalidPropertyGetter:
-		return fmt.Sprintf("Invalid Property Getter at %s:%v", e.path, e.token.Position)
+		return "Invalid Property Getter"
 
 	case invalidPropertySetter:
-		return fmt.Sprintf("Invalid Property Setter at %s:%v", e.path, e.token.Position)
+		return "Invalid Property Setter"
 
 	case duplicateKey:
-		return fmt.Sprintf("Duplicate Key at %s:%v", e.path, e.token.Position)
+		return "Duplicate Key"
+
+	case invalidMagicField:
+		return fmt.Sprintf("Invalid Magic Field '%v'", e.token.Text)
+
+	case invalidPattern:
+		return "Invalid Pattern"
 
 	default:
 		panic("unreachable")
 	}
 }
+
+func (e *parserError) expecting() string {
+	if e.expected == "" {
+		return ""
+	}
+	return ", expected " + e.expected
+}
+
+// describe returns a description of a kind of token, for use in error messages.
+func describe(kind ast.TokenKind) string {
+	switch kind {
+	case ast.EOF:
+		return "end of file"
+	case ast.Ident:
+		return "identifier"
+	}
+	if text, ok := tokenText[kind]; ok {
+		return "'" + text + "'"
+	}
+	return kind.String()
+}
+
+// describeAll returns a description of several kinds of token, any one of
+// which would be valid, for use in error messages.
+func describeAll(kinds ...ast.TokenKind) string {
+	n := len(kinds) - 1
+	descs := make([]string, n)
+	for i, kind := range kinds[:n] {
+		descs[i] = describe(kind)
+	}
+	return strings.Join(descs, ", ") + " or " + describe(kinds[n])
+}
+
+var tokenText = map[ast.TokenKind]string{
+	ast.Lparen:     "(",
+	ast.Rparen:     ")",
+	ast.Lbrace:     "{",
+	ast.Rbrace:     "}",
+	ast.Lbracket:   "[",
+	ast.Rbracket:   "]",
+	ast.Colon:      ":",
+	ast.Comma:      ",",
+	ast.TripleDot:  "...",
+	ast.BlankIdent: "_",
+	ast.Dot:        ".",
+	ast.Eq:         "=",
+	ast.EqGt:       "=>",
+	ast.Pipe:       "|",
+	ast.DoublePipe: "||",
+
+	ast.Break:    "break",
+	ast.Case:     "case",
+	ast.Catch:    "catch",
+	ast.Const:    "const",
+	ast.Continue: "continue",
+	ast.Default:  "default",
+	ast.Defer:    "defer",
+	ast.Dict:     "dict",
+	ast.Finally:  "finally",
+	ast.Fn:       "fn",
+	ast.For:      "for",
+	ast.Go:       "go",
+	ast.If:       "if",
+	ast.Import:   "import",
+	ast.In:       "in",
+	ast.Let:      "let",
+	ast.Prop:     "prop",
+	ast.Return:   "return",
+	ast.Select:   "select",
+	ast.Set:      "set",
+	ast.Struct:   "struct",
+	ast.Switch:   "switch",
+	ast.Throw:    "throw",
+	ast.Try:      "try",
+	ast.While:    "while",
+	ast.Yield:    "yield",
+}
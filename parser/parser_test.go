@@ -7,6 +7,7 @@ package parser
 import (
 	"reflect"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/mjarmy/golem-lang/ast"
@@ -116,7 +117,12 @@ func parseExpression(p *Parser) (expr ast.Expression, err error) {
 				panic(r)
 			}
 			expr = nil
-			err = r.(error)
+			if _, ok := r.(bailout); ok {
+				// the scanner found a bad token
+				err = p.errors[0]
+			} else {
+				err = r.(error)
+			}
 		}
 	}()
 
@@ -134,7 +140,7 @@ func parseExpression(p *Parser) (expr ast.Expression, err error) {
 func TestPrimary(t *testing.T) {
 
 	p := newParser("")
-	failExpr(t, p, "Unexpected EOF at foo.glm:1:1")
+	failExpr(t, p, "Unexpected EOF, expected expression at foo.glm:1:1")
 
 	p = newParser("#")
 	failExpr(t, p, "Unexpected Character '#' at foo.glm:1:1")
@@ -143,10 +149,10 @@ func TestPrimary(t *testing.T) {
 	failExpr(t, p, "Unexpected EOF at foo.glm:1:2")
 
 	p = newParser("1 2")
-	failExpr(t, p, "Unexpected Token '2' at foo.glm:1:3")
+	failExpr(t, p, "Unexpected Token '2', expected end of file at foo.glm:1:3")
 
 	p = newParser("a == goto")
-	failExpr(t, p, "Unexpected Reserved Word 'goto' at foo.glm:1:6")
+	failExpr(t, p, "Unexpected Reserved Word 'goto', expected expression at foo.glm:1:6")
 
 	p = newParser("1 #")
 	failExpr(t, p, "Unexpected Character '#' at foo.glm:1:3")
@@ -215,13 +221,13 @@ func TestTernary(t *testing.T) {
 	okExpr(t, p, "((a || b) ? (b = c) : (d ? e : f))")
 
 	p = newParser("a ?")
-	failExpr(t, p, "Unexpected EOF at foo.glm:1:4")
+	failExpr(t, p, "Unexpected EOF, expected expression at foo.glm:1:4")
 
 	p = newParser("a ? b")
-	failExpr(t, p, "Unexpected EOF at foo.glm:1:6")
+	failExpr(t, p, "Unexpected EOF, expected ':' at foo.glm:1:6")
 
 	p = newParser("a ? b :")
-	failExpr(t, p, "Unexpected EOF at foo.glm:1:8")
+	failExpr(t, p, "Unexpected EOF, expected expression at foo.glm:1:8")
 }
 
 func TestMultiplicative(t *testing.T) {
@@ -273,7 +279,7 @@ func TestAdditive(t *testing.T) {
 	okExpr(t, p, "(1 ^ (2 % 3))")
 
 	p = newParser("1 +")
-	failExpr(t, p, "Unexpected EOF at foo.glm:1:4")
+	failExpr(t, p, "Unexpected EOF, expected expression at foo.glm:1:4")
 }
 
 func TestAssign(t *testing.T) {
@@ -398,10 +404,10 @@ func TestFor(t *testing.T) {
 	ok(t, p, "fn() { for (a, b, c) in d {  }; }")
 
 	p = newParser("for a b")
-	fail(t, p, "Unexpected Token 'b' at foo.glm:1:7")
+	fail(t, p, "Unexpected Token 'b', expected 'in' at foo.glm:1:7")
 
 	p = newParser("for in")
-	fail(t, p, "Unexpected Token 'in' at foo.glm:1:5")
+	fail(t, p, "Unexpected Token 'in', expected identifier or '(' at foo.glm:1:5")
 
 	p = newParser("for (a) in c {}")
 	fail(t, p, "Invalid ForStmt Expression at foo.glm:1:5")
@@ -448,17 +454,26 @@ func TestFn(t *testing.T) {
 	ok(t, p, "fn() { fn(const a, b) { (a = 1); }; }")
 
 	p = newParser("return;")
-	fail(t, p, "Unexpected Token ';' at foo.glm:1:7")
+	fail(t, p, "Unexpected Token ';', expected expression at foo.glm:1:7")
 
 }
 
+func TestYield(t *testing.T) {
+
+	p := newParser("fn() { yield a; }")
+	ok(t, p, "fn() { fn() { yield a; }; }")
+
+	p = newParser("fn() { yield a + b; yield; }")
+	fail(t, p, "Unexpected Token ';', expected expression at foo.glm:1:26")
+}
+
 func TestTry(t *testing.T) {
 
 	p := newParser("throw a;")
 	ok(t, p, "fn() { throw a; }")
 
 	p = newParser("throw;")
-	fail(t, p, "Unexpected Token ';' at foo.glm:1:6")
+	fail(t, p, "Unexpected Token ';', expected expression at foo.glm:1:6")
 
 	p = newParser("try { a; } catch e { b; };")
 	ok(t, p, "fn() { try { a; } catch e { b; }; }")
@@ -466,11 +481,17 @@ func TestTry(t *testing.T) {
 	p = newParser("try { a; } catch e { b; } finally { c; };")
 	ok(t, p, "fn() { try { a; } catch e { b; } finally { c; }; }")
 
+	p = newParser("try { a; } catch e if e.kind == 'Foo' { b; };")
+	ok(t, p, "fn() { try { a; } catch e if (e.kind == 'Foo') { b; }; }")
+
+	p = newParser("try { a; } catch e if { b; };")
+	fail(t, p, "Unexpected Token '{', expected expression at foo.glm:1:23")
+
 	p = newParser("try { a; } finally { c; };")
 	ok(t, p, "fn() { try { a; } finally { c; }; }")
 
 	p = newParser("try;")
-	fail(t, p, "Unexpected Token ';' at foo.glm:1:4")
+	fail(t, p, "Unexpected Token ';', expected '{' at foo.glm:1:4")
 
 	p = newParser("try {}")
 	fail(t, p, "Invalid Try Expression at foo.glm:1:1")
@@ -522,7 +543,7 @@ func TestStruct(t *testing.T) {
 	okExpr(t, p, "(a.b = 3)")
 
 	p = newParser("let a.b = 3;")
-	fail(t, p, "Unexpected Token '.' at foo.glm:1:6")
+	fail(t, p, "Unexpected Token '.', expected ',' or end of statement at foo.glm:1:6")
 
 	p = newParser("this")
 	okExpr(t, p, "this")
@@ -555,7 +576,7 @@ func TestStruct(t *testing.T) {
 	okExpr(t, p, "(a = (struct { x: 8 }.x = 5))")
 
 	p = newParser("this = b")
-	fail(t, p, "Unexpected Token '=' at foo.glm:1:6")
+	fail(t, p, "Unexpected Token '=', expected end of statement at foo.glm:1:6")
 
 	////////////
 
@@ -577,7 +598,7 @@ func TestStruct(t *testing.T) {
 	okExpr(t, p, "struct { a: prop { fn() { x; }, fn(y) { y; } } }")
 
 	p = newParser("struct { a: prop { || => x, } }")
-	fail(t, p, "Unexpected Token '}' at foo.glm:1:29")
+	fail(t, p, "Unexpected Token '}', expected 'fn', '|' or '||' at foo.glm:1:29")
 
 	p = newParser("struct { a: prop { || => x, || => y} }")
 	fail(t, p, "Invalid Property Setter at foo.glm:1:29")
@@ -589,7 +610,27 @@ func TestStruct(t *testing.T) {
 	fail(t, p, "Invalid Property Setter at foo.glm:1:29")
 
 	p = newParser("struct { $foo: 1 }")
+	fail(t, p, "Invalid Magic Field '$foo' at foo.glm:1:10")
+
+	p = newParser("struct { $: 1 }")
 	fail(t, p, "Unexpected Character '$' at foo.glm:1:10")
+
+	////////////
+
+	p = newParser("struct { x: 1, $add: |v| => this.x + v.x }")
+	okExpr(t, p, "struct { x: 1, $add: fn(v) { (this.x + v.x); } }")
+
+	p = newParser("struct { $eq: 1, $eq: 2 }")
+	fail(t, p, "Duplicate Key at foo.glm:1:18")
+
+	p = newParser("a.$toStr()")
+	okExpr(t, p, "a.$toStr()")
+
+	p = newParser("a.$bar")
+	fail(t, p, "Invalid Magic Field '$bar' at foo.glm:1:3")
+
+	p = newParser("$len")
+	fail(t, p, "Unexpected Token '$len', expected expression at foo.glm:1:1")
 }
 
 func TestPrimarySuffix(t *testing.T) {
@@ -611,17 +652,17 @@ func TestPrimarySuffix(t *testing.T) {
 	p = newParser("a[:b]")
 	okExpr(t, p, "a[:b]")
 	p = newParser("a[:]")
-	fail(t, p, "Unexpected Token ']' at foo.glm:1:4")
+	fail(t, p, "Unexpected Token ']', expected expression at foo.glm:1:4")
 
 	p = newParser("a[b:]")
 	okExpr(t, p, "a[b:]")
 	p = newParser("a[b:}")
-	fail(t, p, "Unexpected Token '}' at foo.glm:1:5")
+	fail(t, p, "Unexpected Token '}', expected expression at foo.glm:1:5")
 
 	p = newParser("a[b:c]")
 	okExpr(t, p, "a[b:c]")
 	p = newParser("a[b:c:]")
-	fail(t, p, "Unexpected Token ':' at foo.glm:1:6")
+	fail(t, p, "Unexpected Token ':', expected ']' at foo.glm:1:6")
 
 	p = newParser("a[b][c[:x]].d[y:].e().f[g[i:j]]")
 	okExpr(t, p, "a[b][c[:x]].d[y:].e().f[g[i:j]]")
@@ -728,6 +769,9 @@ fn() {
 
 	p = newParser("if 0 {} else {};")
 	okPos(t, p, ast.Pos{Line: 1, Col: 1}, ast.Pos{Line: 1, Col: 15})
+
+	p = newParser("`a${b}cd`")
+	okExprPos(t, p, ast.Pos{Line: 1, Col: 1}, ast.Pos{Line: 1, Col: 9})
 }
 
 func TestList(t *testing.T) {
@@ -799,6 +843,102 @@ func TestTuple(t *testing.T) {
 	okExpr(t, p, "(a, b, struct { z: 1 })[2]")
 }
 
+func TestInterpStr(t *testing.T) {
+
+	p := newParser("`a${b}c`")
+	okExpr(t, p, "`a${b}c`")
+
+	p = newParser("`${a + 1}, ${ f(b) }`")
+	okExpr(t, p, "`${(a + 1)}, ${f(b)}`")
+
+	p = newParser("`a${`b${c}`}`")
+	okExpr(t, p, "`a${`b${c}`}`")
+
+	p = newParser("`a${ struct { b: 1 }.b }`")
+	okExpr(t, p, "`a${struct { b: 1 }.b}`")
+
+	p = newParser("`a${}`")
+	failExpr(t, p, "Unexpected Token '}', expected expression at foo.glm:1:5")
+
+	p = newParser("`a${b c}`")
+	failExpr(t, p, "Unexpected Token 'c', expected '}' at foo.glm:1:7")
+
+	p = newParser("`a${b}")
+	failExpr(t, p, "Unexpected EOF at foo.glm:1:7")
+}
+
+func TestDestructure(t *testing.T) {
+
+	p := newParser("let (a, b) = c")
+	ok(t, p, "fn() { let (a, b) = c; }")
+
+	p = newParser("const [a, _, ...b] = c, d = 1")
+	ok(t, p, "fn() { const [ a, _, ...b ] = c, d = 1; }")
+
+	p = newParser("let [...b] = c")
+	ok(t, p, "fn() { let [ ...b ] = c; }")
+
+	p = newParser("let [] = c")
+	ok(t, p, "fn() { let [  ] = c; }")
+
+	p = newParser("let { a, b: (c, [d, _]) } = e")
+	ok(t, p, "fn() { let { a: a, b: (c, [ d, _ ]) } = e; }")
+
+	p = newParser("let _ = c")
+	ok(t, p, "fn() { let _ = c; }")
+
+	p = newParser("(a, b) = (b, a)")
+	okExpr(t, p, "((a, b) = (b, a))")
+
+	p = newParser("[a, (b, _)] = c")
+	okExpr(t, p, "([ a, (b, _) ] = c)")
+
+	p = newParser("[a, ...b] = c")
+	okExpr(t, p, "([ a, ...b ] = c)")
+
+	p = newParser("[..._] = c")
+	okExpr(t, p, "([ ..._ ] = c)")
+
+	p = newParser("_ = c")
+	okExpr(t, p, "(_ = c)")
+
+	p = newParser("x = (a, b) = c")
+	okExpr(t, p, "(x = ((a, b) = c))")
+
+	p = newParser(`
+let a = b
+(a, b) = (b, a)
+[a, b] = [b, a]`)
+	ok(t, p, "fn() { let a = b; ((a, b) = (b, a)); ([ a, b ] = [ b, a ]); }")
+
+	p = newParser("let (a) = c")
+	fail(t, p, "Invalid Pattern at foo.glm:1:5")
+
+	p = newParser("let (a, b)")
+	fail(t, p, "Unexpected EOF, expected '=' at foo.glm:1:11")
+
+	p = newParser("let [...a, b] = c")
+	fail(t, p, "Unexpected Token ',', expected ']' at foo.glm:1:10")
+
+	p = newParser("let { a, a: b } = c")
+	fail(t, p, "Duplicate Key at foo.glm:1:10")
+
+	p = newParser("let (a, 1) = c")
+	fail(t, p, "Unexpected Token '1', expected pattern at foo.glm:1:9")
+
+	p = newParser("(a, 1) = c")
+	fail(t, p, "Invalid Pattern at foo.glm:1:1")
+
+	p = newParser("[a.b, c] = d")
+	fail(t, p, "Invalid Pattern at foo.glm:1:1")
+
+	p = newParser("[...a, b] = d")
+	fail(t, p, "Invalid Pattern at foo.glm:1:1")
+
+	p = newParser("[...a.b] = d")
+	fail(t, p, "Unexpected Token '.', expected ',' or ']' at foo.glm:1:6")
+}
+
 func TestSwitch(t *testing.T) {
 
 	p := newParser("switch { case a: x; };")
@@ -817,19 +957,19 @@ func TestSwitch(t *testing.T) {
 	ok(t, p, "fn() { switch { case a: x; case b: y; default: z; }; }")
 
 	p = newParser("switch { }")
-	fail(t, p, "Unexpected Token '}' at foo.glm:1:10")
+	fail(t, p, "Unexpected Token '}', expected 'case' at foo.glm:1:10")
 
 	p = newParser("switch { case a: x;")
-	fail(t, p, "Unexpected EOF at foo.glm:1:20")
+	fail(t, p, "Unexpected EOF, expected expression at foo.glm:1:20")
 
 	p = newParser("switch { default: x; }")
-	fail(t, p, "Unexpected Token 'default' at foo.glm:1:10")
+	fail(t, p, "Unexpected Token 'default', expected 'case' at foo.glm:1:10")
 
 	p = newParser("switch { case case a: x; }")
-	fail(t, p, "Unexpected Token 'case' at foo.glm:1:15")
+	fail(t, p, "Unexpected Token 'case', expected expression at foo.glm:1:15")
 
 	p = newParser("switch { case z, x; }")
-	fail(t, p, "Unexpected Token ';' at foo.glm:1:19")
+	fail(t, p, "Unexpected Token ';', expected ',' or ':' at foo.glm:1:19")
 
 	p = newParser("switch { case a, b, c: }")
 	fail(t, p, "Invalid SwitchStmt Expression at foo.glm:1:22")
@@ -838,6 +978,91 @@ func TestSwitch(t *testing.T) {
 	fail(t, p, "Invalid SwitchStmt Expression at foo.glm:1:28")
 }
 
+func TestSwitchPatterns(t *testing.T) {
+
+	p := newParser("switch x { case 1, -2.5, a.b: y; }")
+	ok(t, p, "fn() { switch x { case 1, -2.5, a.b: y; }; }")
+
+	p = newParser("switch x { case Int, Str, null: y; }")
+	ok(t, p, "fn() { switch x { case Int, Str, null: y; }; }")
+
+	p = newParser("switch x { case (a, _) if a > 0: y; case _: z; }")
+	ok(t, p, "fn() { switch x { case (a, _) if (a > 0): y; case _: z; }; }")
+
+	p = newParser("switch x { case [1, Int, -3, ...a]: y; }")
+	ok(t, p, "fn() { switch x { case [ 1, Int, -3, ...a ]: y; }; }")
+
+	p = newParser("switch x { case { a, b: 'z', c: [_, d] }: y; }")
+	ok(t, p, "fn() { switch x { case { a: a, b: 'z', c: [ _, d ] }: y; }; }")
+
+	p = newParser("switch x { case 1 if a: y; }")
+	ok(t, p, "fn() { switch x { case 1 if a: y; }; }")
+
+	// a parenthesized case without a comma is an expression, not a tuple
+	p = newParser("switch x { case (2 + 3): y; case (z): w; default: v; }")
+	ok(t, p, "fn() { switch x { case (2 + 3): y; case z: w; default: v; }; }")
+
+	p = newParser("switch x { case (_, 1), (f(b, c) + 1): y; }")
+	ok(t, p, "fn() { switch x { case (_, 1), (f(b, c) + 1): y; }; }")
+
+	p = newParser("switch { case a if b: y; }")
+	fail(t, p, "Unexpected Token 'if', expected ',' or ':' at foo.glm:1:17")
+
+	p = newParser("switch x { case (a, _), (_, a): y; }")
+	fail(t, p, "Invalid Pattern at foo.glm:1:12")
+
+	p = newParser("switch x { case (Int, -a): y; }")
+	fail(t, p, "Unexpected Token '-', expected pattern at foo.glm:1:23")
+
+	p = newParser("switch x { case (a.b, c): y; }")
+	fail(t, p, "Unexpected Token '.', expected ')' at foo.glm:1:19")
+
+	p = newParser("let (Int, 1) = x")
+	fail(t, p, "Unexpected Token '1', expected pattern at foo.glm:1:11")
+}
+
+func TestSelect(t *testing.T) {
+
+	p := newParser("select { case a.recv(): x; };")
+	ok(t, p, "fn() { select { case a.recv(): x; }; }")
+
+	p = newParser("select { case b = a.recv(): x; y; };")
+	ok(t, p, "fn() { select { case b = a.recv(): x; y; }; }")
+
+	p = newParser("select { case a.recv(): x; case b.send(c): y; };")
+	ok(t, p, "fn() { select { case a.recv(): x; case b.send(c): y; }; }")
+
+	p = newParser("select { case a.recv(): x; default: y; };")
+	ok(t, p, "fn() { select { case a.recv(): x; default: y; }; }")
+
+	p = newParser("select { case z[0].recv(): x; case b = c.d.recv(): y; default: z; };")
+	ok(t, p, "fn() { select { case z[0].recv(): x; case b = c.d.recv(): y; default: z; }; }")
+
+	p = newParser("select { }")
+	fail(t, p, "Unexpected Token '}', expected 'case' at foo.glm:1:10")
+
+	p = newParser("select { default: x; }")
+	fail(t, p, "Unexpected Token 'default', expected 'case' at foo.glm:1:10")
+
+	p = newParser("select { case a: x; }")
+	fail(t, p, "Invalid SelectStmt Expression at foo.glm:1:10")
+
+	p = newParser("select { case a(): x; }")
+	fail(t, p, "Invalid SelectStmt Expression at foo.glm:1:10")
+
+	p = newParser("select { case a.recv(1): x; }")
+	fail(t, p, "Invalid SelectStmt Expression at foo.glm:1:10")
+
+	p = newParser("select { case a.send(): x; }")
+	fail(t, p, "Invalid SelectStmt Expression at foo.glm:1:10")
+
+	p = newParser("select { case b = a.send(c): x; }")
+	fail(t, p, "Invalid SelectStmt Expression at foo.glm:1:10")
+
+	p = newParser("select { case a.recv(): }")
+	fail(t, p, "Invalid SelectStmt Expression at foo.glm:1:23")
+}
+
 func TestLambda(t *testing.T) {
 
 	p := newParser("|| => true")
@@ -865,7 +1090,34 @@ func TestSpawn(t *testing.T) {
 	ok(t, p, "fn() { go false(a, b, c); }")
 
 	p = newParser("go foo;")
-	fail(t, p, "Unexpected Token ';' at foo.glm:1:7")
+	fail(t, p, "Unexpected Token ';', expected '(' at foo.glm:1:7")
+
+	p = newParser("let a = go foo(b);")
+	ok(t, p, "fn() { let a = go foo(b); }")
+
+	p = newParser("go foo().join()")
+	okExpr(t, p, "go foo().join()")
+
+	p = newParser("[go foo(), go bar()]")
+	okExpr(t, p, "[ go foo(), go bar() ]")
+}
+
+func TestDefer(t *testing.T) {
+
+	p := newParser("defer foo();")
+	ok(t, p, "fn() { defer foo(); }")
+
+	p = newParser("defer a.b(c, d);")
+	ok(t, p, "fn() { defer a.b(c, d); }")
+
+	p = newParser("defer foo()(1);")
+	ok(t, p, "fn() { defer foo()(1); }")
+
+	p = newParser("defer foo;")
+	fail(t, p, "Invalid Defer Expression at foo.glm:1:1")
+
+	p = newParser("defer a.b;")
+	fail(t, p, "Invalid Defer Expression at foo.glm:1:1")
 }
 
 func TestImport(t *testing.T) {
@@ -887,7 +1139,7 @@ func TestImport(t *testing.T) {
 	tassert(t, reflect.DeepEqual([]string{"a", "b", "a", "c"}, mod.Imports()))
 
 	p = newParser("let z = 3; import a;")
-	fail(t, p, "Unexpected Token 'import' at foo.glm:1:12")
+	fail(t, p, "Unexpected Token 'import', expected expression at foo.glm:1:12")
 }
 
 func TestLookaheadLF(t *testing.T) {
@@ -1024,13 +1276,13 @@ func TestArity(t *testing.T) {
 	okExpr(t, p, "fn(const a, b, const c...) {  }")
 
 	p = newParser("fn(a..., b) {}")
-	fail(t, p, "Unexpected Token ',' at foo.glm:1:8")
+	fail(t, p, "Unexpected Token ',', expected ')' at foo.glm:1:8")
 
 	p = newParser("fn(a..., b = 1) {}")
-	fail(t, p, "Unexpected Token ',' at foo.glm:1:8")
+	fail(t, p, "Unexpected Token ',', expected ')' at foo.glm:1:8")
 
 	p = newParser("fn(a, b = 1, c...) {}")
-	fail(t, p, "Unexpected Token '...' at foo.glm:1:15")
+	fail(t, p, "Unexpected Token '...', expected '=' at foo.glm:1:15")
 
 	p = newParser("fn(a, b, c = 1) {}")
 	okExpr(t, p, "fn(a, b, c = 1) {  }")
@@ -1039,8 +1291,93 @@ func TestArity(t *testing.T) {
 	okExpr(t, p, "fn(const c = 1) {  }")
 
 	p = newParser("fn(a, b = 1, c, d) {}")
-	fail(t, p, "Unexpected Token ',' at foo.glm:1:15")
+	fail(t, p, "Unexpected Token ',', expected '=' at foo.glm:1:15")
 
 	p = newParser("fn(a = 1, b) {}")
-	fail(t, p, "Unexpected Token ')' at foo.glm:1:12")
+	fail(t, p, "Unexpected Token ')', expected '=' at foo.glm:1:12")
+}
+
+func TestErrorRecovery(t *testing.T) {
+
+	p := newParser(`
+let a = 1 2
+let b = 3
+fn f(x) {
+    let y = )
+    return x
+}
+if a { b = ; }
+let c = 4
+let d = [1, 2
+`)
+	fail(t, p, strings.Join([]string{
+		"Unexpected Token '2', expected ',' or end of statement at foo.glm:2:11",
+		"Unexpected Token ')', expected expression at foo.glm:5:13",
+		"Unexpected Token ';', expected expression at foo.glm:8:12",
+		"Unexpected EOF, expected ',' or ']' at foo.glm:11:1",
+	}, "\n"))
+
+	// only the first error on a line is reported
+	p = newParser("let a = ); let b = ); let c = )")
+	fail(t, p, "Unexpected Token ')', expected expression at foo.glm:1:9")
+
+	// errors in imports
+	p = newParser("import a b\nimport c\nlet d = )")
+	fail(t, p, "Unexpected Token 'b', expected ',' or end of statement at foo.glm:1:10\n"+
+		"Unexpected Token ')', expected expression at foo.glm:3:9")
+
+	// the scanner cannot recover from a bad token
+	p = newParser("let a = )\nlet b = $\nlet c = )")
+	fail(t, p, "Unexpected Token ')', expected expression at foo.glm:1:9\n"+
+		"Unexpected Character '$' at foo.glm:2:9")
+
+	// the errors are available individually
+	p = newParser("let a = )\nlet b = 'xyz' 3")
+	_, err := p.ParseModule()
+	ds, ok := err.(ast.Diagnostics)
+	if !ok || len(ds) != 2 {
+		t.Fatalf("expected Diagnostics, got %v", err)
+	}
+	expect := ast.Diagnostic{
+		Path:     "foo.glm",
+		Start:    ast.Pos{Line: 2, Col: 15},
+		End:      ast.Pos{Line: 2, Col: 16},
+		Severity: ast.SeverityError,
+		Code:     "unexpected-token",
+		Msg:      "Unexpected Token '3', expected ',' or end of statement",
+	}
+	if *ds[1] != expect {
+		t.Errorf("%#v != %#v", *ds[1], expect)
+	}
+
+	// an error in a case clause skips ahead to the next clause
+	p = newParser(`
+switch x {
+case (2 + :
+    a()
+case 3:
+    b()
+default:
+    c()
+}
+let y = 1
+`)
+	fail(t, p, "Unexpected Token ':', expected expression at foo.glm:3:11")
+
+	p = newParser(`
+select {
+case a.recv(:
+    x()
+case b.send(1):
+    y()
+}
+switch {
+case true if false:
+    z()
+}
+`)
+	fail(t, p, strings.Join([]string{
+		"Unexpected Token ':', expected expression at foo.glm:3:13",
+		"Unexpected Token 'if', expected ',' or ':' at foo.glm:9:11",
+	}, "\n"))
 }
@@ -8,49 +8,53 @@ import (
 	"fmt"
 
 	"github.com/mjarmy/golem-lang/ast"
+	"github.com/mjarmy/golem-lang/scanner"
 )
 
 func (p *Parser) imports() []ast.Statement {
 
 	stmts := []ast.Statement{}
 
-	for {
-		if p.cur.token.Kind != ast.Import {
-			break
+	for p.cur.token.Kind == ast.Import {
+		if stmt := p.parseStatement(p.importStmt); stmt != nil {
+			stmts = append(stmts, stmt)
 		}
+	}
 
-		tok := p.expect(ast.Import)
+	return stmts
+}
 
-		idents := []*ast.IdentExpr{}
-		idents = append(idents, &ast.IdentExpr{
-			Symbol:   p.expect(ast.Ident),
-			Variable: nil,
-		})
+func (p *Parser) importStmt() ast.Statement {
 
-	loop:
-		for {
-			switch {
-			case p.cur.token.Kind == ast.Comma:
-				p.consume()
-				idents = append(idents, &ast.IdentExpr{
-					Symbol:   p.expect(ast.Ident),
-					Variable: nil,
-				})
-			case p.atStatementDelimiter():
-				break loop
-			default:
-				panic(p.unexpected())
-			}
-		}
+	tok := p.expect(ast.Import)
 
-		p.expectStatementDelimiter()
-		stmts = append(stmts, &ast.ImportStmt{
-			Token:  tok,
-			Idents: idents,
-		})
+	idents := []*ast.IdentExpr{}
+	idents = append(idents, &ast.IdentExpr{
+		Symbol:   p.expect(ast.Ident),
+		Variable: nil,
+	})
+
+loop:
+	for {
+		switch {
+		case p.cur.token.Kind == ast.Comma:
+			p.consume()
+			idents = append(idents, &ast.IdentExpr{
+				Symbol:   p.expect(ast.Ident),
+				Variable: nil,
+			})
+		case p.atStatementDelimiter():
+			break loop
+		default:
+			panic(p.unexpected(describe(ast.Comma) + " or end of statement"))
+		}
 	}
 
-	return stmts
+	p.expectStatementDelimiter()
+	return &ast.ImportStmt{
+		Token:  tok,
+		Idents: idents,
+	}
 }
 
 // Parse a sequence of statements or expressions.
@@ -63,7 +67,9 @@ func (p *Parser) statements(endKind ast.TokenKind) []ast.Statement {
 			return stmts
 		}
 
-		stmts = append(stmts, p.statement())
+		if stmt := p.parseStatement(p.statement); stmt != nil {
+			stmts = append(stmts, stmt)
+		}
 	}
 
 }
@@ -80,7 +86,9 @@ func (p *Parser) statementsAny(endKinds ...ast.TokenKind) []ast.Statement {
 			}
 		}
 
-		stmts = append(stmts, p.statement())
+		if stmt := p.parseStatement(p.statement); stmt != nil {
+			stmts = append(stmts, stmt)
+		}
 	}
 }
 
@@ -118,6 +126,9 @@ func (p *Parser) statement() ast.Statement {
 	case ast.Switch:
 		return p.switchStmt()
 
+	case ast.Select:
+		return p.selectStmt()
+
 	case ast.Break:
 		return p.breakStmt()
 
@@ -127,14 +138,17 @@ func (p *Parser) statement() ast.Statement {
 	case ast.Return:
 		return p.returnStmt()
 
+	case ast.Yield:
+		return p.yieldStmt()
+
 	case ast.Throw:
 		return p.throwStmt()
 
 	case ast.Try:
 		return p.tryStmt()
 
-	case ast.Go:
-		return p.goStmt()
+	case ast.Defer:
+		return p.deferStmt()
 
 	default:
 		// we couldn't find a statement to parse, so parse an expression instead
@@ -175,7 +189,7 @@ func (p *Parser) constStmt() *ast.ConstStmt {
 				Decls: decls,
 			}
 		default:
-			panic(p.unexpected())
+			panic(p.unexpected(describe(ast.Comma) + " or end of statement"))
 		}
 	}
 }
@@ -197,13 +211,23 @@ func (p *Parser) letStmt() *ast.LetStmt {
 				Decls: decls,
 			}
 		default:
-			panic(p.unexpected())
+			panic(p.unexpected(describe(ast.Comma) + " or end of statement"))
 		}
 	}
 }
 
 func (p *Parser) decl() *ast.DeclNode {
 
+	switch p.cur.token.Kind {
+	case ast.Lparen, ast.Lbracket, ast.Lbrace, ast.BlankIdent:
+		pattern := p.pattern(false)
+		p.expect(ast.Eq)
+		return &ast.DeclNode{
+			Pattern: pattern,
+			Val:     p.expression(),
+		}
+	}
+
 	ident := &ast.IdentExpr{
 		Symbol:   p.expect(ast.Ident),
 		Variable: nil,
@@ -220,6 +244,205 @@ func (p *Parser) decl() *ast.DeclNode {
 	}
 }
 
+//--------------------------------------------------------------
+// patterns
+
+// pattern parses a Pattern.  A refutable pattern is one that might not
+// match a value, i.e. a pattern in a 'switch' statement.  Only refutable
+// patterns can contain literals and type names.
+func (p *Parser) pattern(refutable bool) ast.Pattern {
+
+	switch p.cur.token.Kind {
+
+	case ast.Ident:
+		if refutable && scanner.IsTypeName(p.cur.token.Text) {
+			return &ast.TypePattern{Token: p.consume().token}
+		}
+		return p.identExpr()
+
+	case ast.BlankIdent:
+		return &ast.BlankExpr{Token: p.consume().token}
+
+	case ast.Lparen:
+		return p.tuplePattern(refutable)
+
+	case ast.Lbracket:
+		return p.listPattern(refutable)
+
+	case ast.Lbrace:
+		return p.structPattern(refutable)
+
+	case ast.Minus:
+		if refutable {
+			switch p.next.token.Kind {
+			case ast.Int, ast.Float:
+				return &ast.ValuePattern{
+					Val: &ast.UnaryExpr{
+						Op:      p.consume().token,
+						Operand: p.basicExpr(),
+					},
+				}
+			}
+		}
+		panic(p.unexpected("pattern"))
+
+	default:
+		if refutable && p.cur.token.IsBasic() {
+			return &ast.ValuePattern{Val: p.basicExpr()}
+		}
+		panic(p.unexpected("pattern"))
+	}
+}
+
+func (p *Parser) tuplePattern(refutable bool) *ast.TuplePattern {
+
+	lparen := p.expect(ast.Lparen)
+	elems := []ast.Pattern{p.pattern(refutable)}
+
+	for p.accept(ast.Comma) {
+		elems = append(elems, p.pattern(refutable))
+	}
+	rparen := p.expect(ast.Rparen)
+
+	// tuples always have at least 2 elements
+	if len(elems) < 2 {
+		panic(newParserError(p.scn.Source.Path, invalidPattern, lparen))
+	}
+
+	return &ast.TuplePattern{
+		LParen: lparen,
+		Elems:  elems,
+		RParen: rparen,
+	}
+}
+
+func (p *Parser) listPattern(refutable bool) *ast.ListPattern {
+
+	lbracket := p.expect(ast.Lbracket)
+	elems := []ast.Pattern{}
+	var rest ast.Pattern
+
+	for p.cur.token.Kind != ast.Rbracket {
+
+		if len(elems) > 0 {
+			p.expect(ast.Comma)
+		}
+
+		// the rest of the list must always be last
+		if p.accept(ast.TripleDot) {
+			switch p.cur.token.Kind {
+			case ast.Ident:
+				rest = p.identExpr()
+			case ast.BlankIdent:
+				rest = &ast.BlankExpr{Token: p.consume().token}
+			default:
+				panic(p.unexpected(describeAll(ast.Ident, ast.BlankIdent)))
+			}
+			break
+		}
+
+		elems = append(elems, p.pattern(refutable))
+	}
+
+	return &ast.ListPattern{
+		LBracket: lbracket,
+		Elems:    elems,
+		Rest:     rest,
+		RBracket: p.expect(ast.Rbracket),
+	}
+}
+
+func (p *Parser) structPattern(refutable bool) *ast.StructPattern {
+
+	lbrace := p.expect(ast.Lbrace)
+	entries := []*ast.StructPatternEntry{}
+	names := make(map[string]bool)
+
+	for {
+		key := p.expect(ast.Ident)
+		if names[key.Text] {
+			panic(newParserError(p.scn.Source.Path, duplicateKey, key))
+		}
+		names[key.Text] = true
+
+		// a key by itself is shorthand for 'key: key'
+		var pattern ast.Pattern
+		if p.accept(ast.Colon) {
+			pattern = p.pattern(refutable)
+		} else {
+			pattern = &ast.IdentExpr{Symbol: key, Variable: nil}
+		}
+		entries = append(entries, &ast.StructPatternEntry{Key: key, Pattern: pattern})
+
+		if !p.accept(ast.Comma) {
+			break
+		}
+	}
+
+	return &ast.StructPattern{
+		LBrace:  lbrace,
+		Entries: entries,
+		RBrace:  p.expect(ast.Rbrace),
+	}
+}
+
+// toPattern turns the left hand side of a destructuring assignment into a
+// Pattern.  Only tuples and lists of identifiers (or of other such tuples
+// and lists) can be assigned to.
+func (p *Parser) toPattern(exp ast.Expression) ast.Pattern {
+
+	switch t := exp.(type) {
+
+	case *ast.BlankExpr:
+		return t
+
+	case *ast.TupleExpr:
+		return &ast.TuplePattern{
+			LParen: t.LParen,
+			Elems:  p.toPatterns(t.LParen, t.Elems),
+			RParen: t.RParen,
+		}
+
+	case *ast.ListExpr:
+		elems := t.Elems
+		var rest ast.Pattern
+
+		// the rest of the list must always be last
+		if n := len(elems); n > 0 {
+			if r, ok := elems[n-1].(*ast.RestExpr); ok {
+				elems = elems[:n-1]
+				rest = r.Rest
+			}
+		}
+
+		return &ast.ListPattern{
+			LBracket: t.LBracket,
+			Elems:    p.toPatterns(t.LBracket, elems),
+			Rest:     rest,
+			RBracket: t.RBracket,
+		}
+
+	default:
+		panic("unreachable")
+	}
+}
+
+func (p *Parser) toPatterns(open *ast.Token, elems []ast.Expression) []ast.Pattern {
+
+	patterns := make([]ast.Pattern, len(elems))
+	for i, e := range elems {
+		switch t := e.(type) {
+		case *ast.IdentExpr:
+			patterns[i] = t
+		case *ast.BlankExpr, *ast.TupleExpr, *ast.ListExpr:
+			patterns[i] = p.toPattern(t)
+		default:
+			panic(newParserError(p.scn.Source.Path, invalidPattern, open))
+		}
+	}
+	return patterns
+}
+
 func (p *Parser) ifStmt() *ast.IfStmt {
 
 	token := p.expect(ast.If)
@@ -251,7 +474,7 @@ func (p *Parser) ifStmt() *ast.IfStmt {
 			return result
 
 		default:
-			panic(p.unexpected())
+			panic(p.unexpected(describeAll(ast.Lbrace, ast.If)))
 		}
 
 	} else {
@@ -291,7 +514,7 @@ func (p *Parser) forStmt() *ast.ForStmt {
 		idents = p.tupleIdents()
 
 	default:
-		panic(p.unexpected())
+		panic(p.unexpected(describeAll(ast.Ident, ast.Lparen)))
 	}
 
 	// parse 'in'
@@ -349,7 +572,7 @@ func (p *Parser) tupleIdents() []*ast.IdentExpr {
 				break loop
 
 			default:
-				panic(p.unexpected())
+				panic(p.unexpected(describeAll(ast.Comma, ast.Rparen)))
 			}
 		}
 
@@ -357,7 +580,7 @@ func (p *Parser) tupleIdents() []*ast.IdentExpr {
 		p.consume()
 
 	default:
-		panic(p.unexpected())
+		panic(p.unexpected(describeAll(ast.Ident, ast.Rparen)))
 	}
 
 	if len(idents) < 2 {
@@ -378,15 +601,22 @@ func (p *Parser) switchStmt() *ast.SwitchStmt {
 	lbrace := p.expect(ast.Lbrace)
 
 	// cases
-	cases := []*ast.CaseNode{p.caseStmt()}
-	for p.cur.token.Kind == ast.Case {
-		cases = append(cases, p.caseStmt())
+	cases := []*ast.CaseNode{}
+	for {
+		p.parseClause(func() {
+			cases = append(cases, p.caseStmt(item != nil))
+		})
+		if p.cur.token.Kind != ast.Case {
+			break
+		}
 	}
 
 	// default
 	var def *ast.DefaultNode
 	if p.cur.token.Kind == ast.Default {
-		def = p.defaultStmt()
+		p.parseClause(func() {
+			def = p.defaultStmt()
+		})
 	}
 
 	// done
@@ -402,7 +632,11 @@ func (p *Parser) switchStmt() *ast.SwitchStmt {
 	return result
 }
 
-func (p *Parser) caseStmt() *ast.CaseNode {
+func (p *Parser) caseStmt(hasItem bool) *ast.CaseNode {
+
+	if hasItem {
+		return p.patternCaseStmt()
+	}
 
 	token := p.expect(ast.Case)
 
@@ -424,12 +658,135 @@ func (p *Parser) caseStmt() *ast.CaseNode {
 				Token:   token,
 				Matches: matches,
 				Body:    body,
+				Scope:   ast.NewScope(),
 			}
 
 		default:
-			panic(p.unexpected())
+			panic(p.unexpected(describeAll(ast.Comma, ast.Colon)))
+		}
+	}
+}
+
+// patternCaseStmt parses a 'case' clause in a 'switch' that has an item.
+// The clause can match the item against patterns, and can have a guard.
+func (p *Parser) patternCaseStmt() *ast.CaseNode {
+
+	token := p.expect(ast.Case)
+
+	patterns := []ast.Pattern{p.casePattern()}
+	for p.accept(ast.Comma) {
+		patterns = append(patterns, p.casePattern())
+	}
+
+	var guard ast.Expression
+	if p.accept(ast.If) {
+		guard = p.expression()
+	}
+
+	colon := p.expect(ast.Colon)
+	body := p.statementsAny(ast.Case, ast.Default, ast.Rbrace)
+	if len(body) == 0 {
+		panic(newParserError(p.scn.Source.Path, invalidSwitch, colon))
+	}
+
+	// A case that only compares the item against some values is an
+	// ordinary case, that has Matches rather than Patterns.
+	if guard == nil {
+		if matches, ok := patternValues(patterns); ok {
+			return &ast.CaseNode{
+				Token:   token,
+				Matches: matches,
+				Body:    body,
+				Scope:   ast.NewScope(),
+			}
+		}
+	}
+
+	// The same identifiers would have to be assigned by each
+	// of the alternatives, so we do not allow that.
+	if len(patterns) > 1 {
+		for _, pt := range patterns {
+			if len(ast.PatternIdents(pt)) > 0 {
+				panic(newParserError(p.scn.Source.Path, invalidPattern, token))
+			}
+		}
+	}
+
+	return &ast.CaseNode{
+		Token:    token,
+		Patterns: patterns,
+		Guard:    guard,
+		Body:     body,
+		Scope:    ast.NewScope(),
+	}
+}
+
+// casePattern parses one of the alternatives in a 'case' clause.  An
+// alternative that does not begin like a pattern is an expression, which
+// matches any value that is equal to it.
+func (p *Parser) casePattern() ast.Pattern {
+
+	switch p.cur.token.Kind {
+
+	case ast.Lparen:
+		if p.isTuplePattern() {
+			return p.pattern(true)
+		}
+
+	case ast.Lbracket, ast.Lbrace, ast.BlankIdent:
+		return p.pattern(true)
+
+	case ast.Ident:
+		if scanner.IsTypeName(p.cur.token.Text) {
+			return p.pattern(true)
+		}
+	}
+
+	return &ast.ValuePattern{Val: p.expression()}
+}
+
+// isTuplePattern reports whether the current token opens a parenthesized
+// list that has a comma in it, like '(x, _)'.  Anything else that is in
+// parentheses, like '(2 + 3)', is an expression.
+func (p *Parser) isTuplePattern() bool {
+
+	depth := 0
+	for n := 0; ; n++ {
+		switch p.lookahead(n).Kind {
+
+		case ast.Lparen, ast.Lbracket, ast.Lbrace:
+			depth++
+
+		case ast.Rparen, ast.Rbracket, ast.Rbrace:
+			depth--
+			if depth == 0 {
+				return false
+			}
+
+		case ast.Comma:
+			if depth == 1 {
+				return true
+			}
+
+		case ast.EOF:
+			return false
+		}
+	}
+}
+
+// patternValues returns the values in a list of patterns, if all of the
+// patterns are ValuePatterns.
+func patternValues(patterns []ast.Pattern) ([]ast.Expression, bool) {
+
+	values := []ast.Expression{}
+	for _, pt := range patterns {
+		vp, ok := pt.(*ast.ValuePattern)
+		if !ok {
+			return nil, false
 		}
+		values = append(values, vp.Val)
 	}
+	return values, true
 }
 
 func (p *Parser) defaultStmt() *ast.DefaultNode {
@@ -448,6 +805,94 @@ func (p *Parser) defaultStmt() *ast.DefaultNode {
 	}
 }
 
+func (p *Parser) selectStmt() *ast.SelectStmt {
+
+	token := p.expect(ast.Select)
+	lbrace := p.expect(ast.Lbrace)
+
+	// cases
+	cases := []*ast.SelectCaseNode{}
+	for {
+		p.parseClause(func() {
+			cases = append(cases, p.selectCase())
+		})
+		if p.cur.token.Kind != ast.Case {
+			break
+		}
+	}
+
+	// default
+	var def *ast.DefaultNode
+	if p.cur.token.Kind == ast.Default {
+		p.parseClause(func() {
+			def = p.defaultStmt()
+		})
+	}
+
+	// done
+	result := &ast.SelectStmt{
+		Token:       token,
+		LBrace:      lbrace,
+		Cases:       cases,
+		DefaultNode: def,
+		RBrace:      p.expect(ast.Rbrace),
+	}
+	p.expectStatementDelimiter()
+	return result
+}
+
+func (p *Parser) selectCase() *ast.SelectCaseNode {
+
+	token := p.expect(ast.Case)
+
+	// the case must be either 'a = ch.recv()', 'ch.recv()', or 'ch.send(b)'
+	var assignee *ast.IdentExpr
+	if p.cur.token.Kind == ast.Ident && p.next.token.Kind == ast.Eq {
+		assignee = &ast.IdentExpr{
+			Symbol:   p.expect(ast.Ident),
+			Variable: nil,
+		}
+		p.expect(ast.Eq)
+	}
+
+	comm, ok := p.primaryExpr().(*ast.InvokeExpr)
+	if !ok || !isSelectComm(comm, assignee == nil) {
+		panic(newParserError(p.scn.Source.Path, invalidSelect, token))
+	}
+
+	colon := p.expect(ast.Colon)
+	body := p.statementsAny(ast.Case, ast.Default, ast.Rbrace)
+	if len(body) == 0 {
+		panic(newParserError(p.scn.Source.Path, invalidSelect, colon))
+	}
+
+	return &ast.SelectCaseNode{
+		Token:    token,
+		Assignee: assignee,
+		Comm:     comm,
+		Body:     body,
+	}
+}
+
+// isSelectComm returns whether an invocation is a 'recv()', or optionally
+// a 'send()', on some operand.
+func isSelectComm(comm *ast.InvokeExpr, allowSend bool) bool {
+
+	fe, ok := comm.Operand.(*ast.FieldExpr)
+	if !ok {
+		return false
+	}
+
+	switch fe.Key.Text {
+	case "recv":
+		return len(comm.Params) == 0
+	case "send":
+		return allowSend && len(comm.Params) == 1
+	default:
+		return false
+	}
+}
+
 func (p *Parser) breakStmt() *ast.BreakStmt {
 	result := &ast.BreakStmt{
 		Token: p.expect(ast.Break),
@@ -476,6 +921,16 @@ func (p *Parser) returnStmt() *ast.ReturnStmt {
 	}
 }
 
+func (p *Parser) yieldStmt() *ast.YieldStmt {
+
+	result := &ast.YieldStmt{
+		Token: p.expect(ast.Yield),
+		Val:   p.expression(),
+	}
+	p.expectStatementDelimiter()
+	return result
+}
+
 func (p *Parser) throwStmt() *ast.ThrowStmt {
 
 	result := &ast.ThrowStmt{
@@ -494,11 +949,15 @@ func (p *Parser) tryStmt() *ast.TryStmt {
 	// catch
 	var catchToken *ast.Token
 	var catchIdent *ast.IdentExpr
+	var catchGuard ast.Expression
 	var catchBlock *ast.BlockNode
 
 	if p.cur.token.Kind == ast.Catch {
 		catchToken = p.expect(ast.Catch)
 		catchIdent = p.identExpr()
+		if p.accept(ast.If) {
+			catchGuard = p.expression()
+		}
 		catchBlock = p.block()
 	}
 
@@ -523,6 +982,7 @@ func (p *Parser) tryStmt() *ast.TryStmt {
 		TryBlock:     tryBlock,
 		CatchToken:   catchToken,
 		CatchIdent:   catchIdent,
+		CatchGuard:   catchGuard,
 		CatchBlock:   catchBlock,
 		FinallyToken: finallyToken,
 		FinallyBlock: finallyBlock,
@@ -530,24 +990,17 @@ func (p *Parser) tryStmt() *ast.TryStmt {
 	}
 }
 
-func (p *Parser) goStmt() *ast.GoStmt {
+func (p *Parser) deferStmt() *ast.DeferStmt {
 
-	token := p.expect(ast.Go)
+	token := p.expect(ast.Defer)
 
-	prm := p.primary()
-	if p.cur.token.Kind != ast.Lparen {
-		panic(p.unexpected())
-	}
-	lparen, actual, rparen := p.actualParams()
-	invocation := &ast.InvokeExpr{
-		Operand: prm,
-		LParen:  lparen,
-		Params:  actual,
-		RParen:  rparen,
+	invocation, ok := p.primaryExpr().(*ast.InvokeExpr)
+	if !ok {
+		panic(newParserError(p.scn.Source.Path, invalidDefer, token))
 	}
 
 	p.expectStatementDelimiter()
-	return &ast.GoStmt{
+	return &ast.DeferStmt{
 		Token:      token,
 		Invocation: invocation,
 	}
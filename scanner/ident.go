@@ -39,3 +39,50 @@ func IsIdentStart(r rune) bool {
 func IsIdentContinue(r rune) bool {
 	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
 }
+
+var magicFields = map[string]bool{
+	"$add":      true,
+	"$sub":      true,
+	"$mul":      true,
+	"$eq":       true,
+	"$cmp":      true,
+	"$hashCode": true,
+	"$toStr":    true,
+	"$get":      true,
+	"$set":      true,
+	"$len":      true,
+	"$iter":     true,
+}
+
+// IsMagicField returns whether a string is the name of a magic field
+func IsMagicField(text string) bool {
+	return magicFields[text]
+}
+
+// IsFieldName returns whether a string can be the name of a struct field
+func IsFieldName(text string) bool {
+	return IsIdentifier(text) || IsMagicField(text)
+}
+
+// typeNames are the names of Golem's types, as returned by the 'type' builtin.
+var typeNames = map[string]bool{
+	"Null":   true,
+	"Bool":   true,
+	"Int":    true,
+	"Float":  true,
+	"Str":    true,
+	"List":   true,
+	"Tuple":  true,
+	"Range":  true,
+	"Dict":   true,
+	"Set":    true,
+	"Struct": true,
+	"Func":   true,
+	"Chan":   true,
+}
+
+// IsTypeName returns whether a string is the name of a type.  In a
+// 'switch' pattern, a type name matches any value of that type.
+func IsTypeName(text string) bool {
+	return typeNames[text]
+}
@@ -16,6 +16,7 @@ var keywords = map[string]ast.TokenKind{
 	"const":    ast.Const,
 	"continue": ast.Continue,
 	"default":  ast.Default,
+	"defer":    ast.Defer,
 	"dict":     ast.Dict,
 	"else":     ast.Else,
 	"false":    ast.False,
@@ -30,6 +31,7 @@ var keywords = map[string]ast.TokenKind{
 	"null":     ast.Null,
 	"prop":     ast.Prop,
 	"return":   ast.Return,
+	"select":   ast.Select,
 	"set":      ast.Set,
 	"struct":   ast.Struct,
 	"switch":   ast.Switch,
@@ -38,13 +40,13 @@ var keywords = map[string]ast.TokenKind{
 	"true":     ast.True,
 	"try":      ast.Try,
 	"while":    ast.While,
+	"yield":    ast.Yield,
 }
 
 // reserve a bunch of keywords just in case
 var reservedWords = map[string]bool{
 	"as":        true,
 	"byte":      true,
-	"defer":     true,
 	"goto":      true,
 	"like":      true,
 	"module":    true,
@@ -59,11 +61,9 @@ var reservedWords = map[string]bool{
 	"pure":      true,
 	"rsync":     true,
 	"rune":      true,
-	"select":    true,
 	"static":    true,
 	"sync":      true,
 	"with":      true,
-	"yield":     true,
 }
 
 // IsKeyword returns whether a string is a keyword
@@ -6,7 +6,6 @@ package scanner
 
 import (
 	"bytes"
-	"fmt"
 	"github.com/mjarmy/golem-lang/ast"
 	"io"
 	"strconv"
@@ -43,14 +42,45 @@ type (
 		pos       ast.Pos
 		isDone    bool
 		doneToken *ast.Token
+
+		// interps is a stack of the interpolations in raw strings that we
+		// are currently inside of.  Each entry counts the braces that have
+		// been opened, but not yet closed, inside of the interpolation.
+		interps []int
 	}
 )
 
+// invalidUTF8 returns the position of the first invalid UTF-8 sequence in a string
+func invalidUTF8(code string) ast.Pos {
+	pos := ast.Pos{Line: 1, Col: 1}
+	for len(code) > 0 {
+		r, size := utf8.DecodeRuneInString(code)
+		switch {
+		case r == utf8.RuneError && size == 1:
+			return pos
+		case r == '\n':
+			pos = ast.Pos{Line: pos.Line + 1, Col: 1}
+		default:
+			pos.Col++
+		}
+		code = code[size:]
+	}
+	return pos
+}
+
 // NewScanner creates a new Scanner
 func NewScanner(source *Source) (*Scanner, error) {
 
 	if !utf8.ValidString(source.Code) {
-		return nil, fmt.Errorf("Source code is not a valid UTF-8-encoded string")
+		pos := invalidUTF8(source.Code)
+		return nil, &ast.Diagnostic{
+			Path:     source.Path,
+			Start:    pos,
+			End:      pos.Advance(1),
+			Severity: ast.SeverityError,
+			Code:     "invalid-encoding",
+			Msg:      "Source code is not a valid UTF-8-encoded string",
+		}
 	}
 
 	s := &Scanner{
@@ -185,8 +215,19 @@ func (s *Scanner) Next() *ast.Token {
 			return &ast.Token{Kind: ast.Rparen, Text: ")", Position: pos}
 		case r == '{':
 			s.consume()
+			if n := len(s.interps); n > 0 {
+				s.interps[n-1]++
+			}
 			return &ast.Token{Kind: ast.Lbrace, Text: "{", Position: pos}
 		case r == '}':
+			if n := len(s.interps); n > 0 {
+				// the end of an interpolation
+				if s.interps[n-1] == 0 {
+					s.interps = s.interps[:n-1]
+					return s.nextStrChunk(ast.InterpMiddle, ast.InterpEnd)
+				}
+				s.interps[n-1]--
+			}
 			s.consume()
 			return &ast.Token{Kind: ast.Rbrace, Text: "}", Position: pos}
 		case r == '[':
@@ -345,6 +386,9 @@ func (s *Scanner) Next() *ast.Token {
 		case r == '`':
 			return s.nextRawStr()
 
+		case r == '$':
+			return s.nextMagicField()
+
 		case isDigit(r):
 			return s.nextNumber()
 
@@ -385,6 +429,22 @@ func (s *Scanner) nextIdentOrKeyword() *ast.Token {
 	return &ast.Token{Kind: ast.Ident, Text: text, Position: pos}
 }
 
+// nextMagicField scans a '$' followed by an identifier.  The parser
+// decides whether the result is actually the name of a magic field.
+func (s *Scanner) nextMagicField() *ast.Token {
+
+	pos := s.pos
+	begin := s.cur.idx
+	s.consume()
+
+	if !IsIdentStart(s.cur.r) {
+		return s.unexpectedChar('$', pos)
+	}
+	s.acceptWhile(IsIdentContinue)
+
+	return &ast.Token{Kind: ast.MagicField, Text: s.Source.Code[begin:s.cur.idx], Position: pos}
+}
+
 func (s *Scanner) nextStr(delim rune) *ast.Token {
 
 	pos := s.pos
@@ -484,6 +544,16 @@ func (s *Scanner) unicodeRune() (rune, *ast.Token) {
 }
 
 func (s *Scanner) nextRawStr() *ast.Token {
+	return s.nextStrChunk(ast.InterpBegin, ast.Str)
+}
+
+// nextStrChunk scans a raw string, up to either the end of the string, or
+// the beginning of an interpolation.  A raw string with interpolations is
+// scanned as an InterpBegin token, followed by the tokens of each of the
+// interpolated expressions, separated by InterpMiddle tokens, and then
+// finally an InterpEnd token.  The text of each of those tokens is the
+// part of the string that the token contains.
+func (s *Scanner) nextStrChunk(interp ast.TokenKind, end ast.TokenKind) *ast.Token {
 
 	pos := s.pos
 	s.consume()
@@ -498,7 +568,21 @@ func (s *Scanner) nextRawStr() *ast.Token {
 		case r == '`':
 			// end of string
 			s.consume()
-			return &ast.Token{Kind: ast.Str, Text: buf.String(), Position: pos}
+			return &ast.Token{Kind: end, Text: buf.String(), Position: pos}
+
+		case r == '$' && s.lookingAt("${"):
+			// beginning of an interpolation
+			s.consume()
+			s.consume()
+			s.interps = append(s.interps, 0)
+			return &ast.Token{Kind: interp, Text: buf.String(), Position: pos}
+
+		case r == '\\' && s.lookingAt("\\${"):
+			// an escaped interpolation
+			s.consume()
+			s.consume()
+			s.consume()
+			buf.WriteString("${")
 
 		case r == eof:
 			// unterminated string literal
@@ -515,6 +599,12 @@ func (s *Scanner) nextRawStr() *ast.Token {
 	}
 }
 
+// lookingAt returns whether the source code starts with the
+// given text at the current rune.
+func (s *Scanner) lookingAt(text string) bool {
+	return strings.HasPrefix(s.Source.Code[s.cur.idx:], text)
+}
+
 func (s *Scanner) nextNumber() *ast.Token {
 
 	pos := s.pos
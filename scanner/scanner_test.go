@@ -272,6 +272,61 @@ func TestStr(t *testing.T) {
 	ok(t, s, ast.EOF, "", 2, 3)
 }
 
+func TestInterpStr(t *testing.T) {
+
+	s := mustScanner(&Source{"", "", "`a${b}c`"})
+	ok(t, s, ast.InterpBegin, "a", 1, 1)
+	ok(t, s, ast.Ident, "b", 1, 5)
+	ok(t, s, ast.InterpEnd, "c", 1, 6)
+	ok(t, s, ast.EOF, "", 1, 9)
+
+	s = mustScanner(&Source{"", "", "`${a}${ b + 1 }`"})
+	ok(t, s, ast.InterpBegin, "", 1, 1)
+	ok(t, s, ast.Ident, "a", 1, 4)
+	ok(t, s, ast.InterpMiddle, "", 1, 5)
+	ok(t, s, ast.Ident, "b", 1, 9)
+	ok(t, s, ast.Plus, "+", 1, 11)
+	ok(t, s, ast.Int, "1", 1, 13)
+	ok(t, s, ast.InterpEnd, "", 1, 15)
+	ok(t, s, ast.EOF, "", 1, 17)
+
+	s = mustScanner(&Source{"", "", "`a${ {b} }c` }"})
+	ok(t, s, ast.InterpBegin, "a", 1, 1)
+	ok(t, s, ast.Lbrace, "{", 1, 6)
+	ok(t, s, ast.Ident, "b", 1, 7)
+	ok(t, s, ast.Rbrace, "}", 1, 8)
+	ok(t, s, ast.InterpEnd, "c", 1, 10)
+	ok(t, s, ast.Rbrace, "}", 1, 14)
+	ok(t, s, ast.EOF, "", 1, 15)
+
+	s = mustScanner(&Source{"", "", "`a${`b${c}`}\\n`"})
+	ok(t, s, ast.InterpBegin, "a", 1, 1)
+	ok(t, s, ast.InterpBegin, "b", 1, 5)
+	ok(t, s, ast.Ident, "c", 1, 9)
+	ok(t, s, ast.InterpEnd, "", 1, 10)
+	ok(t, s, ast.InterpEnd, "\\n", 1, 12)
+	ok(t, s, ast.EOF, "", 1, 16)
+
+	s = mustScanner(&Source{"", "", "`a\\${b}$c`"})
+	ok(t, s, ast.Str, "a${b}$c", 1, 1)
+	ok(t, s, ast.EOF, "", 1, 11)
+
+	// a raw string that contains '${' has to escape it
+	s = mustScanner(&Source{"", "", "`^\\${[a-z]+}$` `\\\\${`"})
+	ok(t, s, ast.Str, "^${[a-z]+}$", 1, 1)
+	ok(t, s, ast.Str, "\\${", 1, 16)
+	ok(t, s, ast.EOF, "", 1, 22)
+
+	s = mustScanner(&Source{"", "", "`^${[a-z]+}$`"})
+	ok(t, s, ast.InterpBegin, "^", 1, 1)
+	ok(t, s, ast.Lbracket, "[", 1, 5)
+
+	s = mustScanner(&Source{"", "", "`a${b}c"})
+	ok(t, s, ast.InterpBegin, "a", 1, 1)
+	ok(t, s, ast.Ident, "b", 1, 5)
+	ok(t, s, ast.UnexpectedEOF, "", 1, 8)
+}
+
 func TestIdentOrKeyword(t *testing.T) {
 	s := mustScanner(&Source{"", "", "a bar"})
 	ok(t, s, ast.Ident, "a", 1, 1)
@@ -337,6 +392,29 @@ func TestIdentOrKeyword(t *testing.T) {
 	ok(t, s, ast.EOF, "", 1, 21)
 }
 
+func TestMagicField(t *testing.T) {
+	s := mustScanner(&Source{"", "", "$add a.$toStr $foo1"})
+	ok(t, s, ast.MagicField, "$add", 1, 1)
+	ok(t, s, ast.Ident, "a", 1, 6)
+	ok(t, s, ast.Dot, ".", 1, 7)
+	ok(t, s, ast.MagicField, "$toStr", 1, 8)
+	ok(t, s, ast.MagicField, "$foo1", 1, 15)
+	ok(t, s, ast.EOF, "", 1, 20)
+
+	s = mustScanner(&Source{"", "", "$ add"})
+	ok(t, s, ast.UnexpectedChar, "$", 1, 1)
+
+	s = mustScanner(&Source{"", "", "$1"})
+	ok(t, s, ast.UnexpectedChar, "$", 1, 1)
+
+	tassert(t, IsMagicField("$cmp"))
+	tassert(t, !IsMagicField("$foo"))
+	tassert(t, !IsMagicField("cmp"))
+	tassert(t, IsFieldName("$cmp"))
+	tassert(t, IsFieldName("cmp"))
+	tassert(t, !IsFieldName("$foo"))
+}
+
 func TestComments(t *testing.T) {
 
 	s := mustScanner(&Source{"", "", "1 //foo\n2"})